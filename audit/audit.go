@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Redacted is recorded in place of values that should not be written to
+// the audit log, such as SecureString parameters.
+const Redacted = "(redacted)"
+
+// Entry is a single line in the audit log.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Account  string    `json:"account"`
+	Region   string    `json:"region"`
+	Action   string    `json:"action"`
+	Resource string    `json:"resource"`
+	OldValue string    `json:"old_value"`
+	NewValue string    `json:"new_value"`
+	Caller   string    `json:"caller" output:"hidden"`
+	Command  string    `json:"command" output:"hidden"`
+}
+
+// command is what entries record as their command: the command path and
+// the names of the flags given. The full command line isn't recorded as
+// its arguments can be secrets, like param put's value.
+var command = "aws-buddy"
+
+// SetCommand sets the command that following entries are made by.
+func SetCommand(cmd *cobra.Command) {
+	parts := []string{cmd.CommandPath()}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		parts = append(parts, "--"+f.Name)
+	})
+	command = strings.Join(parts, " ")
+}
+
+// Path returns the location of the audit log.
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "audit.jsonl"), nil
+}
+
+// Record appends e to the audit log, filling in the timestamp, caller
+// identity, region and command. The change has already been made by
+// the time Record is called, so failures are logged rather than returned.
+func Record(e Entry) {
+	if err := record(e); err != nil {
+		log.Printf("audit log err: %s", err)
+	}
+}
+
+func record(e Entry) error {
+	e.Time = time.Now().UTC()
+	e.Region = config.ResolvedRegion()
+	e.Command = command
+
+	ident, err := client.STS(config.Session()).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		log.Printf("audit: GetCallerIdentity err: %s", err)
+	} else {
+		e.Caller = aws.StringValue(ident.Arn)
+		e.Account = aws.StringValue(ident.Account)
+	}
+
+	p, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns all entries in the audit log, oldest first.
+func Read() ([]Entry, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", p, lineNo, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+var (
+	limitFlag  int
+	actionFlag string
+)
+
+func Command() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "history",
+		Short: "Show the local audit log of changes made with aws-buddy",
+		RunE:  historyAction,
+	}
+
+	cmd.Flags().IntVarP(&limitFlag, "limit", "n", 0, "Only show the most recent n entries")
+	cmd.Flags().StringVarP(&actionFlag, "action", "", "", "Only show entries for this action (e.g. \"tag set\")")
+
+	return &cmd
+}
+
+func historyAction(cmd *cobra.Command, args []string) error {
+	entries, err := Read()
+	if err != nil {
+		return fmt.Errorf("read audit log err: %w", err)
+	}
+
+	if actionFlag != "" {
+		filtered := entries[:0]
+		for _, e := range entries {
+			if e.Action == actionFlag {
+				filtered = append(filtered, e)
+			}
+		}
+		entries = filtered
+	}
+
+	if limitFlag > 0 && len(entries) > limitFlag {
+		entries = entries[len(entries)-limitFlag:]
+	}
+
+	out := output.New(cmd.OutOrStdout())
+	for _, e := range entries {
+		out.Add(e)
+	}
+	return out.Flush()
+}
@@ -1,12 +1,19 @@
 package awsconfig
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"sort"
 
 	"github.com/aws/aws-sdk-go/service/configservice"
+	"github.com/aws/aws-sdk-go/service/configservice/configserviceiface"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
@@ -27,7 +34,7 @@ func queryIPCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "query_eni_by_ip",
 		Short: "Query for ENIs matching an ip",
-		Run:   queryIPAction,
+		RunE:  queryIPAction,
 	}
 
 	cmd.Flags().StringVarP(&aggregatorName, "aggregator-name", "", "AllAccounts", "AWS Config Aggretator Name")
@@ -40,14 +47,15 @@ var (
 	resourceType   string
 )
 
-func queryIPAction(cmd *cobra.Command, args []string) {
+func queryIPAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) == 0 {
-		log.Fatalf("Usage: query_eni_by_public_ip <PUBLIC_IP>")
+		return fmt.Errorf("%w: query_eni_by_public_ip <PUBLIC_IP>", errs.ErrUsage)
 	}
 
 	publicIP := args[0]
 
-	svc := configservice.New(config.Session())
+	svc := client.Config(config.Session())
 
 	queryTmpl := `SELECT
   resourceId,
@@ -67,27 +75,14 @@ WHERE
 
 	query := fmt.Sprintf(queryTmpl, publicIP, publicIP)
 
-	input := &configservice.SelectAggregateResourceConfigInput{
-		ConfigurationAggregatorName: &aggregatorName,
-		Expression:                  &query,
-	}
-	err := svc.SelectAggregateResourceConfigPages(input, func(resp *configservice.SelectAggregateResourceConfigOutput, b bool) bool {
-		for _, result := range resp.Results {
-			fmt.Println(*result)
-		}
-
-		return true
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
+	return selectAggregate(ctx, cmd.OutOrStdout(), svc, query)
 }
 
 func queryResourceIDCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "query_by_id",
 		Short: "Query by resource id for resoucre",
-		Run:   queryResourceIDAction,
+		RunE:  queryResourceIDAction,
 	}
 
 	cmd.Flags().StringVarP(&aggregatorName, "aggregator-name", "", "AllAccounts", "AWS Config Aggretator Name")
@@ -95,14 +90,15 @@ func queryResourceIDCommand() *cobra.Command {
 	return &cmd
 }
 
-func queryResourceIDAction(cmd *cobra.Command, args []string) {
+func queryResourceIDAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) == 0 {
-		log.Fatalf("Usage: query_by_id <RESOURCE_ID>")
+		return fmt.Errorf("%w: query_by_id <RESOURCE_ID>", errs.ErrUsage)
 	}
 
 	resourceID := args[0]
 
-	svc := configservice.New(config.Session())
+	svc := client.Config(config.Session())
 
 	queryTmpl := `
 SELECT
@@ -121,45 +117,84 @@ WHERE
 
 	query := fmt.Sprintf(queryTmpl, resourceID)
 
+	return selectAggregate(ctx, cmd.OutOrStdout(), svc, query)
+}
+
+type resourceRow struct {
+	ResourceID   string `json:"resource_id"`
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+	AccountID    string `json:"account_id"`
+}
+
+func selectAggregate(ctx context.Context, w io.Writer, svc configserviceiface.ConfigServiceAPI, query string) error {
+	out := output.New(w)
+	out.Default = output.JSONL
+
 	input := &configservice.SelectAggregateResourceConfigInput{
 		ConfigurationAggregatorName: &aggregatorName,
 		Expression:                  &query,
 	}
-	err := svc.SelectAggregateResourceConfigPages(input, func(resp *configservice.SelectAggregateResourceConfigOutput, b bool) bool {
+	err := svc.SelectAggregateResourceConfigPagesWithContext(ctx, input, func(resp *configservice.SelectAggregateResourceConfigOutput, b bool) bool {
 		for _, result := range resp.Results {
-			fmt.Println(*result)
+			var detail map[string]interface{}
+			err := json.Unmarshal([]byte(*result), &detail)
+			if err != nil {
+				log.Printf("decode result err: %s", err)
+				continue
+			}
+
+			str := func(key string) string {
+				s, _ := detail[key].(string)
+				return s
+			}
+			out.AddDetail(resourceRow{
+				ResourceID:   str("resourceId"),
+				ResourceType: str("resourceType"),
+				ResourceName: str("resourceName"),
+				AccountID:    str("accountId"),
+			}, detail)
 		}
 
 		return true
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+
+	return out.Flush()
 }
 
 func listResourceTypesCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "resource_types",
 		Short: "List the resource types for aws config",
-		Run:   listResourceTypes,
+		RunE:  listResourceTypes,
 	}
 
 	return &cmd
 }
 
-func listResourceTypes(cmd *cobra.Command, args []string) {
+func listResourceTypes(cmd *cobra.Command, args []string) error {
 	types := configservice.ResourceType_Values()
 	sort.Strings(types)
+
+	out := output.New(cmd.OutOrStdout())
 	for _, t := range types {
-		fmt.Println(t)
+		out.Add(resourceTypeRow{Type: t})
 	}
+	return out.Flush()
+}
+
+type resourceTypeRow struct {
+	Type string `json:"type"`
 }
 
 func resourceInventoryByTypeCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "inventory_by_type",
 		Short: "List all config resources for a given type",
-		Run:   resourceInventoryByType,
+		RunE:  resourceInventoryByType,
 	}
 
 	cmd.Flags().StringVarP(&aggregatorName, "aggregator-name", "", "AllAccounts", "AWS Config Aggretator Name")
@@ -168,10 +203,11 @@ func resourceInventoryByTypeCommand() *cobra.Command {
 	return &cmd
 }
 
-func resourceInventoryByType(cmd *cobra.Command, args []string) {
+func resourceInventoryByType(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	// resource types https://docs.aws.amazon.com/config/latest/developerguide/resource-config-reference.html
 
-	svc := configservice.New(config.Session())
+	svc := client.Config(config.Session())
 
 	queryTmpl := `
 SELECT
@@ -188,18 +224,5 @@ WHERE
 
 	query := fmt.Sprintf(queryTmpl, resourceType)
 
-	input := &configservice.SelectAggregateResourceConfigInput{
-		ConfigurationAggregatorName: &aggregatorName,
-		Expression:                  &query,
-	}
-	err := svc.SelectAggregateResourceConfigPages(input, func(resp *configservice.SelectAggregateResourceConfigOutput, b bool) bool {
-		for _, result := range resp.Results {
-			fmt.Println(*result)
-		}
-
-		return true
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
+	return selectAggregate(ctx, cmd.OutOrStdout(), svc, query)
 }
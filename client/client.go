@@ -0,0 +1,55 @@
+// Package client constructs the AWS service clients used by the commands.
+//
+// Commands call these instead of the service package New functions and
+// only depend on the aws-sdk-go *iface interfaces, so the constructors can
+// be swapped for in-memory fakes (see the fake package).
+package client
+
+import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/configservice"
+	"github.com/aws/aws-sdk-go/service/configservice/configserviceiface"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/costexplorer/costexploreriface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/organizations/organizationsiface"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/aws/aws-sdk-go/service/ssoadmin"
+	"github.com/aws/aws-sdk-go/service/ssoadmin/ssoadminiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/aws/aws-sdk-go/service/textract"
+	"github.com/aws/aws-sdk-go/service/textract/textractiface"
+)
+
+var (
+	AutoScaling   = func(sess *session.Session) autoscalingiface.AutoScalingAPI { return autoscaling.New(sess) }
+	Config        = func(sess *session.Session) configserviceiface.ConfigServiceAPI { return configservice.New(sess) }
+	CostExplorer  = func(sess *session.Session) costexploreriface.CostExplorerAPI { return costexplorer.New(sess) }
+	EC2           = func(sess *session.Session) ec2iface.EC2API { return ec2.New(sess) }
+	IAM           = func(sess *session.Session) iamiface.IAMAPI { return iam.New(sess) }
+	Organizations = func(sess *session.Session) organizationsiface.OrganizationsAPI { return organizations.New(sess) }
+	Pricing       = func(sess *session.Session) pricingiface.PricingAPI { return pricing.New(sess) }
+	Route53       = func(sess *session.Session) route53iface.Route53API { return route53.New(sess) }
+	S3            = func(sess *session.Session) s3iface.S3API { return s3.New(sess) }
+	SSM           = func(sess *session.Session) ssmiface.SSMAPI { return ssm.New(sess) }
+	SSOAdmin      = func(sess *session.Session) ssoadminiface.SSOAdminAPI { return ssoadmin.New(sess) }
+	SQS           = func(sess *session.Session) sqsiface.SQSAPI { return sqs.New(sess) }
+	STS           = func(sess *session.Session) stsiface.STSAPI { return sts.New(sess) }
+	Textract      = func(sess *session.Session) textractiface.TextractAPI { return textract.New(sess) }
+)
@@ -1,41 +1,103 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/aws/aws-sdk-go/aws"
-	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/psanford/aws-buddy/audit"
 	"github.com/psanford/aws-buddy/awsconfig"
+	"github.com/psanford/aws-buddy/completion"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
 	"github.com/psanford/aws-buddy/cost"
+	"github.com/psanford/aws-buddy/creds"
 	"github.com/psanford/aws-buddy/ec2"
+	"github.com/psanford/aws-buddy/errs"
 	"github.com/psanford/aws-buddy/iam"
 	"github.com/psanford/aws-buddy/org"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/psanford/aws-buddy/parameterstore"
 	"github.com/psanford/aws-buddy/route53"
 	"github.com/psanford/aws-buddy/s3"
 	"github.com/psanford/aws-buddy/sqs"
 	"github.com/psanford/aws-buddy/textract"
+	"github.com/psanford/aws-buddy/whoami"
 	"github.com/spf13/cobra"
 )
 
-var region = "us-east-1"
-
-var rootCmd = &cobra.Command{
-	Use:   "aws-buddy",
-	Short: "AWS tools",
-}
-
 func Execute() error {
-	if os.Getenv("AWS_DEFAULT_REGION") != "" {
-		region = os.Getenv("AWS_DEFAULT_REGION")
+	// the first ctrl-c cancels the command's context so it can stop
+	// cleanly; a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := RootCommand().ExecuteContext(ctx)
+
+	var exitErr *errs.ExitStatusError
+	if errs.IsCanceled(err) && !errors.As(err, &exitErr) {
+		return fmt.Errorf("%w: interrupted", errs.ErrAborted)
 	}
-	if os.Getenv("AWS_REGION") != "" {
-		region = os.Getenv("AWS_REGION")
+	return err
+}
+
+// RootCommand returns the aws-buddy command tree. Its input and output
+// can be redirected with SetIn and SetOut.
+func RootCommand() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "aws-buddy",
+		Short: "AWS tools",
+
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
 
-	os.Setenv("AWS_DEFAULT_REGION", region)
+	rootCmd.PersistentFlags().StringVarP(&config.Profile, "profile", "", "", "AWS profile to use (default $AWS_PROFILE)")
+	rootCmd.PersistentFlags().StringVarP(&config.FileName, "config", "", "", "Config file (default ~/.config/aws-buddy/config.yml)")
+	rootCmd.PersistentFlags().StringVarP(&config.Region, "region", "", "", "AWS region to use (default $AWS_REGION, $AWS_DEFAULT_REGION or profile region)")
+	rootCmd.PersistentFlags().StringArrayVarP(&config.EndpointURLs, "endpoint-url", "", nil, "Send requests to this URL instead of AWS (URL, or service=URL for one service, e.g. s3=http://localhost:9000; repeatable)")
+	rootCmd.PersistentFlags().StringVarP(&config.RecordDir, "record", "", "", "Record AWS API requests and responses to fixture files in this directory (fixtures may contain secrets)")
+	rootCmd.PersistentFlags().StringVarP(&config.ReplayDir, "replay", "", "", "Serve AWS API responses from fixtures recorded with --record instead of the network")
+	rootCmd.PersistentFlags().IntVarP(&config.MaxRetries, "max-retries", "", 10, "Retry throttled and failed AWS requests up to this many times, with exponential backoff")
+	rootCmd.PersistentFlags().Float64VarP(&config.MaxRPS, "max-rps", "", 0, "Limit AWS requests to this many per second (default no limit)")
+	rootCmd.PersistentFlags().StringVarP(&config.RoleARN, "role-arn", "", "", "Assume this IAM role for all requests")
+	rootCmd.PersistentFlags().StringVarP(&config.Account, "account", "", "", "Account id to assume --role in")
+	rootCmd.PersistentFlags().StringVarP(&config.RoleName, "role", "", "", "Role name to assume in --account (default the caller's account)")
+	rootCmd.PersistentFlags().StringVarP(&config.MFASerial, "mfa-serial", "", "", "MFA device ARN to use when assuming a role; prompts for the token code")
+	rootCmd.PersistentFlags().BoolVarP(&console.AssumeYes, "yes", "y", false, "Answer yes to confirmation prompts and skip the grace period")
+	rootCmd.PersistentFlags().BoolVarP(&console.DryRun, "dry-run", "", false, "Print the API requests mutating commands would send without sending them (SecureString values are masked)")
+	rootCmd.PersistentFlags().BoolVarP(&console.ShowBanner, "banner", "", false, "Show the account, region and profile above confirmation prompts")
+	output.AddFlags(rootCmd)
+	rootCmd.RegisterFlagCompletionFunc("account", completion.Accounts())
+
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %s", errs.ErrUsage, err)
+	})
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		console.In = cmd.InOrStdin()
+		console.Out = cmd.OutOrStdout()
+		console.Banner = whoami.Banner
+		audit.SetCommand(cmd)
+
+		if err := config.ApplyFlagDefaults(cmd); err != nil {
+			return fmt.Errorf("%w: %s", errs.ErrUsage, err)
+		}
+		if err := output.CheckFlags(); err != nil {
+			return fmt.Errorf("%w: %s", errs.ErrUsage, err)
+		}
+		if _, err := config.NewSession(); err != nil {
+			return fmt.Errorf("aws session err: %w", err)
+		}
+		return nil
+	}
 
 	rootCmd.AddCommand(ec2.Command())
 	rootCmd.AddCommand(s3.Command())
@@ -46,10 +108,13 @@ func Execute() error {
 	rootCmd.AddCommand(parameterstore.Command())
 	rootCmd.AddCommand(awsconfig.Command())
 	rootCmd.AddCommand(sqs.Command())
+	rootCmd.AddCommand(audit.Command())
 	rootCmd.AddCommand(helpTreeCommand())
 	rootCmd.AddCommand(textract.Command())
+	rootCmd.AddCommand(whoami.Command())
+	rootCmd.AddCommand(creds.Command())
 
-	return rootCmd.Execute()
+	return rootCmd
 }
 
 func helpTreeCommand() *cobra.Command {
@@ -60,33 +125,16 @@ func helpTreeCommand() *cobra.Command {
 			var printHelp func(cmd *cobra.Command)
 
 			printHelp = func(cmd *cobra.Command) {
-				fmt.Println("\n========================================")
+				fmt.Fprintln(cmd.OutOrStdout(), "\n========================================")
 				cmd.Help()
 				for _, childCmd := range cmd.Commands() {
 					printHelp(childCmd)
 				}
 			}
 
-			printHelp(rootCmd)
+			printHelp(cmd.Root())
 		},
 	}
 
 	return cmd
 }
-
-func session() *awssession.Session {
-	sess, err := awssession.NewSession(&aws.Config{Region: &region})
-	if err != nil {
-		log.Fatalf("AWS NewSession error: %s", err)
-	}
-
-	return sess
-}
-
-func confirm(prompt string) bool {
-	fmt.Print(prompt)
-	var result string
-	fmt.Scanln(&result)
-
-	return result == "y" || result == "Y" || result == "yes" || result == "Yes"
-}
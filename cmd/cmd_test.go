@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/fake"
+)
+
+// newBackend installs an empty fake backend for the test and points the
+// config, cache and AWS shared config files at a temp dir so the user's
+// own aren't used.
+func newBackend(t *testing.T) *fake.Backend {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "aws", "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "aws", "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "fake")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "fake")
+
+	oldDelay := console.GraceDelay
+	console.GraceDelay = 0
+	t.Cleanup(func() { console.GraceDelay = oldDelay })
+
+	b := fake.NewBackend()
+	t.Cleanup(b.Install())
+	return b
+}
+
+// run runs aws-buddy with args, answering prompts from stdin, and returns
+// what it wrote to stdout.
+func run(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	root := RootCommand()
+	root.SetArgs(args)
+	root.SetIn(strings.NewReader(stdin))
+	root.SetOut(&out)
+	err := root.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func auditLog(t *testing.T) []audit.Entry {
+	t.Helper()
+
+	entries, err := audit.Read()
+	if err != nil {
+		t.Fatalf("read audit log err: %s", err)
+	}
+	return entries
+}
+
+func called(calls []string, op string) bool {
+	for _, c := range calls {
+		if c == op {
+			return true
+		}
+	}
+	return false
+}
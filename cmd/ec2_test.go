@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/fake"
+)
+
+func seedInstances(b *fake.Backend) {
+	web := fake.Instance("i-0000000000000001a", "web-1")
+	web.Tags = append(web.Tags, &ec2.Tag{Key: aws.String("env"), Value: aws.String("prod")})
+	web.LaunchTime = aws.Time(time.Now().Add(-24 * time.Hour))
+
+	db := fake.Instance("i-0000000000000002b", "db-1")
+	db.InstanceType = aws.String("r5.large")
+	db.State = &ec2.InstanceState{Code: aws.Int64(80), Name: aws.String(ec2.InstanceStateNameStopped)}
+
+	gone := fake.Instance("i-0000000000000003c", "web-old")
+	gone.State = &ec2.InstanceState{Code: aws.Int64(48), Name: aws.String(ec2.InstanceStateNameTerminated)}
+
+	b.EC2.AddInstance(web)
+	b.EC2.AddInstance(db)
+	b.EC2.AddInstance(gone)
+}
+
+func TestEC2ListTable(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+
+	out, err := run(t, "", "ec2", "list", "--columns", "id,name,type,state", "--sort-by", "name")
+	if err != nil {
+		t.Fatalf("ec2 list err: %s", err)
+	}
+
+	// short types are multibyte, so columns are padded by rune
+	want := strings.Join([]string{
+		"id                  | name    | type | state",
+		"i-0000000000000002b | db-1    | r5.l | stp  ",
+		"i-0000000000000001a | web-1   | t3.μ | run  ",
+		"i-0000000000000003c | web-old | t3.μ | ter  ",
+		"",
+	}, "\n")
+	if out != want {
+		t.Errorf("ec2 list output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestEC2ListJSON(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+
+	out, err := run(t, "", "ec2", "list", "-o", "json", "state=running")
+	if err != nil {
+		t.Fatalf("ec2 list err: %s", err)
+	}
+
+	var instances []ec2.Instance
+	if err := json.Unmarshal([]byte(out), &instances); err != nil {
+		t.Fatalf("unmarshal ec2 list json err: %s\n%s", err, out)
+	}
+	if len(instances) != 1 || aws.StringValue(instances[0].InstanceId) != "i-0000000000000001a" {
+		t.Errorf("ec2 list -o json = %s, want just i-0000000000000001a", out)
+	}
+}
+
+func TestEC2ListFilters(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"state=stopped"}, []string{"i-0000000000000002b"}},
+		{[]string{"state!=stopped"}, []string{"i-0000000000000001a", "i-0000000000000003c"}},
+		{[]string{"web"}, []string{"i-0000000000000001a", "i-0000000000000003c"}},
+		{[]string{"tag:env=prod"}, []string{"i-0000000000000001a"}},
+		{[]string{"type~R5"}, []string{"i-0000000000000002b"}},
+		{[]string{"-f", "name=web-*", "-f", "launched<7d"}, []string{"i-0000000000000001a"}},
+		{[]string{"name=web-*", "state=running,terminated", "state!=terminated"}, []string{"i-0000000000000001a"}},
+		{[]string{"launched<2023-01-01"}, []string{"i-0000000000000002b", "i-0000000000000003c"}},
+		{[]string{"state=pending"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			b := newBackend(t)
+			seedInstances(b)
+
+			args := append([]string{"ec2", "list", "--columns", "id", "--no-header", "--sort-by", "id"}, tt.args...)
+			out, err := run(t, "", args...)
+			if err != nil {
+				t.Fatalf("ec2 list err: %s", err)
+			}
+			got := strings.Fields(out)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ec2 list %s = %q, want %q", strings.Join(tt.args, " "), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEC2ListBadFilter(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+
+	_, err := run(t, "", "ec2", "list", "color=red")
+	if !errors.Is(err, errs.ErrUsage) {
+		t.Errorf("ec2 list color=red err = %v, want usage error", err)
+	}
+}
+
+func TestEC2ListFilterName(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+
+	out, err := run(t, "", "ec2", "list", "--columns", "id", "--no-header", "--filter-name", "db-1")
+	if err != nil {
+		t.Fatalf("ec2 list --filter-name err: %s", err)
+	}
+	if got, want := strings.Fields(out), []string{"i-0000000000000002b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ec2 list --filter-name db-1 = %q, want %q", got, want)
+	}
+
+	// both would send tag:Name twice
+	_, err = run(t, "", "ec2", "list", "--filter-name", "x", "name=y")
+	if !errors.Is(err, errs.ErrUsage) {
+		t.Errorf("ec2 list --filter-name x name=y err = %v, want usage error", err)
+	}
+}
+
+func TestEC2Terminate(t *testing.T) {
+	tests := []struct {
+		name       string
+		stdin      string
+		args       []string
+		err        error
+		terminated bool
+	}{
+		{"confirmed", "y\n", nil, nil, true},
+		{"declined", "n\n", nil, errs.ErrAborted, false},
+		{"no answer", "", nil, errs.ErrAborted, false},
+		{"yes flag", "", []string{"--yes"}, nil, true},
+		{"dry run", "y\n", []string{"--dry-run"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBackend(t)
+			seedInstances(b)
+
+			args := append([]string{"ec2", "terminate", "web-1"}, tt.args...)
+			out, err := run(t, tt.stdin, args...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ec2 terminate err = %v, want %v", err, tt.err)
+			}
+			if !strings.Contains(out, "id       : i-0000000000000001a") {
+				t.Errorf("ec2 terminate didn't print the instance summary:\n%s", out)
+			}
+
+			if got := called(b.EC2.Calls(), "TerminateInstances"); got != tt.terminated {
+				t.Errorf("TerminateInstances called = %t, want %t", got, tt.terminated)
+			}
+			state := aws.StringValue(b.EC2.Instances[0].State.Name)
+			if tt.terminated != (state == ec2.InstanceStateNameShuttingDown || state == ec2.InstanceStateNameTerminated) {
+				t.Errorf("instance state = %s after ec2 terminate", state)
+			}
+
+			entries := auditLog(t)
+			if tt.terminated && (len(entries) != 1 || entries[0].Action != "ec2 terminate") {
+				t.Errorf("audit log = %+v, want one ec2 terminate entry", entries)
+			} else if !tt.terminated && len(entries) != 0 {
+				t.Errorf("audit log = %+v, want no entries", entries)
+			}
+
+			if tt.name == "dry run" && !strings.Contains(out, "dry-run: TerminateInstances") {
+				t.Errorf("ec2 terminate --dry-run didn't print the request:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestEC2ResolveWildcard(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+
+	// EC2 would match web-1 with the wildcard, but it isn't web-1's name
+	_, err := run(t, "", "--yes", "ec2", "terminate", "web-?")
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("ec2 terminate web-? err = %v, want not found", err)
+	}
+	if called(b.EC2.Calls(), "TerminateInstances") {
+		t.Errorf("ec2 terminate web-? terminated an instance")
+	}
+}
+
+func TestEC2TagSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		err   error
+		set   bool
+	}{
+		{"confirmed", "y\n", nil, nil, true},
+		{"declined", "n\n", nil, errs.ErrAborted, false},
+		{"dry run", "y\n", []string{"--dry-run"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBackend(t)
+			seedInstances(b)
+
+			args := append([]string{"ec2", "tag", "set", "web-1", "env", "staging"}, tt.args...)
+			out, err := run(t, tt.stdin, args...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ec2 tag set err = %v, want %v", err, tt.err)
+			}
+			if !strings.Contains(out, "tag env: prod => staging") {
+				t.Errorf("ec2 tag set didn't print the change:\n%s", out)
+			}
+
+			var env string
+			for _, tag := range b.EC2.Instances[0].Tags {
+				if aws.StringValue(tag.Key) == "env" {
+					env = aws.StringValue(tag.Value)
+				}
+			}
+			want := "prod"
+			if tt.set {
+				want = "staging"
+			}
+			if env != want {
+				t.Errorf("env tag = %q, want %q", env, want)
+			}
+			if got := called(b.EC2.Calls(), "CreateTags"); got != tt.set {
+				t.Errorf("CreateTags called = %t, want %t", got, tt.set)
+			}
+
+			if tt.name == "dry run" {
+				if !strings.Contains(out, "dry-run: CreateTags") || !strings.Contains(out, `"Value": "staging"`) {
+					t.Errorf("ec2 tag set --dry-run didn't print the request:\n%s", out)
+				}
+				if strings.Contains(out, "[yN]") {
+					t.Errorf("ec2 tag set --dry-run prompted:\n%s", out)
+				}
+			}
+		})
+	}
+}
+
+func TestEC2ResizeRollback(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+	b.EC2.InstanceTypeOfferings = map[string][]string{"us-east-1a": {"t3.micro", "t3.large"}}
+	b.EC2.InsufficientCapacity = []string{"t3.large"}
+
+	_, err := run(t, "", "--yes", "ec2", "resize", "web-1", "t3.large")
+	if err == nil || !strings.Contains(err.Error(), "rolled back to t3.micro") {
+		t.Fatalf("ec2 resize err = %v, want a rolled back error", err)
+	}
+
+	inst := b.EC2.Instances[0]
+	if typ, state := aws.StringValue(inst.InstanceType), aws.StringValue(inst.State.Name); typ != "t3.micro" || state != ec2.InstanceStateNameRunning {
+		t.Errorf("instance is %s %s after rollback, want running t3.micro", state, typ)
+	}
+}
+
+func TestEC2ResizeArchitecture(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+	// a1 is graviton even though its name has no g
+	b.EC2.InstanceTypeOfferings = map[string][]string{"us-east-1a": {"t3.micro", "a1.large"}}
+	b.EC2.InstanceTypeArchitectures = map[string][]string{"a1.large": {ec2.ArchitectureTypeArm64}}
+
+	_, err := run(t, "", "--yes", "ec2", "resize", "web-1", "a1.large")
+	if !errors.Is(err, errs.ErrUsage) {
+		t.Fatalf("ec2 resize to a1.large err = %v, want usage error", err)
+	}
+	if called(b.EC2.Calls(), "StopInstances") || called(b.EC2.Calls(), "ModifyInstanceAttribute") {
+		t.Errorf("ec2 resize changed the instance: %q", b.EC2.Calls())
+	}
+}
+
+func TestEC2ListRegionOrder(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+
+	out, err := run(t, "", "ec2", "list", "--regions", "us-west-2,eu-west-1,us-east-1", "--columns", "region", "--no-header", "state=running")
+	if err != nil {
+		t.Fatalf("ec2 list err: %s", err)
+	}
+	if got, want := strings.Fields(out), []string{"us-west-2", "eu-west-1", "us-east-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ec2 list regions = %q, want %q", got, want)
+	}
+}
+
+func TestEC2Exec(t *testing.T) {
+	b := newBackend(t)
+	seedInstances(b)
+	b.EC2.AddInstance(fake.Instance("i-0000000000000004d", "web-2"))
+	b.SSM.RunShellScript = func(id string, commands []string) (string, string, int64) {
+		if id == "i-0000000000000004d" {
+			return "", "boom\n", 3
+		}
+		return "up 3 days\n", "", 0
+	}
+
+	// a single instance is confirmed too
+	_, err := run(t, "n\n", "ec2", "exec", "web-1", "--", "uptime")
+	if !errors.Is(err, errs.ErrAborted) || called(b.SSM.Calls(), "SendCommand") {
+		t.Fatalf("declined ec2 exec err = %v, SendCommand called = %t", err, called(b.SSM.Calls(), "SendCommand"))
+	}
+	out, err := run(t, "", "ec2", "exec", "--dry-run", "web-1", "--", "uptime")
+	if err != nil || !strings.Contains(out, "dry-run: SendCommand") || called(b.SSM.Calls(), "SendCommand") {
+		t.Fatalf("ec2 exec --dry-run err = %v, output:\n%s", err, out)
+	}
+
+	out, err = run(t, "y\n", "ec2", "exec", "web-1", "--", "uptime")
+	if err != nil {
+		t.Fatalf("ec2 exec err: %s", err)
+	}
+	if !strings.HasSuffix(out, "web-1 | up 3 days\n") {
+		t.Errorf("ec2 exec output = %q", out)
+	}
+
+	// a single instance exits with the command's status
+	_, err = run(t, "", "--yes", "ec2", "exec", "web-2", "--", "uptime")
+	if code := errs.ExitCode(err); code != 3 {
+		t.Errorf("ec2 exec exit code = %d (%v), want 3", code, err)
+	}
+
+	_, err = run(t, "", "--yes", "ec2", "exec", "web-1", "web-2", "--", "uptime")
+	if code := errs.ExitCode(err); code != errs.ExitPartialFailure {
+		t.Errorf("ec2 exec on two instances exit code = %d (%v), want %d", code, err, errs.ExitPartialFailure)
+	}
+
+	var gets, lists int
+	for _, c := range b.SSM.Calls() {
+		switch c {
+		case "GetCommandInvocation":
+			gets++
+		case "ListCommandInvocations":
+			lists++
+		}
+	}
+	// output is fetched once per instance; status is polled per command
+	if gets != 4 || lists != 3 {
+		t.Errorf("GetCommandInvocation called %d times and ListCommandInvocations %d, want 4 and 3", gets, lists)
+	}
+}
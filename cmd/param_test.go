@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/errs"
+)
+
+func TestParamPut(t *testing.T) {
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		err   error
+		put   bool
+	}{
+		{"confirmed", "y\n", nil, nil, true},
+		{"declined", "n\n", nil, errs.ErrAborted, false},
+		{"dry run", "y\n", []string{"--dry-run"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBackend(t)
+			b.SSM.PutParam("/app/mode", "String", "blue")
+
+			args := append([]string{"param", "put", "/app/mode", "green", "--type", "String"}, tt.args...)
+			out, err := run(t, tt.stdin, args...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("param put err = %v, want %v", err, tt.err)
+			}
+			if !strings.Contains(out, "param /app/mode: blue => green") {
+				t.Errorf("param put didn't print the change:\n%s", out)
+			}
+
+			want := "blue"
+			if tt.put {
+				want = "green"
+			}
+			if got := aws.StringValue(b.SSM.Parameters["/app/mode"].Value); got != want {
+				t.Errorf("/app/mode = %q, want %q", got, want)
+			}
+			if got := called(b.SSM.Calls(), "PutParameter"); got != tt.put {
+				t.Errorf("PutParameter called = %t, want %t", got, tt.put)
+			}
+
+			if tt.name == "dry run" {
+				if !strings.Contains(out, "dry-run: PutParameter") || !strings.Contains(out, `"Value": "green"`) {
+					t.Errorf("param put --dry-run didn't print the request:\n%s", out)
+				}
+				if strings.Contains(out, "[yN]") {
+					t.Errorf("param put --dry-run prompted:\n%s", out)
+				}
+			}
+		})
+	}
+}
+
+func TestParamPutSecureString(t *testing.T) {
+	const secret = "hunter2"
+
+	t.Run("dry run", func(t *testing.T) {
+		newBackend(t)
+
+		out, err := run(t, "", "param", "put", "/db/password", secret, "--type", "SecureString", "--dry-run")
+		if err != nil {
+			t.Fatalf("param put err: %s", err)
+		}
+		dryRun := out[strings.Index(out, "dry-run:"):]
+		if strings.Contains(dryRun, secret) || !strings.Contains(dryRun, audit.Redacted) {
+			t.Errorf("param put --dry-run didn't mask the SecureString value:\n%s", dryRun)
+		}
+	})
+
+	t.Run("audit log", func(t *testing.T) {
+		b := newBackend(t)
+
+		if _, err := run(t, "y\n", "param", "put", "/db/password", secret, "--type", "SecureString"); err != nil {
+			t.Fatalf("param put err: %s", err)
+		}
+		if got := aws.StringValue(b.SSM.Parameters["/db/password"].Value); got != secret {
+			t.Errorf("/db/password = %q, want %q", got, secret)
+		}
+
+		entries := auditLog(t)
+		if len(entries) != 1 {
+			t.Fatalf("audit log = %+v, want one entry", entries)
+		}
+		line, _ := json.Marshal(entries[0])
+		if strings.Contains(string(line), secret) {
+			t.Errorf("audit log contains the secret: %s", line)
+		}
+		if entries[0].Command != "aws-buddy param put --type" {
+			t.Errorf("audit command = %q, want %q", entries[0].Command, "aws-buddy param put --type")
+		}
+	})
+}
@@ -0,0 +1,286 @@
+// Package completion provides shell completion functions for AWS resource
+// identifiers. Results are cached on disk for a short time so repeated
+// tab presses don't each wait on an API call.
+package completion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/spf13/cobra"
+)
+
+// TTL is how long completion results are cached.
+var TTL = 2 * time.Minute
+
+type cacheEntry struct {
+	Time   time.Time `json:"time"`
+	Values []string  `json:"values"`
+}
+
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aws-buddy", "completion"), nil
+}
+
+// cached returns the values for key from the on-disk cache, calling fetch
+// and storing the result if the entry is missing or stale. Keys are scoped
+// to the credential source (profile, role and endpoints) and region.
+func cached(sess *session.Session, key string, fetch func() ([]string, error)) ([]string, error) {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s", config.CredentialSource(), aws.StringValue(sess.Config.Region), key)))
+
+	dir, err := cacheDir()
+	if err != nil {
+		return fetch()
+	}
+	p := filepath.Join(dir, hex.EncodeToString(h[:])+".json")
+
+	if data, err := os.ReadFile(p); err == nil {
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err == nil && time.Since(entry.Time) < TTL {
+			return entry.Values, nil
+		}
+	}
+
+	values, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(cacheEntry{Time: time.Now(), Values: values})
+	if err == nil && os.MkdirAll(dir, 0700) == nil {
+		os.WriteFile(p, data, 0600)
+	}
+
+	return values, nil
+}
+
+// complete wraps a value lister as a cobra completion function for the
+// positional arguments in positions (all positions if none are given).
+// Values may carry a description after a tab.
+func complete(list func(ctx context.Context, sess *session.Session, toComplete string) ([]string, error), positions ...int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(positions) > 0 {
+			var match bool
+			for _, pos := range positions {
+				if pos == len(args) {
+					match = true
+				}
+			}
+			if !match {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+		}
+
+		// PersistentPreRun doesn't run for completions
+		if err := config.ApplyFlagDefaults(cmd); err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveError
+		}
+		sess, err := config.NewSession()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		values, err := list(cmd.Context(), sess, toComplete)
+		if err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		return filter(values, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func filter(values []string, prefix string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Instances completes instance IDs and Name tags.
+func Instances(positions ...int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return complete(func(ctx context.Context, sess *session.Session, toComplete string) ([]string, error) {
+		return cached(sess, "instances", func() ([]string, error) {
+			var values []string
+			svc := client.EC2(sess)
+			err := svc.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{}, func(resp *ec2.DescribeInstancesOutput, lastPage bool) bool {
+				for _, res := range resp.Reservations {
+					for _, inst := range res.Instances {
+						var name string
+						for _, t := range inst.Tags {
+							if aws.StringValue(t.Key) == "Name" {
+								name = aws.StringValue(t.Value)
+							}
+						}
+						id, state := aws.StringValue(inst.InstanceId), aws.StringValue(inst.State.Name)
+						values = append(values, fmt.Sprintf("%s\t%s (%s)", id, name, state))
+						if name != "" && state != ec2.InstanceStateNameTerminated {
+							values = append(values, fmt.Sprintf("%s\t%s (%s)", name, id, state))
+						}
+					}
+				}
+				return true
+			})
+			return values, err
+		})
+	}, positions...)
+}
+
+// SecurityGroups completes security group IDs and names.
+func SecurityGroups(positions ...int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return complete(func(ctx context.Context, sess *session.Session, toComplete string) ([]string, error) {
+		return cached(sess, "security-groups", func() ([]string, error) {
+			var values []string
+			svc := client.EC2(sess)
+			err := svc.DescribeSecurityGroupsPagesWithContext(ctx, &ec2.DescribeSecurityGroupsInput{}, func(resp *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+				for _, sg := range resp.SecurityGroups {
+					id, name := aws.StringValue(sg.GroupId), aws.StringValue(sg.GroupName)
+					values = append(values, fmt.Sprintf("%s\t%s", id, name))
+					values = append(values, fmt.Sprintf("%s\t%s", name, id))
+				}
+				return true
+			})
+			return values, err
+		})
+	}, positions...)
+}
+
+// Params completes SSM parameter names.
+func Params(positions ...int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return complete(func(ctx context.Context, sess *session.Session, toComplete string) ([]string, error) {
+		return cached(sess, "params", func() ([]string, error) {
+			var values []string
+			svc := client.SSM(sess)
+			err := svc.DescribeParametersPagesWithContext(ctx, &ssm.DescribeParametersInput{}, func(resp *ssm.DescribeParametersOutput, lastPage bool) bool {
+				for _, p := range resp.Parameters {
+					values = append(values, aws.StringValue(p.Name))
+				}
+				return true
+			})
+			return values, err
+		})
+	}, positions...)
+}
+
+// Queues completes SQS queue URLs.
+func Queues(positions ...int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return complete(func(ctx context.Context, sess *session.Session, toComplete string) ([]string, error) {
+		return cached(sess, "queues", func() ([]string, error) {
+			var values []string
+			svc := client.SQS(sess)
+			// SQS only pages when MaxResults is set
+			err := svc.ListQueuesPagesWithContext(ctx, &sqs.ListQueuesInput{MaxResults: aws.Int64(1000)}, func(resp *sqs.ListQueuesOutput, lastPage bool) bool {
+				values = append(values, aws.StringValueSlice(resp.QueueUrls)...)
+				return true
+			})
+			return values, err
+		})
+	}, positions...)
+}
+
+// Accounts completes organization account IDs, described by account name.
+func Accounts(positions ...int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return complete(func(ctx context.Context, sess *session.Session, toComplete string) ([]string, error) {
+		return cached(sess, "accounts", func() ([]string, error) {
+			var values []string
+			svc := client.Organizations(sess)
+			err := svc.ListAccountsPagesWithContext(ctx, &organizations.ListAccountsInput{}, func(resp *organizations.ListAccountsOutput, lastPage bool) bool {
+				for _, a := range resp.Accounts {
+					values = append(values, fmt.Sprintf("%s\t%s", aws.StringValue(a.Id), aws.StringValue(a.Name)))
+				}
+				return true
+			})
+			return values, err
+		})
+	}, positions...)
+}
+
+// S3Paths completes bucket names, then the prefixes and keys one level
+// below the path typed so far.
+func S3Paths(positions ...int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	list := complete(func(ctx context.Context, sess *session.Session, toComplete string) ([]string, error) {
+		scheme := ""
+		if strings.HasPrefix(toComplete, "s3://") {
+			scheme = "s3://"
+		}
+		bucketPath := strings.TrimPrefix(toComplete, "s3://")
+
+		svc := client.S3(sess)
+
+		bucket, prefix, found := strings.Cut(bucketPath, "/")
+		if !found {
+			return cached(sess, "s3-buckets "+scheme, func() ([]string, error) {
+				resp, err := svc.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+				if err != nil {
+					return nil, err
+				}
+				values := make([]string, 0, len(resp.Buckets))
+				for _, b := range resp.Buckets {
+					values = append(values, scheme+aws.StringValue(b.Name)+"/")
+				}
+				return values, nil
+			})
+		}
+
+		// list the directory containing the partial key
+		if i := strings.LastIndex(prefix, "/"); i >= 0 {
+			prefix = prefix[:i+1]
+		} else {
+			prefix = ""
+		}
+
+		base := scheme + bucket + "/"
+		return cached(sess, "s3-objects "+base+prefix, func() ([]string, error) {
+			var values []string
+			input := &s3.ListObjectsV2Input{
+				Bucket:    &bucket,
+				Prefix:    &prefix,
+				Delimiter: aws.String("/"),
+			}
+			err := svc.ListObjectsV2PagesWithContext(ctx, input, func(resp *s3.ListObjectsV2Output, lastPage bool) bool {
+				for _, p := range resp.CommonPrefixes {
+					values = append(values, base+aws.StringValue(p.Prefix))
+				}
+				for _, obj := range resp.Contents {
+					values = append(values, base+aws.StringValue(obj.Key))
+				}
+				return len(values) < 1000
+			})
+			return values, err
+		})
+	}, positions...)
+
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		values, directive := list(cmd, args, toComplete)
+		if directive == cobra.ShellCompDirectiveNoFileComp {
+			// don't add a space after a bucket or prefix so the user can
+			// keep completing
+			directive |= cobra.ShellCompDirectiveNoSpace
+		}
+		return values, directive
+	}
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/errs"
+)
+
+var (
+	// RoleARN, Account, RoleName and MFASerial are set from the global
+	// --role-arn, --account, --role and --mfa-serial flags.
+	RoleARN   string
+	Account   string
+	RoleName  string
+	MFASerial string
+)
+
+// RoleDuration is how long assumed role credentials are requested for.
+var RoleDuration = time.Hour
+
+// roleSessionName names assumed role sessions after the local user and
+// host, so CloudTrail shows who assumed the role.
+func roleSessionName() string {
+	name := "aws-buddy"
+	if u, err := user.Current(); err == nil {
+		name += "-" + u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+	// RoleSessionName allows [\w+=,.@-] and at most 64 characters
+	name = sessionNameRegex.ReplaceAllString(name, "-")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+var sessionNameRegex = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// refresh cached credentials a little before they actually expire
+const credsExpiryWindow = 5 * time.Minute
+
+// Session is called many times per command, so the caller's account and
+// assumed role credentials are remembered for the life of the process.
+var (
+	roleMu         sync.Mutex
+	callerAccounts = make(map[string]string)
+	roleCreds      = make(map[string]*credentials.Credentials)
+)
+
+// targetRoleARN returns the role the flags ask to assume, or "" if none.
+// --role without --account assumes the role in the caller's own account.
+func targetRoleARN(sess *awssession.Session) (string, error) {
+	if RoleARN != "" {
+		if Account != "" || RoleName != "" {
+			return "", fmt.Errorf("%w: --role-arn can't be combined with --account or --role", errs.ErrUsage)
+		}
+		return RoleARN, nil
+	}
+	if RoleName == "" {
+		if Account != "" {
+			return "", fmt.Errorf("%w: --account requires --role", errs.ErrUsage)
+		}
+		return "", nil
+	}
+
+	account := Account
+	if account == "" {
+		var err error
+		account, err = callerAccount(sess)
+		if err != nil {
+			return "", fmt.Errorf("lookup account id for --role: %w", err)
+		}
+	}
+	if !accountIDRegex.MatchString(account) {
+		return "", fmt.Errorf("%w: invalid --account %q: want a 12 digit account id", errs.ErrUsage, account)
+	}
+
+	partition := "aws"
+	if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), aws.StringValue(sess.Config.Region)); ok {
+		partition = p.ID()
+	}
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, account, RoleName), nil
+}
+
+func callerAccount(sess *awssession.Session) (string, error) {
+	roleMu.Lock()
+	defer roleMu.Unlock()
+
+	if account, ok := callerAccounts[Profile]; ok {
+		return account, nil
+	}
+	ident, err := client.STS(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	callerAccounts[Profile] = aws.StringValue(ident.Account)
+	return callerAccounts[Profile], nil
+}
+
+// AssumeRole returns credentials for roleARN, assumed using sess's
+// credentials. If --mfa-serial is set the token code is prompted for on
+// the terminal. Credentials are cached on disk until shortly before they
+// expire, so repeated commands don't call STS (or prompt) each time.
+func AssumeRole(sess *awssession.Session, roleARN string) *credentials.Credentials {
+	roleMu.Lock()
+	defer roleMu.Unlock()
+
+	key := fmt.Sprintf("%s\x00%s\x00%s", Profile, MFASerial, roleARN)
+	if creds, ok := roleCreds[key]; ok {
+		return creds
+	}
+
+	provider := &stscreds.AssumeRoleProvider{
+		Client:          client.STS(sess.Copy()),
+		RoleARN:         roleARN,
+		RoleSessionName: roleSessionName(),
+		Duration:        RoleDuration,
+	}
+	if MFASerial != "" {
+		provider.SerialNumber = aws.String(MFASerial)
+		provider.TokenProvider = stscreds.StdinTokenProvider
+	}
+
+	var creds *credentials.Credentials
+	if RecordDir != "" || ReplayDir != "" {
+		// recorded and replayed sessions should always talk to STS
+		creds = credentials.NewCredentials(provider)
+	} else {
+		creds = credentials.NewCredentials(&cachedRoleProvider{
+			key:      roleARN,
+			provider: provider,
+		})
+	}
+	roleCreds[key] = creds
+	return creds
+}
+
+// cachedRoleProvider wraps an AssumeRoleProvider with an on-disk cache.
+type cachedRoleProvider struct {
+	credentials.Expiry
+
+	key      string
+	provider *stscreds.AssumeRoleProvider
+}
+
+type cachedCreds struct {
+	AccessKeyID     string    `json:"access_key_id"`
+	SecretAccessKey string    `json:"secret_access_key"`
+	SessionToken    string    `json:"session_token"`
+	Expiration      time.Time `json:"expiration"`
+}
+
+func (p *cachedRoleProvider) Retrieve() (credentials.Value, error) {
+	path, pathErr := p.cachePath()
+	if pathErr == nil {
+		data, err := os.ReadFile(path)
+		var c cachedCreds
+		if err == nil && json.Unmarshal(data, &c) == nil && time.Until(c.Expiration) > credsExpiryWindow {
+			p.SetExpiration(c.Expiration, credsExpiryWindow)
+			return credentials.Value{
+				AccessKeyID:     c.AccessKeyID,
+				SecretAccessKey: c.SecretAccessKey,
+				SessionToken:    c.SessionToken,
+				ProviderName:    stscreds.ProviderName,
+			}, nil
+		}
+	}
+
+	v, err := p.provider.Retrieve()
+	if err != nil {
+		return v, err
+	}
+	expiration := p.provider.ExpiresAt()
+	p.SetExpiration(expiration, credsExpiryWindow)
+
+	if pathErr == nil {
+		data, _ := json.Marshal(cachedCreds{
+			AccessKeyID:     v.AccessKeyID,
+			SecretAccessKey: v.SecretAccessKey,
+			SessionToken:    v.SessionToken,
+			Expiration:      expiration,
+		})
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err == nil {
+			os.WriteFile(path, data, 0600)
+		}
+	}
+
+	return v, nil
+}
+
+// cachePath returns the cache file for the role, scoped to the source
+// profile (or environment credentials) and MFA device used to assume it.
+func (p *cachedRoleProvider) cachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	profile := Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%s", profile, os.Getenv("AWS_ACCESS_KEY_ID"), MFASerial, p.key)))
+
+	return filepath.Join(dir, "aws-buddy", "credentials", fmt.Sprintf("%x.json", h[:16])), nil
+}
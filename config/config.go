@@ -1,29 +1,230 @@
 package config
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	awsclient "github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
 	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/replay"
 )
 
 var DefaultRegion = "us-east-1"
 
+var (
+	// Profile and Region are set from the global --profile and --region flags.
+	// When empty the usual AWS_PROFILE/AWS_REGION/AWS_DEFAULT_REGION
+	// environment variables and shared config are used.
+	Profile string
+	Region  string
+
+	// EndpointURLs are set from the global --endpoint-url flag. Each is
+	// either a URL used for every service or service=URL to override a
+	// single service (by endpoint id, e.g. s3, sqs, ssm, ec2).
+	EndpointURLs []string
+
+	// RecordDir and ReplayDir are set from the global --record and --replay
+	// flags.
+	RecordDir string
+	ReplayDir string
+
+	// MaxRetries and MaxRPS are set from the global --max-retries and
+	// --max-rps flags. Throttled and failed requests are retried with
+	// exponential backoff and jitter; MaxRPS of 0 means no rate limit.
+	MaxRetries int
+	MaxRPS     float64
+)
+
+var (
+	transportOnce sync.Once
+	transport     http.RoundTripper
+	transportErr  error
+)
+
+// httpTransport returns the shared record or replay transport, or nil if
+// neither is enabled. It is shared by every session so repeated requests
+// are numbered consistently. base is the transport the recorder sends
+// requests with.
+func httpTransport(base http.RoundTripper) (http.RoundTripper, error) {
+	transportOnce.Do(func() {
+		switch {
+		case RecordDir != "" && ReplayDir != "":
+			transportErr = fmt.Errorf("%w: --record and --replay are mutually exclusive", errs.ErrUsage)
+		case RecordDir != "":
+			transport, transportErr = replay.NewRecorder(RecordDir, base)
+		case ReplayDir != "":
+			transport, transportErr = replay.NewReplayer(ReplayDir)
+		}
+	})
+	return transport, transportErr
+}
+
 func Session() *awssession.Session {
-	region := DefaultRegion
-	if os.Getenv("AWS_DEFAULT_REGION") != "" {
-		region = os.Getenv("AWS_DEFAULT_REGION")
+	sess, err := NewSession()
+	if err != nil {
+		panic(err)
+	}
+	return sess
+}
+
+func NewSession() (*awssession.Session, error) {
+	var cfg aws.Config
+	if Region != "" {
+		cfg.Region = aws.String(Region)
+	}
+
+	if len(EndpointURLs) > 0 {
+		resolver, overridesS3, err := endpointResolver(EndpointURLs)
+		if err != nil {
+			return nil, err
+		}
+		cfg.EndpointResolver = resolver
+		if overridesS3 {
+			// emulators like MinIO and LocalStack don't do virtual host style buckets
+			cfg.S3ForcePathStyle = aws.Bool(true)
+		}
+	}
+
+	if MaxRetries < 0 || MaxRPS < 0 {
+		return nil, fmt.Errorf("%w: --max-retries and --max-rps must not be negative", errs.ErrUsage)
+	}
+
+	if ReplayDir != "" {
+		// don't look for real credentials or retry missing fixtures
+		cfg.Credentials = credentials.NewStaticCredentials("replay", "replay", "")
+		cfg.MaxRetries = aws.Int(0)
+	} else {
+		cfg.Retryer = awsclient.DefaultRetryer{
+			NumMaxRetries:    MaxRetries,
+			MinRetryDelay:    100 * time.Millisecond,
+			MaxRetryDelay:    5 * time.Second,
+			MinThrottleDelay: 500 * time.Millisecond,
+			MaxThrottleDelay: 30 * time.Second,
+		}
 	}
 
 	sess, err := awssession.NewSessionWithOptions(awssession.Options{
 		SharedConfigState: awssession.SharedConfigEnable,
-		Config: aws.Config{
-			Region: &region,
-		},
+		Profile:           Profile,
+		Config:            cfg,
+
+		// for role profiles in ~/.aws/config with mfa_serial set
+		AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
 	})
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return sess
+	// set after the session is created so the SDK can still apply
+	// AWS_CA_BUNDLE to the underlying transport
+	base := http.DefaultTransport
+	if sess.Config.HTTPClient != nil && sess.Config.HTTPClient.Transport != nil {
+		base = sess.Config.HTTPClient.Transport
+	}
+	rt, err := httpTransport(base)
+	if err != nil {
+		return nil, err
+	}
+	if rt != nil {
+		sess.Config.HTTPClient = &http.Client{Transport: rt}
+	}
+
+	if MaxRPS > 0 {
+		sess.Handlers.Send.PushFrontNamed(request.NamedHandler{
+			Name: "awsbuddy.RateLimit",
+			Fn:   sharedRateLimiter(MaxRPS).handler,
+		})
+	}
+
+	if aws.StringValue(sess.Config.Region) == "" {
+		sess.Config.Region = aws.String(DefaultRegion)
+	}
+
+	roleARN, err := targetRoleARN(sess)
+	if err != nil {
+		return nil, err
+	}
+	if roleARN != "" {
+		sess.Config.Credentials = AssumeRole(sess, roleARN)
+	}
+
+	return sess, nil
+}
+
+// CredentialSource identifies where requests' credentials and endpoints
+// come from: the profile or environment keys, any role assumed with
+// --role-arn or --account/--role, and --endpoint-url. Caches of API
+// results include it in their keys so one account's results aren't
+// returned for another.
+func CredentialSource() string {
+	profile := Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	return strings.Join([]string{
+		profile,
+		os.Getenv("AWS_ACCESS_KEY_ID"),
+		RoleARN,
+		Account,
+		RoleName,
+		strings.Join(EndpointURLs, ","),
+	}, "\x00")
+}
+
+// ResolvedRegion returns the region commands will run against.
+func ResolvedRegion() string {
+	return aws.StringValue(Session().Config.Region)
+}
+
+// endpointResolver returns a resolver for the --endpoint-url values and
+// whether they apply to s3.
+func endpointResolver(urls []string) (endpoints.Resolver, bool, error) {
+	var (
+		defaultURL string
+		byService  = make(map[string]string)
+	)
+	for _, v := range urls {
+		service, u, found := strings.Cut(v, "=")
+		if !found {
+			service, u = "", v
+		}
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, false, fmt.Errorf("%w: invalid --endpoint-url %q: want URL or service=URL", errs.ErrUsage, v)
+		}
+		if service == "" {
+			defaultURL = u
+		} else {
+			byService[service] = u
+		}
+	}
+
+	overridesS3 := defaultURL != "" || byService[endpoints.S3ServiceID] != ""
+
+	resolver := endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		u, ok := byService[service]
+		if !ok {
+			u = defaultURL
+		}
+		if u == "" {
+			return endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+		}
+		return endpoints.ResolvedEndpoint{
+			URL:           u,
+			SigningRegion: region,
+		}, nil
+	})
+
+	return resolver, overridesS3, nil
 }
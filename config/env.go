@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// CredentialEnvNames are the variables replaced when handing credentials
+// to another process, so the SDK or cli it runs can't pick up a different
+// identity from a leftover profile or session token.
+var CredentialEnvNames = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_SECURITY_TOKEN",
+	"AWS_PROFILE",
+	"AWS_DEFAULT_PROFILE",
+	"AWS_REGION",
+	"AWS_DEFAULT_REGION",
+}
+
+// CredentialVars returns the environment variables, as KEY=value, that
+// hand creds and region to another AWS tool.
+func CredentialVars(creds credentials.Value, region string) []string {
+	vars := []string{
+		"AWS_ACCESS_KEY_ID=" + creds.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + creds.SecretAccessKey,
+	}
+	if creds.SessionToken != "" {
+		vars = append(vars, "AWS_SESSION_TOKEN="+creds.SessionToken)
+	}
+	return append(vars,
+		"AWS_REGION="+region,
+		"AWS_DEFAULT_REGION="+region,
+	)
+}
+
+// CredentialEnv returns environ with any AWS credential, profile and
+// region variables replaced by CredentialVars(creds, region).
+func CredentialEnv(environ []string, creds credentials.Value, region string) []string {
+	env := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		var replaced bool
+		for _, n := range CredentialEnvNames {
+			if n == name {
+				replaced = true
+			}
+		}
+		if !replaced {
+			env = append(env, kv)
+		}
+	}
+	return append(env, CredentialVars(creds, region)...)
+}
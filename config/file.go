@@ -0,0 +1,300 @@
+package config
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// FileName is the config file to load, set from the global --config flag.
+// When empty, config.yml in Dir() is used.
+var FileName string
+
+// File is the aws-buddy config file. Keys in each section are flag names;
+// a nested map keyed by command path (e.g. "ec2 launch_template") only
+// applies to that command and its subcommands:
+//
+//	defaults:
+//	  aggregator-name: AllAccounts
+//	profiles:
+//	  work:
+//	    region: us-west-2
+//	    textract analyze:
+//	      bucket: work-textract
+//	  "123456789012":
+//	    security-group-name: ssh-from-vpn
+//	  localstack:
+//	    endpoint-url:
+//	      - http://localhost:4566
+//	      - s3=http://localhost:9000
+//
+// Profile sections are selected by the active AWS profile name or by the
+// 12 digit account ID of the current credentials.
+type File struct {
+	Defaults map[interface{}]interface{}                 `yaml:"defaults"`
+	Profiles map[interface{}]map[interface{}]interface{} `yaml:"profiles"`
+}
+
+// Dir returns the aws-buddy config directory, $XDG_CONFIG_HOME/aws-buddy
+// or ~/.config/aws-buddy.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "aws-buddy"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "aws-buddy"), nil
+}
+
+// LoadFile reads the config file. A missing default config file is not an
+// error.
+func LoadFile() (*File, error) {
+	name := FileName
+	if name == "" {
+		dir, err := Dir()
+		if err != nil {
+			return nil, err
+		}
+		name = filepath.Join(dir, "config.yml")
+	}
+
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) && FileName == "" {
+		return &File{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return &f, nil
+}
+
+var accountIDRegex = regexp.MustCompile(`\A[0-9]{12}\z`)
+
+// ApplyFlagDefaults sets any flags of cmd that were not given on the
+// command line from the config file. Values from the defaults section are
+// applied first, then the section for the active profile, then the section
+// for the current account ID.
+func ApplyFlagDefaults(cmd *cobra.Command) error {
+	f, err := LoadFile()
+	if err != nil {
+		return err
+	}
+
+	userSet := make(map[string]bool)
+	cmd.Flags().Visit(func(fl *pflag.Flag) {
+		userSet[fl.Name] = true
+	})
+
+	path := commandPath(cmd)
+
+	if err := applySection(cmd, userSet, path, f.Defaults); err != nil {
+		return fmt.Errorf("config defaults: %w", err)
+	}
+
+	profile := Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	var accountSections bool
+	for key, section := range f.Profiles {
+		name := fmt.Sprint(key)
+		if accountIDRegex.MatchString(name) {
+			accountSections = accountSections || applies(cmd, userSet, path, section)
+			continue
+		}
+		if name == profile {
+			if err := applySection(cmd, userSet, path, section); err != nil {
+				return fmt.Errorf("config profile %s: %w", name, err)
+			}
+		}
+	}
+
+	// only look up the account when one of its sections could set a flag
+	// of this command
+	if !accountSections {
+		return nil
+	}
+
+	sess, err := NewSession()
+	if err != nil {
+		return err
+	}
+	account, err := SessionAccount(sess)
+	if err != nil {
+		return fmt.Errorf("config: lookup account id: %w", err)
+	}
+	for key, section := range f.Profiles {
+		if fmt.Sprint(key) == account {
+			if err := applySection(cmd, userSet, path, section); err != nil {
+				return fmt.Errorf("config profile %s: %w", account, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SessionAccount returns the account id of sess's credentials. Lookups
+// are cached on disk by access key id, since the account a key belongs to
+// never changes, so most commands don't need to call STS for it.
+func SessionAccount(sess *awssession.Session) (string, error) {
+	if RoleARN != "" {
+		if a, err := arn.Parse(RoleARN); err == nil {
+			return a.AccountID, nil
+		}
+	}
+	if Account != "" && RoleName != "" {
+		return Account, nil
+	}
+
+	creds, err := sess.Config.Credentials.Get()
+	if err != nil {
+		return "", err
+	}
+
+	// recorded and replayed sessions should always talk to STS
+	var path string
+	if dir, err := os.UserCacheDir(); err == nil && RecordDir == "" && ReplayDir == "" {
+		h := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s", creds.AccessKeyID, strings.Join(EndpointURLs, "\x00"))))
+		path = filepath.Join(dir, "aws-buddy", "accounts", fmt.Sprintf("%x", h[:16]))
+		if data, err := os.ReadFile(path); err == nil && accountIDRegex.Match(data) {
+			return string(data), nil
+		}
+	}
+
+	ident, err := client.STS(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	account := aws.StringValue(ident.Account)
+
+	if path != "" && os.MkdirAll(filepath.Dir(path), 0700) == nil {
+		os.WriteFile(path, []byte(account), 0600)
+	}
+	return account, nil
+}
+
+// commandPath returns the path of cmd without the root command name,
+// e.g. "ec2 launch_template".
+func commandPath(cmd *cobra.Command) string {
+	parts := strings.Fields(cmd.CommandPath())
+	if len(parts) > 0 {
+		parts = parts[1:]
+	}
+	return strings.Join(parts, " ")
+}
+
+// applies reports whether section would set any flag of cmd that wasn't
+// given on the command line.
+func applies(cmd *cobra.Command, userSet map[string]bool, path string, section map[interface{}]interface{}) bool {
+	set := func(name string) bool {
+		return cmd.Flags().Lookup(name) != nil && !userSet[name]
+	}
+	for key, val := range section {
+		name := fmt.Sprint(key)
+		scoped, ok := val.(map[interface{}]interface{})
+		if !ok {
+			if set(name) {
+				return true
+			}
+			continue
+		}
+		if path != name && !strings.HasPrefix(path, name+" ") {
+			continue
+		}
+		for key := range scoped {
+			if set(fmt.Sprint(key)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func applySection(cmd *cobra.Command, userSet map[string]bool, path string, section map[interface{}]interface{}) error {
+	type scope struct {
+		name  string
+		flags map[interface{}]interface{}
+	}
+	var scopes []scope
+	for key, val := range section {
+		name := fmt.Sprint(key)
+		if flags, ok := val.(map[interface{}]interface{}); ok {
+			if path == name || strings.HasPrefix(path, name+" ") {
+				scopes = append(scopes, scope{name, flags})
+			}
+			continue
+		}
+		if err := setFlag(cmd, userSet, name, val); err != nil {
+			return err
+		}
+	}
+
+	// apply less specific scopes first so the closest match wins
+	sort.Slice(scopes, func(i, j int) bool {
+		return len(scopes[i].name) < len(scopes[j].name)
+	})
+	for _, s := range scopes {
+		for key, val := range s.flags {
+			if err := setFlag(cmd, userSet, fmt.Sprint(key), val); err != nil {
+				return fmt.Errorf("%s: %w", s.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func setFlag(cmd *cobra.Command, userSet map[string]bool, name string, val interface{}) error {
+	fl := cmd.Flags().Lookup(name)
+	if fl == nil || userSet[name] {
+		// not a flag of this command, or given on the command line
+		return nil
+	}
+
+	var vals []string
+	if list, ok := val.([]interface{}); ok {
+		for _, v := range list {
+			vals = append(vals, fmt.Sprint(v))
+		}
+	} else {
+		vals = []string{fmt.Sprint(val)}
+	}
+
+	if sv, ok := fl.Value.(pflag.SliceValue); ok {
+		if err := sv.Replace(vals); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	} else {
+		for _, v := range vals {
+			if err := fl.Value.Set(v); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	fl.Changed = true
+	return nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// rateLimiter spaces requests at least interval apart. One limiter is
+// shared by every session so --max-rps applies to the whole process,
+// including commands that fan out across regions.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+var (
+	limiterOnce sync.Once
+	limiter     *rateLimiter
+)
+
+func sharedRateLimiter(rps float64) *rateLimiter {
+	limiterOnce.Do(func() {
+		limiter = &rateLimiter{
+			interval: time.Duration(float64(time.Second) / rps),
+		}
+	})
+	return limiter
+}
+
+// reserve returns how long to wait before sending the next request.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	return wait
+}
+
+// handler waits for the request's turn. It runs as a Send handler so
+// retries are rate limited too.
+func (l *rateLimiter) handler(r *request.Request) {
+	wait := l.reserve()
+	if wait <= 0 {
+		return
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-r.Context().Done():
+		r.Error = awserr.New(request.CanceledErrorCode, "request context canceled", r.Context().Err())
+	}
+}
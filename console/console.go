@@ -1,10 +1,43 @@
 package console
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+var (
+	// AssumeYes answers yes to every Confirm prompt and skips the
+	// grace period (--yes).
+	AssumeYes bool
+
+	// DryRun makes mutating commands print the request they would send
+	// instead of sending it (--dry-run).
+	DryRun bool
+
+	// ShowBanner prints Banner above every Confirm prompt (--banner), so
+	// it's clear which account a change is about to be made in.
+	ShowBanner bool
+	Banner     func() string
+
+	// In and Out are where Confirm reads answers from and where prompts
+	// and dry-run requests are written.
+	In  io.Reader = os.Stdin
+	Out io.Writer = os.Stdout
+
+	// GraceDelay is how long GracePeriod waits.
+	GraceDelay = 3 * time.Second
 )
 
+// FormatTable aligns rows into " | " separated columns. Widths are
+// counted in runes, so multibyte values like shortType's symbols line up.
 func FormatTable(rows [][]string) string {
 	if len(rows) == 0 {
 		return ""
@@ -12,7 +45,7 @@ func FormatTable(rows [][]string) string {
 	widths := make([]int, len(rows[0]))
 	for _, row := range rows {
 		for i, s := range row {
-			size := len(s)
+			size := utf8.RuneCountInString(s)
 			if size > widths[i] {
 				widths[i] = size
 			}
@@ -22,7 +55,7 @@ func FormatTable(rows [][]string) string {
 	var out strings.Builder
 	for _, row := range rows {
 		for colIdx, s := range row {
-			size := len(s)
+			size := utf8.RuneCountInString(s)
 			pad := widths[colIdx] - size
 			out.WriteString(s)
 			padStr := strings.Repeat(" ", pad)
@@ -36,10 +69,85 @@ func FormatTable(rows [][]string) string {
 	return out.String()
 }
 
-func Confirm(prompt string) bool {
-	fmt.Print(prompt)
-	var result string
-	fmt.Scanln(&result)
+// Confirm asks prompt and reports whether the answer was yes. It returns
+// false if ctx is canceled while waiting for an answer.
+func Confirm(ctx context.Context, prompt string) bool {
+	if ShowBanner && Banner != nil {
+		if b := Banner(); b != "" {
+			fmt.Fprintln(Out, b)
+		}
+	}
+	fmt.Fprint(Out, prompt)
+	if AssumeYes {
+		fmt.Fprintln(Out, "y (--yes)")
+		return true
+	}
+
+	answer := make(chan string, 1)
+	go func() {
+		var result string
+		fmt.Fscanln(In, &result)
+		answer <- result
+	}()
+
+	select {
+	case result := <-answer:
+		return result == "y" || result == "Y" || result == "yes" || result == "Yes"
+	case <-ctx.Done():
+		fmt.Fprintln(Out)
+		return false
+	}
+}
+
+// GracePeriod gives the user a few seconds to reconsider and ctrl-c after
+// confirming a change. It is skipped with --yes, and returns ctx's error
+// if canceled.
+func GracePeriod(ctx context.Context) error {
+	if AssumeYes {
+		return nil
+	}
+	return aws.SleepWithContext(ctx, GraceDelay)
+}
+
+// PrintDryRun prints the request a mutating command would have sent as
+// JSON, leaving out unset fields. Unlike awsutil.Prettify, fields the SDK
+// marks sensitive are printed as is; callers mask any values (such as
+// SecureString parameters) that shouldn't be shown.
+func PrintDryRun(op string, input interface{}) {
+	fmt.Fprintf(Out, "dry-run: %s\n%s\n", op, dryRunJSON(input))
+}
+
+func dryRunJSON(input interface{}) string {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Sprintf("%+v", input)
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	out, err := json.MarshalIndent(dropNulls(v), "", "  ")
+	if err != nil {
+		return string(raw)
+	}
+	return string(out)
+}
 
-	return result == "y" || result == "Y" || result == "yes" || result == "Yes"
+// dropNulls removes the nil fields an SDK input struct marshals as null.
+func dropNulls(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if val == nil {
+				delete(v, k)
+			} else {
+				v[k] = dropNulls(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = dropNulls(val)
+		}
+	}
+	return v
 }
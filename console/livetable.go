@@ -0,0 +1,57 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// LiveTable redraws a table in place on stderr as its rows change, e.g.
+// while waiting for instances to change state. Like Progress, nothing is
+// drawn when stderr isn't a terminal.
+type LiveTable struct {
+	mu     sync.Mutex
+	out    io.Writer
+	lines  int
+	active bool
+}
+
+// NewLiveTable returns an empty LiveTable. Call Clear when finished.
+func NewLiveTable() *LiveTable {
+	return &LiveTable{
+		out:    os.Stderr,
+		active: term.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+// Set replaces the table's rows, the first of which is the header, and
+// redraws it.
+func (t *LiveTable) Set(rows [][]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.active {
+		return
+	}
+	t.clear()
+	table := FormatTable(rows)
+	fmt.Fprint(t.out, table)
+	t.lines = strings.Count(table, "\n")
+}
+
+// Clear erases the table.
+func (t *LiveTable) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clear()
+}
+
+func (t *LiveTable) clear() {
+	if t.active && t.lines > 0 {
+		fmt.Fprintf(t.out, "\x1b[%dA\r\x1b[J", t.lines)
+		t.lines = 0
+	}
+}
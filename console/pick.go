@@ -0,0 +1,207 @@
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/psanford/aws-buddy/errs"
+	"golang.org/x/term"
+)
+
+// Interactive reports whether stdin and stderr are both terminals, so
+// Pick can be used.
+func Interactive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+const pickHeight = 15
+
+// Pick shows a fuzzy finder over items on the terminal and returns the
+// index of the selected item. Typing filters the list, up/down (or
+// ctrl-p/ctrl-n) moves the selection and enter picks it. Esc or ctrl-c
+// returns errs.ErrAborted. The finder is drawn on stderr so stdout stays
+// clean for the command's output.
+func Pick(prompt string, items []string) (int, error) {
+	if len(items) == 0 {
+		return -1, fmt.Errorf("%w: nothing to pick from", errs.ErrNotFound)
+	}
+	if !Interactive() {
+		return -1, fmt.Errorf("%w: not a terminal", errs.ErrUsage)
+	}
+
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return -1, err
+	}
+	defer term.Restore(fd, oldState)
+
+	var (
+		in      = bufio.NewReader(os.Stdin)
+		out     = os.Stderr
+		query   []rune
+		cursor  int
+		matches = fuzzyFilter(items, "")
+	)
+
+	width, _, err := term.GetSize(int(out.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	clear := func() {
+		// return to the prompt line and clear everything below it
+		fmt.Fprint(out, "\r\x1b[J")
+	}
+
+	draw := func() {
+		clear()
+		header := fmt.Sprintf("%s %d/%d > ", prompt, len(matches), len(items))
+		fmt.Fprint(out, header, string(query))
+		n := len(matches)
+		if n > pickHeight {
+			n = pickHeight
+		}
+		start := 0
+		if cursor >= n {
+			start = cursor - n + 1
+		}
+		for i := start; i < start+n; i++ {
+			line := items[matches[i]]
+			if r := []rune(line); len(r) > width-2 {
+				line = string(r[:width-2])
+			}
+			if i == cursor {
+				fmt.Fprintf(out, "\r\n\x1b[7m> %s\x1b[0m", line)
+			} else {
+				fmt.Fprintf(out, "\r\n  %s", line)
+			}
+		}
+		if n > 0 {
+			fmt.Fprintf(out, "\x1b[%dA", n)
+		}
+		fmt.Fprintf(out, "\r\x1b[%dC", len([]rune(header))+len(query))
+	}
+
+	for {
+		draw()
+
+		r, _, err := in.ReadRune()
+		if err != nil {
+			clear()
+			return -1, err
+		}
+
+		switch r {
+		case 3, 7: // ctrl-c, ctrl-g
+			clear()
+			return -1, errs.ErrAborted
+		case 27: // esc, or the start of an arrow key sequence
+			if in.Buffered() == 0 {
+				clear()
+				return -1, errs.ErrAborted
+			}
+			seq := make([]byte, 2)
+			seq[0], _ = in.ReadByte()
+			seq[1], _ = in.ReadByte()
+			switch string(seq) {
+			case "[A":
+				cursor--
+			case "[B":
+				cursor++
+			}
+		case 16, 11: // ctrl-p, ctrl-k
+			cursor--
+		case 14, 10: // ctrl-n, ctrl-j
+			cursor++
+		case 13: // enter
+			if len(matches) > 0 {
+				clear()
+				return matches[cursor], nil
+			}
+		case 127, 8: // backspace
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				matches = fuzzyFilter(items, string(query))
+				cursor = 0
+			}
+		case 21: // ctrl-u
+			query = query[:0]
+			matches = fuzzyFilter(items, "")
+			cursor = 0
+		default:
+			if unicode.IsPrint(r) {
+				query = append(query, r)
+				matches = fuzzyFilter(items, string(query))
+				cursor = 0
+			}
+		}
+
+		if cursor >= len(matches) {
+			cursor = len(matches) - 1
+		}
+		if cursor < 0 {
+			cursor = 0
+		}
+	}
+}
+
+// PickRow is like Pick but formats rows as an aligned table first.
+func PickRow(prompt string, rows [][]string) (int, error) {
+	table := strings.TrimSuffix(FormatTable(rows), "\n")
+	return Pick(prompt, strings.Split(table, "\n"))
+}
+
+// fuzzyFilter returns the indexes of items that contain the characters of
+// query in order (case-insensitively), best matches first. Items where
+// query appears as a substring rank above scattered matches, and tighter
+// matches rank above looser ones.
+func fuzzyFilter(items []string, query string) []int {
+	query = strings.ToLower(query)
+
+	type match struct {
+		idx   int
+		score int
+	}
+	var matches []match
+	for i, item := range items {
+		lower := strings.ToLower(item)
+		if query == "" {
+			matches = append(matches, match{idx: i})
+			continue
+		}
+		if pos := strings.Index(lower, query); pos >= 0 {
+			matches = append(matches, match{idx: i, score: pos})
+			continue
+		}
+
+		first, qi := -1, 0
+		q := []rune(query)
+		for pos, r := range lower {
+			if r == q[qi] {
+				if first < 0 {
+					first = pos
+				}
+				qi++
+				if qi == len(q) {
+					matches = append(matches, match{idx: i, score: len(lower) + pos - first})
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score < matches[j].score
+	})
+
+	idxs := make([]int, len(matches))
+	for i, m := range matches {
+		idxs[i] = m.idx
+	}
+	return idxs
+}
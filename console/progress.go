@@ -0,0 +1,89 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Progress reports how far a long running command has got on a single
+// stderr line. Nothing is drawn when stderr isn't a terminal so piped
+// and logged output stays clean.
+type Progress struct {
+	mu     sync.Mutex
+	out    io.Writer
+	label  string
+	total  int
+	done   int
+	drawn  time.Time
+	active bool
+}
+
+// progressInterval limits how often the progress line is redrawn.
+const progressInterval = 100 * time.Millisecond
+
+// NewProgress returns a Progress for work described by label, e.g.
+// "users". Call Clear when finished.
+func NewProgress(label string) *Progress {
+	return &Progress{
+		out:    os.Stderr,
+		label:  label,
+		active: term.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+// AddTotal adds n to the expected number of items, for work that is
+// discovered a page at a time.
+func (p *Progress) AddTotal(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += n
+}
+
+// Inc marks one item, named item, as done.
+func (p *Progress) Inc(item string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if !p.active || time.Since(p.drawn) < progressInterval {
+		return
+	}
+	p.drawn = time.Now()
+
+	count := fmt.Sprint(p.done)
+	if p.total > 0 {
+		count = fmt.Sprintf("%d/%d", p.done, p.total)
+	}
+	fmt.Fprintf(p.out, "\r\x1b[K%s %s %s", count, p.label, item)
+}
+
+// Clear erases the progress line. Call it before writing other output
+// to the terminal, and when finished; the next Inc redraws the line.
+func (p *Progress) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active && !p.drawn.IsZero() {
+		fmt.Fprint(p.out, "\r\x1b[K")
+		p.drawn = time.Time{}
+	}
+}
+
+// Writer returns w wrapped to clear the progress line before each write,
+// for commands that stream their output while making progress.
+func (p *Progress) Writer(w io.Writer) io.Writer {
+	return &progressWriter{p: p, w: w}
+}
+
+type progressWriter struct {
+	p *Progress
+	w io.Writer
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	pw.p.Clear()
+	return pw.w.Write(b)
+}
@@ -7,13 +7,14 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
 var (
-	jsonOutput bool
-	daysFlag   int
+	daysFlag int
 )
 
 func Command() *cobra.Command {
@@ -30,7 +31,7 @@ func costDailyComparison() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "daily",
 		Short: "Show daily costs",
-		Run:   dailyCostComparisonAction,
+		RunE:  dailyCostComparisonAction,
 	}
 
 	cmd.Flags().IntVarP(&daysFlag, "days", "", 14, "Number of days to fetch")
@@ -38,8 +39,14 @@ func costDailyComparison() *cobra.Command {
 	return &cmd
 }
 
-func dailyCostComparisonAction(cmd *cobra.Command, args []string) {
-	svc := costexplorer.New(config.Session())
+type dailyCost struct {
+	Date   string  `json:"date"`
+	Amount float64 `json:"amount"`
+}
+
+func dailyCostComparisonAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.CostExplorer(config.Session())
 
 	today := time.Now()
 	start := today.AddDate(0, 0, -1*daysFlag)
@@ -55,20 +62,15 @@ func dailyCostComparisonAction(cmd *cobra.Command, args []string) {
 		},
 	}
 
-	type dailyCost struct {
-		date string
-		amt  float64
-	}
-
 	var (
 		maxCost float64
 		costs   []dailyCost
 	)
 
 	for moreData := true; moreData; {
-		output, err := svc.GetCostAndUsage(&req)
+		output, err := svc.GetCostAndUsageWithContext(ctx, &req)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("GetCostAndUsage error: %w", err)
 		}
 
 		if output.NextPageToken != nil {
@@ -81,7 +83,7 @@ func dailyCostComparisonAction(cmd *cobra.Command, args []string) {
 			amtStr := result.Total["NetAmortizedCost"].Amount
 			amt, err := strconv.ParseFloat(*amtStr, 64)
 			if err != nil {
-				panic(err)
+				return fmt.Errorf("parse cost %q: %w", *amtStr, err)
 			}
 
 			if amt > maxCost {
@@ -89,22 +91,33 @@ func dailyCostComparisonAction(cmd *cobra.Command, args []string) {
 			}
 
 			costs = append(costs, dailyCost{
-				date: *result.TimePeriod.Start,
-				amt:  amt,
+				Date:   *result.TimePeriod.Start,
+				Amount: amt,
 			})
 		}
 	}
 
+	w := cmd.OutOrStdout()
+	out := output.New(w)
+	if !output.IsTable(out.Default) {
+		for _, cost := range costs {
+			out.Add(cost)
+		}
+		return out.Flush()
+	}
+
 	starWidth := maxCost / 70.0
 
 	for _, cost := range costs {
 
-		stars := cost.amt / starWidth
+		stars := cost.Amount / starWidth
 
-		fmt.Printf("%s $%d ", cost.date, int(cost.amt))
+		fmt.Fprintf(w, "%s $%d ", cost.Date, int(cost.Amount))
 		for i := 0.0; i < stars; i++ {
-			fmt.Print("*")
+			fmt.Fprint(w, "*")
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
+
+	return nil
 }
@@ -0,0 +1,116 @@
+package creds
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "creds",
+		Short: "Credential Commands",
+	}
+
+	cmd.AddCommand(envCommand())
+	cmd.AddCommand(execCommand())
+
+	return &cmd
+}
+
+func envCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "env",
+		Short: "Print export statements for the current credentials",
+		Long: `Print shell export statements for the current credentials, including
+any role assumed with --role-arn or --account/--role:
+
+  eval "$(aws-buddy --account 123456789012 --role OrgAdmin creds env)"`,
+		Args: cobra.NoArgs,
+		RunE: envAction,
+	}
+
+	return &cmd
+}
+
+func envAction(cmd *cobra.Command, args []string) error {
+	sess := config.Session()
+	v, err := sess.Config.Credentials.Get()
+	if err != nil {
+		return fmt.Errorf("get credentials err: %w", err)
+	}
+
+	w := cmd.OutOrStdout()
+	if expires, err := sess.Config.Credentials.ExpiresAt(); err == nil {
+		fmt.Fprintf(w, "# expires %s\n", expires.Local().Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "unset %s\n", strings.Join(config.CredentialEnvNames, " "))
+	for _, kv := range config.CredentialVars(v, config.ResolvedRegion()) {
+		name, val, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(w, "export %s=%s\n", name, shellQuote(val))
+	}
+
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func execCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "exec -- <cmd> [args...]",
+		Short: "Run a command with the current credentials",
+		Long: `Run a command with the current credentials, including any role assumed
+with --role-arn or --account/--role, in its environment:
+
+  aws-buddy --account 123456789012 --role OrgAdmin creds exec -- terraform plan`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: execAction,
+	}
+
+	// everything after the command name belongs to the command
+	cmd.Flags().SetInterspersed(false)
+
+	return &cmd
+}
+
+func execAction(cmd *cobra.Command, args []string) error {
+	v, err := config.Session().Config.Credentials.Get()
+	if err != nil {
+		return fmt.Errorf("get credentials err: %w", err)
+	}
+
+	// not CommandContext: ctrl-c reaches the child from the terminal, and
+	// it decides how to exit
+	c := exec.Command(args[0], args[1:]...)
+	c.Env = config.CredentialEnv(os.Environ(), v, config.ResolvedRegion())
+	c.Stdin = cmd.InOrStdin()
+	c.Stdout = cmd.OutOrStdout()
+	c.Stderr = os.Stderr
+
+	err = c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if code < 0 {
+			// killed by a signal
+			code = errs.ExitError
+		}
+		return &errs.ExitStatusError{
+			Code: code,
+			Err:  fmt.Errorf("%s: %w", strings.Join(args, " "), err),
+		}
+	} else if err != nil {
+		return fmt.Errorf("exec %s err: %w", args[0], err)
+	}
+
+	return nil
+}
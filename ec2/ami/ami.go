@@ -2,10 +2,11 @@ package ami
 
 import (
 	"fmt"
-	"log"
 	"sort"
+	"time"
 
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/psanford/ubuntuami"
 	"github.com/spf13/cobra"
 )
@@ -25,16 +26,16 @@ func listUbuntuCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list_ubuntu",
 		Short: "list ubuntu AMIs",
-		Run:   listUbuntuAction,
+		RunE:  listUbuntuAction,
 	}
 
 	return &cmd
 }
 
-func listUbuntuAction(cmd *cobra.Command, args []string) {
+func listUbuntuAction(cmd *cobra.Command, args []string) error {
 	amis, err := ubuntuami.Fetch()
 	if err != nil {
-		log.Fatalf("fetch ubuntu ami err: %s", err)
+		return fmt.Errorf("fetch ubuntu ami err: %w", err)
 	}
 
 	sort.Slice(amis, func(i, j int) bool {
@@ -45,11 +46,33 @@ func listUbuntuAction(cmd *cobra.Command, args []string) {
 		return amis[i].ReleaseVersion < amis[j].ReleaseVersion
 	})
 
+	out := output.New(cmd.OutOrStdout())
+	region := config.ResolvedRegion()
 	for _, ami := range amis {
-		if ami.Region != config.DefaultRegion {
+		if ami.Region != region {
 			continue
 		}
 
-		fmt.Printf("%20s %10.10s %10.10s %10.10s %7.7s %20.20s %s\n", ami.ID, ami.Region, ami.ReleaseName, ami.ReleaseVersion, ami.Arch, ami.InstanceType, ami.ReleaseTime)
+		out.Add(amiRow{
+			ID:             ami.ID,
+			Region:         ami.Region,
+			ReleaseName:    ami.ReleaseName,
+			ReleaseVersion: ami.ReleaseVersion,
+			Arch:           ami.Arch,
+			InstanceType:   ami.InstanceType,
+			ReleaseTime:    ami.ReleaseTime,
+		})
 	}
+
+	return out.Flush()
+}
+
+type amiRow struct {
+	ID             string    `json:"id"`
+	Region         string    `json:"region"`
+	ReleaseName    string    `json:"release_name"`
+	ReleaseVersion string    `json:"release_version"`
+	Arch           string    `json:"arch"`
+	InstanceType   string    `json:"instance_type"`
+	ReleaseTime    time.Time `json:"release_time"`
 }
@@ -1,12 +1,15 @@
 package asg
 
 import (
-	"encoding/json"
-	"log"
-	"os"
+	"fmt"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
@@ -25,32 +28,45 @@ func asgScalingActivitiesCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "scaling-activites <asg-name>",
 		Short: "list scaling activities",
-		Run:   asgListScalingActivitiesAction,
+		RunE:  asgListScalingActivitiesAction,
 	}
 
 	return &cmd
 }
 
-func asgListScalingActivitiesAction(cmd *cobra.Command, args []string) {
+func asgListScalingActivitiesAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) != 1 {
-		log.Fatalf("Missing required asg-name argument")
+		return fmt.Errorf("%w: missing required asg-name argument", errs.ErrUsage)
 	}
-	svc := autoscaling.New(config.Session())
+	svc := client.AutoScaling(config.Session())
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	out := output.New(cmd.OutOrStdout())
+	out.Default = output.JSON
 
 	input := autoscaling.DescribeScalingActivitiesInput{
 		AutoScalingGroupName: &args[0],
 	}
-	err := svc.DescribeScalingActivitiesPages(&input, func(out *autoscaling.DescribeScalingActivitiesOutput, more bool) bool {
-		for _, act := range out.Activities {
-			jsonOut.Encode(act)
+	err := svc.DescribeScalingActivitiesPagesWithContext(ctx, &input, func(resp *autoscaling.DescribeScalingActivitiesOutput, more bool) bool {
+		for _, act := range resp.Activities {
+			out.AddDetail(activityRow{
+				StartTime:   aws.TimeValue(act.StartTime),
+				Status:      aws.StringValue(act.StatusCode),
+				Description: aws.StringValue(act.Description),
+			}, act)
 		}
 		return true
 	})
 
 	if err != nil {
-		log.Fatalf("DescribeScalingActivities error: %s", err)
+		return fmt.Errorf("DescribeScalingActivities error: %w", err)
 	}
+
+	return out.Flush()
+}
+
+type activityRow struct {
+	StartTime   time.Time `json:"start_time"`
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
 }
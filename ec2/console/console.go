@@ -9,7 +9,11 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/ec2/instance"
+	"github.com/psanford/aws-buddy/errs"
 	"github.com/spf13/cobra"
 )
 
@@ -17,9 +21,10 @@ var waitForOutput bool
 
 func Command() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "console <i-instanceid>",
-		Short: "Get console output from instance",
-		Run:   consoleAction,
+		Use:               "console <instance>",
+		Short:             "Get console output from instance",
+		RunE:              consoleAction,
+		ValidArgsFunction: completion.Instances(0),
 	}
 
 	cmd.Flags().BoolVarP(&waitForOutput, "wait", "", false, "Wait for output")
@@ -27,12 +32,18 @@ func Command() *cobra.Command {
 	return &cmd
 }
 
-func consoleAction(cmd *cobra.Command, args []string) {
+func consoleAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) == 0 {
-		log.Fatalf("usage: console <i-instanceid>")
+		return fmt.Errorf("%w: console <instance>", errs.ErrUsage)
 	}
 
-	svc := ec2.New(config.Session())
+	inst, err := instance.Resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	svc := client.EC2(config.Session())
 
 	maxCount := 1
 	maxTimeout := time.Now().Add(10 * time.Minute)
@@ -43,11 +54,11 @@ func consoleAction(cmd *cobra.Command, args []string) {
 	}
 
 	for i := 0; i < maxCount; i++ {
-		out, err := svc.GetConsoleOutput(&ec2.GetConsoleOutputInput{
-			InstanceId: aws.String(args[0]),
+		out, err := svc.GetConsoleOutputWithContext(ctx, &ec2.GetConsoleOutputInput{
+			InstanceId: inst.InstanceId,
 		})
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
 		if out.Timestamp != nil {
@@ -59,19 +70,24 @@ func consoleAction(cmd *cobra.Command, args []string) {
 		} else {
 			b, err := base64.StdEncoding.DecodeString(*out.Output)
 			if err != nil {
-				panic(err)
+				return fmt.Errorf("decode console output: %w", err)
 			}
-			fmt.Println(string(b))
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
 			gotOutput = true
 			break
 		}
 
-		if time.Now().After(maxTimeout) {
+		if i == maxCount-1 || time.Now().After(maxTimeout) {
 			break
 		}
+		if err := aws.SleepWithContext(ctx, 30*time.Second); err != nil {
+			return err
+		}
 	}
 
 	if !gotOutput && maxCount > 1 {
 		log.Printf("Giving up")
 	}
+
+	return nil
 }
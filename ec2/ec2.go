@@ -1,37 +1,45 @@
 package ec2
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
-	"os"
-	"regexp"
+	"io"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
+	"github.com/psanford/aws-buddy/console"
 	"github.com/psanford/aws-buddy/ec2/ami"
 	"github.com/psanford/aws-buddy/ec2/asg"
-	"github.com/psanford/aws-buddy/ec2/console"
+	ec2console "github.com/psanford/aws-buddy/ec2/console"
 	"github.com/psanford/aws-buddy/ec2/eip"
 	"github.com/psanford/aws-buddy/ec2/eni"
+	"github.com/psanford/aws-buddy/ec2/filter"
 	"github.com/psanford/aws-buddy/ec2/instance"
 	"github.com/psanford/aws-buddy/ec2/launch"
 	"github.com/psanford/aws-buddy/ec2/launchtemplate"
+	"github.com/psanford/aws-buddy/ec2/lifecycle"
+	"github.com/psanford/aws-buddy/ec2/resize"
 	"github.com/psanford/aws-buddy/ec2/securitygroup"
+	"github.com/psanford/aws-buddy/ec2/ssh"
+	"github.com/psanford/aws-buddy/ec2/ssmrun"
 	"github.com/psanford/aws-buddy/ec2/tag"
 	"github.com/psanford/aws-buddy/ec2/terminate"
 	"github.com/psanford/aws-buddy/ec2/volume"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/psanford/aws-buddy/regions"
 	"github.com/spf13/cobra"
 )
 
 var (
-	jsonOutput     bool
 	verboseOutput  bool
 	truncateFields bool
-	filterFlag     string
+	filterFlag     []string
 	filterNameFlag string
 )
 
@@ -53,50 +61,92 @@ func Command() *cobra.Command {
 	cmd.AddCommand(launchtemplate.Command())
 	cmd.AddCommand(launch.Command())
 	cmd.AddCommand(terminate.Command())
-	cmd.AddCommand(console.Command())
+	cmd.AddCommand(lifecycle.Commands()...)
+	cmd.AddCommand(resize.Command())
+	cmd.AddCommand(ssh.Command())
+	cmd.AddCommand(ssh.ProxyCommand())
+	cmd.AddCommand(ssmrun.ExecCommand())
+	cmd.AddCommand(ssmrun.StatusCommand())
+	cmd.AddCommand(ec2console.Command())
 	return &cmd
 }
 
 func ec2ListCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "list",
+		Use:   "list [filter...]",
 		Short: "List instances",
-		Run:   ec2ListAction,
+		Long: `List instances, optionally filtered by expressions like:
+
+  aws-buddy ec2 list state=stopped tag:env=prod
+  aws-buddy ec2 list -f 'type~t4g az=us-east-1a launched<7d'
+
+Operators are = and != (comma separated values, * and ? are wildcards),
+~ and !~ (case insensitive substring), and < and > for launched (an age
+like 7d or 12h, or a date like 2024-01-01). A bare word matches a
+substring of the Name tag or instance ID.
+
+Keys: ` + strings.Join(filter.Keys(), ", ") + `, tag:<key>
+
+Equality filters are sent to the EC2 API; the rest are applied to the
+results.`,
+		RunE: ec2ListAction,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
 	cmd.Flags().BoolVarP(&truncateFields, "truncate", "", true, "Trucate fields")
 	cmd.Flags().BoolVarP(&verboseOutput, "verbose", "v", false, "Show verbose (multi-line) output")
-	cmd.Flags().StringVarP(&filterFlag, "filter", "f", "", "Filter results by name or id")
+	cmd.Flags().StringArrayVarP(&filterFlag, "filter", "f", nil, "Filter expressions, e.g. 'state=running tag:env=prod' (see --help), or a name or id substring")
 	cmd.Flags().StringVarP(&filterNameFlag, "filter-name", "", "", "API Filter by Tag:Name")
+	regions.AddFlags(&cmd)
 
 	return &cmd
 }
 
-func ec2ListAction(cmd *cobra.Command, args []string) {
-	showInstances(nil)
+func ec2ListAction(cmd *cobra.Command, args []string) error {
+	f, err := filter.Parse(append(filterFlag, args...))
+	if err != nil {
+		return err
+	}
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: f.APIFilters(),
+	}
+	return showInstances(cmd.Context(), cmd.OutOrStdout(), input, f)
 }
 
 func ec2ShowCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "show <instance-id>",
-		Short: "Show Instance",
-		Run:   ec2ShowAction,
+		Use:               "show <instance> [...<instance>]",
+		Short:             "Show Instance (by id, name, ip or unique name substring)",
+		RunE:              ec2ShowAction,
+		ValidArgsFunction: completion.Instances(),
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
 	cmd.Flags().BoolVarP(&verboseOutput, "verbose", "v", false, "Show verbose (multi-line) output")
 	cmd.Flags().StringVarP(&filterNameFlag, "filter-name", "", "", "API Filter by Tag:Name")
 
 	return &cmd
 }
 
-func showInstances(input *ec2.DescribeInstancesInput) {
-	svc := ec2.New(config.Session())
-
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+type instanceRow struct {
+	Region         string    `json:"region" output:"hidden"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	AZ             string    `json:"az"`
+	State          string    `json:"state"`
+	PrivateIPs     []string  `json:"private_ips"`
+	PublicIPs      []string  `json:"public_ips"`
+	SecurityGroups []string  `json:"security_groups"`
+	LaunchTime     time.Time `json:"launch_time" output:"hidden"`
+	ImageID        string    `json:"image_id" output:"hidden"`
+	KeyName        string    `json:"key_name" output:"hidden"`
+	VpcID          string    `json:"vpc_id" output:"hidden"`
+	SubnetID       string    `json:"subnet_id" output:"hidden"`
+}
 
+// showInstances prints the instances described by input. If f is non-nil
+// only instances matching it are shown.
+func showInstances(ctx context.Context, w io.Writer, input *ec2.DescribeInstancesInput, f *filter.Filter) error {
 	if input == nil {
 		input = &ec2.DescribeInstancesInput{}
 	}
@@ -105,6 +155,13 @@ func showInstances(input *ec2.DescribeInstancesInput) {
 	}
 
 	if filterNameFlag != "" {
+		// EC2 rejects a repeated filter name, and merging the values
+		// would match either name instead of both
+		for _, af := range input.Filters {
+			if aws.StringValue(af.Name) == "tag:Name" {
+				return fmt.Errorf("%w: --filter-name can't be used with a name= filter", errs.ErrUsage)
+			}
+		}
 		if input.Filters == nil {
 			input.Filters = []*ec2.Filter{}
 		}
@@ -114,25 +171,23 @@ func showInstances(input *ec2.DescribeInstancesInput) {
 		})
 	}
 
-	err := svc.DescribeInstancesPages(input, func(output *ec2.DescribeInstancesOutput, lastPage bool) bool {
-		for _, inst := range instance.InstancesFromDesc(output) {
-			tags := make(map[string]string)
-			for _, t := range inst.Tags {
-				tags[*t.Key] = *t.Value
-			}
-			name := tags["Name"]
-			if filterFlag != "" {
-				filterFlag = strings.ToLower(filterFlag)
-				if strings.Index(strings.ToLower(name), filterFlag) < 0 && strings.Index(*inst.InstanceId, filterFlag) < 0 {
+	out := output.New(w)
+	short := truncateFields && output.IsTable(out.Default)
+	verbose := verboseOutput && output.IsTable(out.Default)
+
+	listErr := regions.Each(ctx, out, func(sess *session.Session, region string, out *output.Printer) error {
+		svc := client.EC2(sess)
+
+		err := svc.DescribeInstancesPagesWithContext(ctx, input, func(resp *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, inst := range instance.InstancesFromDesc(resp) {
+				tags := make(map[string]string)
+				for _, t := range inst.Tags {
+					tags[*t.Key] = *t.Value
+				}
+				name := tags["Name"]
+				if f != nil && !f.Match(&inst) {
 					continue
 				}
-			}
-
-			if jsonOutput {
-				jsonOut.Encode(inst)
-			} else {
-				instType := shortType(*inst.InstanceType)
-				state := shortState(*inst.State.Name)
 
 				az := *inst.Placement.AvailabilityZone
 
@@ -157,64 +212,107 @@ func showInstances(input *ec2.DescribeInstancesInput) {
 					securityGroupNameIDs = append(securityGroupNameIDs, fmt.Sprintf("%s(%s)", *sg.GroupName, *sg.GroupId))
 				}
 
-				var ifaces []string
-				for _, iface := range inst.NetworkInterfaces {
-					ifaces = append(ifaces, *iface.NetworkInterfaceId)
-				}
+				if verbose {
+					var ifaces []string
+					for _, iface := range inst.NetworkInterfaces {
+						ifaces = append(ifaces, *iface.NetworkInterfaceId)
+					}
 
-				if verboseOutput {
 					var arn string
 					if inst.IamInstanceProfile != nil && inst.IamInstanceProfile.Arn != nil {
 						arn = *inst.IamInstanceProfile.Arn
 					}
-					fmt.Printf("========[ %s ]===================\n", *inst.InstanceId)
-					fmt.Printf("name     : %s\n", name)
-					fmt.Printf("id       : %s\n", *inst.InstanceId)
-					fmt.Printf("type     : %s\n", *inst.InstanceType)
-					fmt.Printf("az       : %s\n", az)
-					fmt.Printf("state    : %s\n", state)
-					fmt.Printf("priv IPs : %s\n", strings.Join(privateIPs, ","))
-					fmt.Printf("pub  IPs : %s\n", strings.Join(publicIPs, ","))
-					fmt.Printf("SGs      : %s\n", strings.Join(securityGroupNameIDs, ","))
-					fmt.Printf("Profile  : %s\n", arn)
-					fmt.Printf("Launch   : %s\n", inst.LaunchTime.Format(time.RFC3339))
-					fmt.Printf("IFaces   : %s\n", strings.Join(ifaces, ";"))
-					fmt.Printf("Tags     : %v\n", tags)
-				} else {
-					formatStr := "%s %-35.35s %6.6s %4.4s %3.3s %15s %15s %s\n"
-					if !truncateFields {
-						formatStr = "%s %s %s %s %s %s %s %s\n"
+					var b strings.Builder
+					fmt.Fprintf(&b, "========[ %s ]===================\n", *inst.InstanceId)
+					fmt.Fprintf(&b, "name     : %s\n", name)
+					fmt.Fprintf(&b, "id       : %s\n", *inst.InstanceId)
+					fmt.Fprintf(&b, "type     : %s\n", *inst.InstanceType)
+					fmt.Fprintf(&b, "az       : %s\n", az)
+					fmt.Fprintf(&b, "state    : %s\n", shortState(*inst.State.Name))
+					fmt.Fprintf(&b, "priv IPs : %s\n", strings.Join(privateIPs, ","))
+					fmt.Fprintf(&b, "pub  IPs : %s\n", strings.Join(publicIPs, ","))
+					fmt.Fprintf(&b, "SGs      : %s\n", strings.Join(securityGroupNameIDs, ","))
+					fmt.Fprintf(&b, "Profile  : %s\n", arn)
+					fmt.Fprintf(&b, "Launch   : %s\n", inst.LaunchTime.Format(time.RFC3339))
+					fmt.Fprintf(&b, "IFaces   : %s\n", strings.Join(ifaces, ";"))
+					fmt.Fprintf(&b, "Tags     : %v\n", tags)
+					if regions.Enabled() {
+						fmt.Fprintf(&b, "Region   : %s\n", region)
 					}
-					fmt.Printf(formatStr, *inst.InstanceId, name, instType, shortAZ(az), state, strings.Join(privateIPs, ","), strings.Join(publicIPs, ","), strings.Join(securityGroupNames, ","))
+					fmt.Fprint(out.Writer(), b.String())
+					continue
 				}
+
+				row := instanceRow{
+					Region:         region,
+					ID:             *inst.InstanceId,
+					Name:           name,
+					Type:           *inst.InstanceType,
+					AZ:             az,
+					State:          *inst.State.Name,
+					PrivateIPs:     privateIPs,
+					PublicIPs:      publicIPs,
+					SecurityGroups: securityGroupNames,
+					LaunchTime:     aws.TimeValue(inst.LaunchTime),
+					ImageID:        aws.StringValue(inst.ImageId),
+					KeyName:        aws.StringValue(inst.KeyName),
+					VpcID:          aws.StringValue(inst.VpcId),
+					SubnetID:       aws.StringValue(inst.SubnetId),
+				}
+				if short {
+					if name := []rune(row.Name); len(name) > 35 {
+						row.Name = string(name[:35])
+					}
+					row.Type = shortType(row.Type)
+					row.AZ = shortAZ(row.AZ)
+					row.State = shortState(row.State)
+				}
+
+				inst := inst
+				out.AddDetail(row, regions.Detail(region, &inst))
 			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("DescribeInstance error: %w", err)
 		}
-		return true
+		return nil
 	})
-	if err != nil {
-		log.Fatalf("DescribeInstance error: %s", err)
+
+	if err := out.Flush(); err != nil {
+		return err
 	}
+	return listErr
 }
 
-var instanceIDRegex = regexp.MustCompile(`\Ai-[0-9a-f]+\z`)
-
-func ec2ShowAction(cmd *cobra.Command, args []string) {
-	if len(args) < 1 {
-		log.Fatalf("Usage: show <instance-id> [...<instance-id>]")
+func ec2ShowAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	var instances []*ec2.Instance
+	if len(args) == 0 && console.Interactive() {
+		inst, err := instance.Pick(ctx)
+		if err != nil {
+			return err
+		}
+		instances = append(instances, inst)
+	} else if len(args) < 1 {
+		return fmt.Errorf("%w: show <instance> [...<instance>]", errs.ErrUsage)
+	} else {
+		var err error
+		instances, err = instance.ResolveAll(ctx, args)
+		if err != nil {
+			return err
+		}
 	}
 
-	instanceIDs := args
-
-	for _, instanceID := range instanceIDs {
-		if !instanceIDRegex.MatchString(instanceID) {
-			log.Fatalf("<instance-id> must be of the form i-[0-9a-f]+")
-		}
+	instanceIDs := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		instanceIDs = append(instanceIDs, *inst.InstanceId)
 	}
 
 	input := &ec2.DescribeInstancesInput{
 		InstanceIds: aws.StringSlice(instanceIDs),
 	}
-	showInstances(input)
+	return showInstances(ctx, cmd.OutOrStdout(), input, nil)
 }
 
 func shortAZ(fullAZ string) string {
@@ -1,21 +1,16 @@
 package eip
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
-	"os"
-	"strings"
 
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/psanford/aws-buddy/regions"
 	"github.com/spf13/cobra"
 )
 
-var (
-	jsonOutput bool
-)
-
 func Command() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "ip",
@@ -31,25 +26,34 @@ func ipListCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "list ips by eni association",
-		Run:   ipListAction,
+		RunE:  ipListAction,
 	}
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
+
+	regions.AddFlags(&cmd)
 
 	return &cmd
 }
 
-func ipListAction(cmd *cobra.Command, args []string) {
-	svc := ec2.New(config.Session())
+type ipRow struct {
+	Region         string   `json:"region" output:"hidden"`
+	PublicIPs      []string `json:"public_ips"`
+	PrivateIPs     []string `json:"private_ips"`
+	InstanceID     string   `json:"instance_id"`
+	SecurityGroups []string `json:"security_groups"`
+	Status         string   `json:"status"`
+	SubnetID       string   `json:"subnet_id"`
+	VpcID          string   `json:"vpc_id"`
+}
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+func ipListAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := output.New(cmd.OutOrStdout())
 
-	err := svc.DescribeNetworkInterfacesPages(nil, func(output *ec2.DescribeNetworkInterfacesOutput, b bool) bool {
-		for _, nic := range output.NetworkInterfaces {
-			if jsonOutput {
-				jsonOut.Encode(nic)
-			} else {
+	listErr := regions.Each(ctx, out, func(sess *session.Session, region string, out *output.Printer) error {
+		svc := client.EC2(sess)
 
+		err := svc.DescribeNetworkInterfacesPagesWithContext(ctx, nil, func(resp *ec2.DescribeNetworkInterfacesOutput, b bool) bool {
+			for _, nic := range resp.NetworkInterfaces {
 				var (
 					instanceID = "-"
 					groups     []string
@@ -87,13 +91,27 @@ func ipListAction(cmd *cobra.Command, args []string) {
 					vpcID = *nic.VpcId
 				}
 
-				fmt.Printf("%s %s %s %s %s %s %s\n", strings.Join(publicIPs, ","), strings.Join(privateIPs, ","), instanceID, strings.Join(groups, "-"), status, subnetID, vpcID)
+				out.AddDetail(ipRow{
+					Region:         region,
+					PublicIPs:      publicIPs,
+					PrivateIPs:     privateIPs,
+					InstanceID:     instanceID,
+					SecurityGroups: groups,
+					Status:         status,
+					SubnetID:       subnetID,
+					VpcID:          vpcID,
+				}, regions.Detail(region, nic))
 			}
-
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("DescribeNetworkInterfaces error: %w", err)
 		}
-		return true
+		return nil
 	})
-	if err != nil {
-		log.Fatalf("DescribeSecurityGroups error: %s", err)
+
+	if err := out.Flush(); err != nil {
+		return err
 	}
+	return listErr
 }
@@ -1,13 +1,16 @@
 package eni
 
 import (
-	"encoding/json"
-	"log"
-	"os"
+	"context"
+	"fmt"
+	"io"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
@@ -23,74 +26,92 @@ func Command() *cobra.Command {
 	return &cmd
 }
 
-var (
-	jsonOutput bool
-)
-
 func showENICommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "show <eni-id>",
 		Short: "Show eni details",
-		Run:   showENIAction,
+		RunE:  showENIAction,
 	}
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
 
 	return &cmd
 }
 
-func showENIAction(cmd *cobra.Command, args []string) {
+func showENIAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatalf("Usage: show <eni-id> [...<eni-id>]")
+		return fmt.Errorf("%w: show <eni-id> [...<eni-id>]", errs.ErrUsage)
 	}
 
 	eniIDs := args
 
-	svc := ec2.New(config.Session())
-
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
-
 	input := &ec2.DescribeNetworkInterfacesInput{
 		NetworkInterfaceIds: aws.StringSlice(eniIDs),
 	}
-	err := svc.DescribeNetworkInterfacesPages(input, func(dnio *ec2.DescribeNetworkInterfacesOutput, b bool) bool {
-		for _, eni := range dnio.NetworkInterfaces {
-			jsonOut.Encode(eni)
-		}
-		return true
-	})
-	if err != nil {
-		log.Fatalf("DescribeNetworkInterfaces err: %s", err)
-	}
+	return printENIs(ctx, cmd.OutOrStdout(), input)
 }
 
 func listENICommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "List eni devices",
-		Run:   listENIAction,
+		RunE:  listENIAction,
 	}
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "List raw json ouput")
 
 	return &cmd
 }
 
-func listENIAction(cmd *cobra.Command, args []string) {
-	svc := ec2.New(config.Session())
-
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
-
+func listENIAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	input := &ec2.DescribeNetworkInterfacesInput{
 		MaxResults: aws.Int64(500),
 	}
-	err := svc.DescribeNetworkInterfacesPages(input, func(dnio *ec2.DescribeNetworkInterfacesOutput, b bool) bool {
+	return printENIs(ctx, cmd.OutOrStdout(), input)
+}
+
+type eniRow struct {
+	ID          string   `json:"id"`
+	Status      string   `json:"status"`
+	InstanceID  string   `json:"instance_id"`
+	PrivateIPs  []string `json:"private_ips"`
+	PublicIPs   []string `json:"public_ips"`
+	SubnetID    string   `json:"subnet_id"`
+	VpcID       string   `json:"vpc_id"`
+	Description string   `json:"description"`
+}
+
+func printENIs(ctx context.Context, w io.Writer, input *ec2.DescribeNetworkInterfacesInput) error {
+	svc := client.EC2(config.Session())
+
+	out := output.New(w)
+	out.Default = output.JSON
+
+	err := svc.DescribeNetworkInterfacesPagesWithContext(ctx, input, func(dnio *ec2.DescribeNetworkInterfacesOutput, b bool) bool {
 		for _, eni := range dnio.NetworkInterfaces {
-			jsonOut.Encode(eni)
+			row := eniRow{
+				ID:          aws.StringValue(eni.NetworkInterfaceId),
+				Status:      aws.StringValue(eni.Status),
+				SubnetID:    aws.StringValue(eni.SubnetId),
+				VpcID:       aws.StringValue(eni.VpcId),
+				Description: aws.StringValue(eni.Description),
+			}
+			if eni.Attachment != nil {
+				row.InstanceID = aws.StringValue(eni.Attachment.InstanceId)
+			}
+			for _, pip := range eni.PrivateIpAddresses {
+				if pip.PrivateIpAddress != nil {
+					row.PrivateIPs = append(row.PrivateIPs, *pip.PrivateIpAddress)
+				}
+				if pip.Association != nil && pip.Association.PublicIp != nil {
+					row.PublicIPs = append(row.PublicIPs, *pip.Association.PublicIp)
+				}
+			}
+			out.AddDetail(row, eni)
 		}
 		return true
 	})
 	if err != nil {
-		log.Fatalf("DescribeNetworkInterfaces err: %s", err)
+		return fmt.Errorf("DescribeNetworkInterfaces err: %w", err)
 	}
+
+	return out.Flush()
 }
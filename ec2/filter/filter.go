@@ -0,0 +1,332 @@
+// Package filter parses instance filter expressions like
+// "state=running type~t4g tag:env=prod launched<7d".
+//
+// Each expression is a key, an operator and a value:
+//
+//	key=a,b    the value matches a or b (* and ? are wildcards)
+//	key!=a,b   the value matches neither a nor b
+//	key~sub    the value contains sub, ignoring case
+//	key!~sub   the value doesn't contain sub, ignoring case
+//	launched<7d, launched>2024-01-01
+//	           launched less (or more) than 7 days ago, or before
+//	           (or after) a date
+//
+// A bare word without an operator matches a substring of the Name tag or
+// instance ID. Equality expressions are sent to the EC2 API as filters;
+// the rest are evaluated client side.
+package filter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/ec2/instance"
+	"github.com/psanford/aws-buddy/errs"
+)
+
+type field struct {
+	// api is the DescribeInstances filter name, or "" if the field can
+	// only be matched client side
+	api    string
+	values func(inst *ec2.Instance) []string
+}
+
+var fields = map[string]field{
+	"id": {"instance-id", func(inst *ec2.Instance) []string {
+		return []string{aws.StringValue(inst.InstanceId)}
+	}},
+	"name": {"tag:Name", func(inst *ec2.Instance) []string {
+		return []string{instance.Name(inst)}
+	}},
+	"state": {"instance-state-name", func(inst *ec2.Instance) []string {
+		if inst.State == nil {
+			return nil
+		}
+		return []string{aws.StringValue(inst.State.Name)}
+	}},
+	"type": {"instance-type", func(inst *ec2.Instance) []string {
+		return []string{aws.StringValue(inst.InstanceType)}
+	}},
+	"az": {"availability-zone", func(inst *ec2.Instance) []string {
+		if inst.Placement == nil {
+			return nil
+		}
+		return []string{aws.StringValue(inst.Placement.AvailabilityZone)}
+	}},
+	"vpc": {"vpc-id", func(inst *ec2.Instance) []string {
+		return []string{aws.StringValue(inst.VpcId)}
+	}},
+	"subnet": {"subnet-id", func(inst *ec2.Instance) []string {
+		return []string{aws.StringValue(inst.SubnetId)}
+	}},
+	"ami": {"image-id", func(inst *ec2.Instance) []string {
+		return []string{aws.StringValue(inst.ImageId)}
+	}},
+	"key": {"key-name", func(inst *ec2.Instance) []string {
+		return []string{aws.StringValue(inst.KeyName)}
+	}},
+	"private-ip": {"network-interface.addresses.private-ip-address", func(inst *ec2.Instance) []string {
+		var ips []string
+		for _, iface := range inst.NetworkInterfaces {
+			for _, addr := range iface.PrivateIpAddresses {
+				ips = append(ips, aws.StringValue(addr.PrivateIpAddress))
+			}
+		}
+		return ips
+	}},
+	"public-ip": {"network-interface.addresses.association.public-ip", func(inst *ec2.Instance) []string {
+		var ips []string
+		for _, iface := range inst.NetworkInterfaces {
+			for _, addr := range iface.PrivateIpAddresses {
+				if addr.Association != nil {
+					ips = append(ips, aws.StringValue(addr.Association.PublicIp))
+				}
+			}
+		}
+		return ips
+	}},
+	// sg matches security group ids and names, so it can't be a single
+	// API filter
+	"sg": {"", func(inst *ec2.Instance) []string {
+		var sgs []string
+		for _, sg := range inst.SecurityGroups {
+			sgs = append(sgs, aws.StringValue(sg.GroupId), aws.StringValue(sg.GroupName))
+		}
+		return sgs
+	}},
+}
+
+// Keys returns the supported filter keys, not including tag:<key>.
+func Keys() []string {
+	keys := make([]string, 0, len(fields)+1)
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	keys = append(keys, "launched")
+	sort.Strings(keys)
+	return keys
+}
+
+// Filter is a parsed set of filter expressions. An instance must match
+// all of them.
+type Filter struct {
+	api     []*ec2.Filter
+	matches []func(inst *ec2.Instance) bool
+}
+
+// Parse parses exprs, each of which may hold several space separated
+// expressions.
+func Parse(exprs []string) (*Filter, error) {
+	f := Filter{}
+	apiNames := make(map[string]bool)
+	now := time.Now()
+
+	for _, expr := range exprs {
+		for _, e := range strings.Fields(expr) {
+			key, op, value := split(e)
+			if op == "" {
+				sub := strings.ToLower(e)
+				f.matches = append(f.matches, func(inst *ec2.Instance) bool {
+					return strings.Contains(strings.ToLower(instance.Name(inst)), sub) || strings.Contains(aws.StringValue(inst.InstanceId), sub)
+				})
+				continue
+			}
+			if key == "" || value == "" {
+				return nil, fmt.Errorf("%w: invalid filter %q", errs.ErrUsage, e)
+			}
+
+			if key == "launched" {
+				m, err := launched(op, value, now)
+				if err != nil {
+					return nil, fmt.Errorf("%w: filter %q: %s", errs.ErrUsage, e, err)
+				}
+				f.matches = append(f.matches, m)
+				continue
+			}
+
+			fld, ok := fields[key]
+			if strings.HasPrefix(key, "tag:") {
+				tagKey := strings.TrimPrefix(key, "tag:")
+				fld = field{key, func(inst *ec2.Instance) []string {
+					for _, t := range inst.Tags {
+						if aws.StringValue(t.Key) == tagKey {
+							return []string{aws.StringValue(t.Value)}
+						}
+					}
+					return nil
+				}}
+			} else if !ok {
+				return nil, fmt.Errorf("%w: unknown filter key %q (valid keys: %s, tag:<key>)", errs.ErrUsage, key, strings.Join(Keys(), ", "))
+			}
+
+			// a repeated API filter name is rejected by EC2, so only the
+			// first is sent
+			if op == "=" && fld.api != "" && !apiNames[fld.api] {
+				apiNames[fld.api] = true
+				f.api = append(f.api, &ec2.Filter{
+					Name:   aws.String(fld.api),
+					Values: aws.StringSlice(strings.Split(value, ",")),
+				})
+				continue
+			}
+
+			m, err := match(op, value, fld.values)
+			if err != nil {
+				return nil, fmt.Errorf("%w: filter %q: %s", errs.ErrUsage, e, err)
+			}
+			f.matches = append(f.matches, m)
+		}
+	}
+
+	return &f, nil
+}
+
+// APIFilters returns the expressions that can be sent in
+// DescribeInstancesInput.Filters.
+func (f *Filter) APIFilters() []*ec2.Filter {
+	return f.api
+}
+
+// Match reports whether inst matches the expressions that are evaluated
+// client side.
+func (f *Filter) Match(inst *ec2.Instance) bool {
+	for _, m := range f.matches {
+		if !m(inst) {
+			return false
+		}
+	}
+	return true
+}
+
+// Instances returns the live instances matching exprs.
+func Instances(ctx context.Context, exprs []string) ([]*ec2.Instance, error) {
+	f, err := Parse(exprs)
+	if err != nil {
+		return nil, err
+	}
+	all, err := instance.List(ctx, f.APIFilters()...)
+	if err != nil {
+		return nil, err
+	}
+	var instances []*ec2.Instance
+	for i := range all {
+		if f.Match(&all[i]) {
+			instances = append(instances, &all[i])
+		}
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("%w: no instances match %q", errs.ErrNotFound, strings.Join(exprs, " "))
+	}
+	return instances, nil
+}
+
+var ops = []string{"!=", "!~", "=", "~", "<", ">"}
+
+// split splits e at its first operator. op is "" if there isn't one.
+func split(e string) (key, op, value string) {
+	idx := strings.IndexAny(e, "!=~<>")
+	if idx < 0 {
+		return "", "", ""
+	}
+	for _, op := range ops {
+		if strings.HasPrefix(e[idx:], op) {
+			return e[:idx], op, e[idx+len(op):]
+		}
+	}
+	return "", "", ""
+}
+
+func match(op, value string, values func(inst *ec2.Instance) []string) (func(inst *ec2.Instance) bool, error) {
+	switch op {
+	case "=", "!=":
+		var patterns []*regexp.Regexp
+		for _, v := range strings.Split(value, ",") {
+			patterns = append(patterns, wildcard(v))
+		}
+		want := op == "="
+		return func(inst *ec2.Instance) bool {
+			for _, v := range values(inst) {
+				for _, p := range patterns {
+					if p.MatchString(v) {
+						return want
+					}
+				}
+			}
+			return !want
+		}, nil
+	case "~", "!~":
+		sub := strings.ToLower(value)
+		want := op == "~"
+		return func(inst *ec2.Instance) bool {
+			for _, v := range values(inst) {
+				if strings.Contains(strings.ToLower(v), sub) {
+					return want
+				}
+			}
+			return !want
+		}, nil
+	}
+	return nil, fmt.Errorf("%s is only supported for launched", op)
+}
+
+// wildcard converts an EC2 filter value, where * and ? are wildcards, to
+// a regexp.
+func wildcard(v string) *regexp.Regexp {
+	re := regexp.QuoteMeta(v)
+	re = strings.ReplaceAll(re, `\*`, ".*")
+	re = strings.ReplaceAll(re, `\?`, ".")
+	return regexp.MustCompile(`\A` + re + `\z`)
+}
+
+func launched(op, value string, now time.Time) (func(inst *ec2.Instance) bool, error) {
+	if op != "<" && op != ">" {
+		return nil, fmt.Errorf("launched only supports < and >")
+	}
+
+	// launched<7d means newer than 7 days, launched<2024-01-01 means
+	// older than the date
+	var cutoff time.Time
+	newer := op == "<"
+	if age, err := parseAge(value); err == nil {
+		cutoff = now.Add(-age)
+	} else if t, err := time.Parse("2006-01-02", value); err == nil {
+		cutoff = t
+		newer = !newer
+	} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+		cutoff = t
+		newer = !newer
+	} else {
+		return nil, fmt.Errorf("invalid age or date %q (e.g. 7d, 12h, 2024-01-01)", value)
+	}
+
+	return func(inst *ec2.Instance) bool {
+		if inst.LaunchTime == nil {
+			return false
+		}
+		if newer {
+			return inst.LaunchTime.After(cutoff)
+		}
+		return inst.LaunchTime.Before(cutoff)
+	}, nil
+}
+
+// parseAge parses a time.Duration, or a number of days or weeks like 7d
+// or 2w.
+func parseAge(s string) (time.Duration, error) {
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[len(s)-1]]
+	if unit == 0 {
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n * float64(unit)), nil
+}
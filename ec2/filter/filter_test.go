@@ -0,0 +1,225 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/errs"
+)
+
+func testInstance(id, name, state, typ string, launched time.Time) *ec2.Instance {
+	return &ec2.Instance{
+		InstanceId:   aws.String(id),
+		InstanceType: aws.String(typ),
+		State:        &ec2.InstanceState{Name: aws.String(state)},
+		LaunchTime:   aws.Time(launched),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("Name"), Value: aws.String(name)},
+			{Key: aws.String("env"), Value: aws.String("prod")},
+		},
+		SecurityGroups: []*ec2.GroupIdentifier{
+			{GroupId: aws.String("sg-0123"), GroupName: aws.String("default")},
+		},
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		expr           string
+		key, op, value string
+	}{
+		{"state=running", "state", "=", "running"},
+		{"state!=running", "state", "!=", "running"},
+		{"type~t4g", "type", "~", "t4g"},
+		{"type!~t4g", "type", "!~", "t4g"},
+		{"launched<7d", "launched", "<", "7d"},
+		{"launched>2024-01-01", "launched", ">", "2024-01-01"},
+		// only the first operator splits
+		{"tag:cmd=a!=b", "tag:cmd", "=", "a!=b"},
+		{"web-1", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		key, op, value := split(tt.expr)
+		if key != tt.key || op != tt.op || value != tt.value {
+			t.Errorf("split(%q) = %q, %q, %q; want %q, %q, %q", tt.expr, key, op, value, tt.key, tt.op, tt.value)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	now := time.Now()
+	var (
+		web    = testInstance("i-0aaa", "web-1", "running", "t4g.small", now.Add(-24*time.Hour))
+		db     = testInstance("i-0bbb", "db-1", "stopped", "r5.large", now.Add(-30*24*time.Hour))
+		old    = testInstance("i-0ccc", "old-1", "running", "t3.micro", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))
+		recent = testInstance("i-0ddd", "recent-1", "running", "t3.micro", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+		noSG   = testInstance("i-0eee", "bare-1", "running", "t3.micro", now)
+	)
+	noSG.SecurityGroups = []*ec2.GroupIdentifier{{GroupId: aws.String("sg-12"), GroupName: aws.String("web")}}
+
+	tests := []struct {
+		name    string
+		exprs   []string
+		api     map[string][]string
+		match   []*ec2.Instance
+		noMatch []*ec2.Instance
+	}{
+		{
+			name:  "equality goes to the api",
+			exprs: []string{"state=running,stopped"},
+			api:   map[string][]string{"instance-state-name": {"running", "stopped"}},
+			match: []*ec2.Instance{web, db},
+		},
+		{
+			name:  "tag equality goes to the api",
+			exprs: []string{"tag:env=prod"},
+			api:   map[string][]string{"tag:env": {"prod"}},
+			match: []*ec2.Instance{web},
+		},
+		{
+			// EC2 rejects a repeated filter name
+			name:    "repeated key is sent once and matched client side after",
+			exprs:   []string{"state=running state=stopped"},
+			api:     map[string][]string{"instance-state-name": {"running"}},
+			match:   []*ec2.Instance{db},
+			noMatch: []*ec2.Instance{web},
+		},
+		{
+			name:    "repeated key across expressions",
+			exprs:   []string{"type=t4g.*", "type=t4g.small"},
+			api:     map[string][]string{"instance-type": {"t4g.*"}},
+			match:   []*ec2.Instance{web},
+			noMatch: []*ec2.Instance{db},
+		},
+		{
+			name:    "not equal",
+			exprs:   []string{"state!=stopped"},
+			match:   []*ec2.Instance{web},
+			noMatch: []*ec2.Instance{db},
+		},
+		{
+			name:    "not equal with wildcards",
+			exprs:   []string{"name!=web-?,old*"},
+			match:   []*ec2.Instance{db},
+			noMatch: []*ec2.Instance{web, old},
+		},
+		{
+			name:    "wildcards client side",
+			exprs:   []string{"sg=def*,sg-9?"},
+			match:   []*ec2.Instance{web},
+			noMatch: []*ec2.Instance{noSG},
+		},
+		{
+			name:    "wildcard doesn't match a substring",
+			exprs:   []string{"state=running", "state=run"},
+			api:     map[string][]string{"instance-state-name": {"running"}},
+			noMatch: []*ec2.Instance{web},
+		},
+		{
+			name:    "contains ignores case",
+			exprs:   []string{"type~T4G"},
+			match:   []*ec2.Instance{web},
+			noMatch: []*ec2.Instance{db},
+		},
+		{
+			name:    "doesn't contain",
+			exprs:   []string{"type!~t4g"},
+			match:   []*ec2.Instance{db},
+			noMatch: []*ec2.Instance{web},
+		},
+		{
+			name:    "bare word matches a name substring",
+			exprs:   []string{"WEB"},
+			match:   []*ec2.Instance{web},
+			noMatch: []*ec2.Instance{db},
+		},
+		{
+			name:    "bare word matches an id substring",
+			exprs:   []string{"0bbb"},
+			match:   []*ec2.Instance{db},
+			noMatch: []*ec2.Instance{web},
+		},
+		{
+			name:    "launched less than an age is newer",
+			exprs:   []string{"launched<7d"},
+			match:   []*ec2.Instance{web},
+			noMatch: []*ec2.Instance{db},
+		},
+		{
+			name:    "launched more than an age is older",
+			exprs:   []string{"launched>1w"},
+			match:   []*ec2.Instance{db},
+			noMatch: []*ec2.Instance{web},
+		},
+		{
+			name:    "launched before a date is older",
+			exprs:   []string{"launched<2024-01-01"},
+			match:   []*ec2.Instance{old},
+			noMatch: []*ec2.Instance{recent},
+		},
+		{
+			name:    "launched after a date is newer",
+			exprs:   []string{"launched>2024-01-01T00:00:00Z"},
+			match:   []*ec2.Instance{recent},
+			noMatch: []*ec2.Instance{old},
+		},
+		{
+			name:    "all expressions must match",
+			exprs:   []string{"type~t3 launched<2024-01-01 old"},
+			match:   []*ec2.Instance{old},
+			noMatch: []*ec2.Instance{recent, web},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(tt.exprs)
+			if err != nil {
+				t.Fatalf("Parse(%q) err: %s", tt.exprs, err)
+			}
+
+			api := make(map[string][]string)
+			for _, af := range f.APIFilters() {
+				api[aws.StringValue(af.Name)] = aws.StringValueSlice(af.Values)
+			}
+			if tt.api == nil {
+				tt.api = map[string][]string{}
+			}
+			if !reflect.DeepEqual(api, tt.api) {
+				t.Errorf("APIFilters = %v, want %v", api, tt.api)
+			}
+
+			for _, inst := range tt.match {
+				if !f.Match(inst) {
+					t.Errorf("%s doesn't match, want match", *inst.InstanceId)
+				}
+			}
+			for _, inst := range tt.noMatch {
+				if f.Match(inst) {
+					t.Errorf("%s matches, want no match", *inst.InstanceId)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"color=red",
+		"state=",
+		"=running",
+		"type<5",
+		"launched=7d",
+		"launched<soon",
+	} {
+		_, err := Parse([]string{expr})
+		if !errors.Is(err, errs.ErrUsage) {
+			t.Errorf("Parse(%q) err = %v, want usage error", expr, err)
+		}
+	}
+}
@@ -1,12 +1,19 @@
 package instance
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"regexp"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/errs"
 )
 
 func InstancesFromDesc(desc *ec2.DescribeInstancesOutput) []ec2.Instance {
@@ -21,32 +28,200 @@ func InstancesFromDesc(desc *ec2.DescribeInstancesOutput) []ec2.Instance {
 	return out
 }
 
-func Get(instanceID string) (*ec2.Instance, error) {
-	svc := ec2.New(config.Session())
-	input := ec2.DescribeInstancesInput{
-		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String("instance-id"),
-				Values: []*string{&instanceID},
-			},
-		},
-	}
-	output, err := svc.DescribeInstances(&input)
+func Get(ctx context.Context, instanceID string) (*ec2.Instance, error) {
+	instances, err := describe(ctx, client.EC2(config.Session()), &ec2.Filter{
+		Name:   aws.String("instance-id"),
+		Values: []*string{&instanceID},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("DescribeInstances err: %w", err)
+		return nil, err
 	}
 
-	instances := InstancesFromDesc(output)
 	if len(instances) < 1 {
-		return nil, fmt.Errorf("No instance found")
+		return nil, fmt.Errorf("%w: no instance %s", errs.ErrNotFound, instanceID)
 	}
 	if len(instances) > 1 {
-		ids := make([]string, 0, len(instances))
-		for _, inst := range instances {
-			ids = append(ids, *inst.InstanceId)
-		}
-		return nil, fmt.Errorf("Multiple instances found found: %s", strings.Join(ids, ","))
+		return nil, &AmbiguousError{Query: instanceID, Candidates: instances}
 	}
 
 	return &instances[0], nil
 }
+
+var IDRegex = regexp.MustCompile(`\Ai-[0-9a-f]+\z`)
+
+// Resolve finds the single instance matching query. query may be an
+// instance ID, an exact Name tag (* and ? aren't wildcards), a private or
+// public IP address, or a case-insensitive substring of the Name tag or
+// instance ID. Terminated instances are only matched by ID.
+func Resolve(ctx context.Context, query string) (*ec2.Instance, error) {
+	if IDRegex.MatchString(query) {
+		return Get(ctx, query)
+	}
+
+	svc := client.EC2(config.Session())
+
+	var filters []*ec2.Filter
+	isName := net.ParseIP(query) == nil
+	if !isName {
+		filters = []*ec2.Filter{
+			{Name: aws.String("private-ip-address"), Values: []*string{&query}},
+			{Name: aws.String("ip-address"), Values: []*string{&query}},
+		}
+	} else {
+		filters = []*ec2.Filter{
+			{Name: aws.String("tag:Name"), Values: []*string{&query}},
+		}
+	}
+
+	// filters within a single request are ANDed, so try each one separately
+	for _, filter := range filters {
+		instances, err := describe(ctx, svc, filter, liveFilter())
+		if err != nil {
+			return nil, err
+		}
+		if isName {
+			// EC2 treats * and ? in filter values as wildcards, so only
+			// keep the instances named exactly query
+			exact := instances[:0]
+			for _, inst := range instances {
+				if Name(&inst) == query {
+					exact = append(exact, inst)
+				}
+			}
+			instances = exact
+		}
+		if len(instances) == 1 {
+			return &instances[0], nil
+		}
+		if len(instances) > 1 {
+			return nil, &AmbiguousError{Query: query, Candidates: instances}
+		}
+	}
+
+	all, err := describe(ctx, svc, liveFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []ec2.Instance
+	lq := strings.ToLower(query)
+	for _, inst := range all {
+		if strings.Contains(strings.ToLower(Name(&inst)), lq) || strings.Contains(*inst.InstanceId, lq) {
+			matches = append(matches, inst)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: no instance matching %q", errs.ErrNotFound, query)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, &AmbiguousError{Query: query, Candidates: matches}
+	}
+}
+
+// Pick lists instances and lets the user choose one with console.Pick.
+func Pick(ctx context.Context) (*ec2.Instance, error) {
+	instances, err := describe(ctx, client.EC2(config.Session()))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(instances))
+	for _, inst := range instances {
+		rows = append(rows, []string{
+			*inst.InstanceId,
+			Name(&inst),
+			aws.StringValue(inst.InstanceType),
+			aws.StringValue(inst.State.Name),
+			aws.StringValue(inst.PrivateIpAddress),
+			aws.StringValue(inst.PublicIpAddress),
+		})
+	}
+
+	idx, err := console.PickRow("instance", rows)
+	if err != nil {
+		return nil, err
+	}
+	return &instances[idx], nil
+}
+
+// ResolveAll resolves each query, returning the instances in order.
+func ResolveAll(ctx context.Context, queries []string) ([]*ec2.Instance, error) {
+	instances := make([]*ec2.Instance, 0, len(queries))
+	for _, q := range queries {
+		inst, err := Resolve(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, inst)
+	}
+	return instances, nil
+}
+
+// List returns the instances matching filters. Terminated instances are
+// left out unless filters include an instance-state-name filter.
+func List(ctx context.Context, filters ...*ec2.Filter) ([]ec2.Instance, error) {
+	live := true
+	for _, f := range filters {
+		if aws.StringValue(f.Name) == "instance-state-name" {
+			live = false
+		}
+	}
+	if live {
+		filters = append(filters, liveFilter())
+	}
+	return describe(ctx, client.EC2(config.Session()), filters...)
+}
+
+// Name returns the Name tag of inst.
+func Name(inst *ec2.Instance) string {
+	for _, t := range inst.Tags {
+		if aws.StringValue(t.Key) == "Name" {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return ""
+}
+
+// AmbiguousError is returned by Resolve when more than one instance
+// matches the query.
+type AmbiguousError struct {
+	Query      string
+	Candidates []ec2.Instance
+}
+
+func (e *AmbiguousError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q matches %d instances:", e.Query, len(e.Candidates))
+	for _, inst := range e.Candidates {
+		fmt.Fprintf(&b, "\n  %s  %-35s %s", *inst.InstanceId, Name(&inst), aws.StringValue(inst.State.Name))
+	}
+	return b.String()
+}
+
+func (e *AmbiguousError) Unwrap() error {
+	return errs.ErrUsage
+}
+
+func liveFilter() *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("instance-state-name"),
+		Values: aws.StringSlice([]string{"pending", "running", "shutting-down", "stopping", "stopped"}),
+	}
+}
+
+func describe(ctx context.Context, svc ec2iface.EC2API, filters ...*ec2.Filter) ([]ec2.Instance, error) {
+	var instances []ec2.Instance
+	err := svc.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: filters,
+	}, func(resp *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		instances = append(instances, InstancesFromDesc(resp)...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("DescribeInstances err: %w", err)
+	}
+	return instances, nil
+}
@@ -3,38 +3,47 @@ package launch
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"regexp"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/errs"
 	"github.com/psanford/ubuntuami"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 )
 
+var ubuntuRelease string
+
 func Command() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "launch <launch_tmpl.yml>",
 		Short: "Launch instance",
-		Run:   launchAction,
+		RunE:  launchAction,
 	}
 
+	cmd.Flags().StringVarP(&ubuntuRelease, "ubuntu-release", "", "22.04", "Ubuntu release to use if the template doesn't set ubuntu_release")
+
 	return &cmd
 }
 
-func launchAction(cmd *cobra.Command, args []string) {
+func launchAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) == 0 {
-		log.Fatalf("usage: launch <launch_tmpl.yml>")
+		return fmt.Errorf("%w: launch <launch_tmpl.yml>", errs.ErrUsage)
 	}
 
 	f, err := os.Open(args[0])
 	if err != nil {
-		log.Fatalf("open %s err: %s", args[0], err)
+		return fmt.Errorf("open %s err: %s", args[0], err)
 	}
 
 	defer f.Close()
@@ -43,30 +52,35 @@ func launchAction(cmd *cobra.Command, args []string) {
 	var cfg launchCfg
 	err = dec.Decode(&cfg)
 	if err != nil {
-		log.Fatalf("decode err: %s", err)
+		return fmt.Errorf("decode err: %w", err)
 	}
 
 	if cfg.Name == "" {
-		log.Fatal("name: is required")
+		return errors.New("name: is required")
 	}
 
 	if cfg.SecurityGroup == "" {
-		log.Fatal("security_group: is required")
+		return errors.New("security_group: is required")
+	}
+
+	if cfg.UbuntuRelease == "" {
+		cfg.UbuntuRelease = ubuntuRelease
 	}
 
-	arch := instanceTypeArch(cfg.InstanceType)
+	arch := InstanceTypeArch(cfg.InstanceType)
 
 	sgID := strings.Fields(cfg.SecurityGroup)[0]
 
 	amis, err := ubuntuami.Fetch()
 	if err != nil {
-		log.Fatalf("ubuntu ami fetch err: %s", err)
+		return fmt.Errorf("ubuntu ami fetch err: %w", err)
 	}
 	var (
 		matchAMI ubuntuami.AMI
+		region   = config.ResolvedRegion()
 	)
 	for _, ami := range amis {
-		if ami.Region != config.DefaultRegion {
+		if ami.Region != region {
 			continue
 		}
 		version := strings.TrimSuffix(ami.ReleaseVersion, " LTS")
@@ -84,10 +98,10 @@ func launchAction(cmd *cobra.Command, args []string) {
 	}
 
 	if matchAMI.ID == "" {
-		log.Fatalf("No matching AMI found")
+		return fmt.Errorf("No matching AMI found")
 	}
 
-	svc := ec2.New(config.Session())
+	svc := client.EC2(config.Session())
 
 	runCfg := &ec2.RunInstancesInput{
 		InstanceType:                      &cfg.InstanceType,
@@ -123,12 +137,25 @@ func launchAction(cmd *cobra.Command, args []string) {
 		runCfg.UserData = &ud
 	}
 
-	r, err := svc.RunInstances(runCfg)
+	if console.DryRun {
+		console.PrintDryRun("RunInstances", runCfg)
+		return nil
+	}
+
+	r, err := svc.RunInstancesWithContext(ctx, runCfg)
 	if err != nil {
-		log.Fatalf("RunInstances err: %s", err)
+		return fmt.Errorf("RunInstances err: %w", err)
 	}
 
-	fmt.Printf("instance: %s\n", *r.Instances[0].InstanceId)
+	fmt.Fprintf(cmd.OutOrStdout(), "instance: %s\n", *r.Instances[0].InstanceId)
+
+	audit.Record(audit.Entry{
+		Action:   "ec2 launch",
+		Resource: *r.Instances[0].InstanceId,
+		NewValue: fmt.Sprintf("%s %s %s", cfg.Name, cfg.InstanceType, matchAMI.ID),
+	})
+
+	return nil
 }
 
 type launchCfg struct {
@@ -143,7 +170,9 @@ type launchCfg struct {
 
 var gravitonRe = regexp.MustCompile(`\dg`)
 
-func instanceTypeArch(t string) string {
+// InstanceTypeArch returns the ubuntu architecture name, amd64 or arm64,
+// for instance type t.
+func InstanceTypeArch(t string) string {
 	if gravitonRe.MatchString(t) {
 		return "arm64"
 	}
@@ -2,30 +2,42 @@ package launchtemplate
 
 import (
 	"fmt"
-	"log"
 	"math/rand"
 	"os"
 	"strings"
 	"text/template"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/errs"
 	"github.com/spf13/cobra"
 )
 
+var (
+	securityGroupName string
+	instanceType      string
+	ubuntuRelease     string
+)
+
 func Command() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "launch_template <name>",
 		Short: "Launch template command",
-		Run:   launchTemplateAction,
+		RunE:  launchTemplateAction,
 	}
 
+	cmd.Flags().StringVarP(&securityGroupName, "security-group-name", "", "allow-ssh", "Name of the default security group")
+	cmd.Flags().StringVarP(&instanceType, "instance-type", "", "t4g.small", "Default instance type")
+	cmd.Flags().StringVarP(&ubuntuRelease, "ubuntu-release", "", "22.04", "Default ubuntu release")
+
 	return &cmd
 }
 
-func launchTemplateAction(cmd *cobra.Command, args []string) {
+func launchTemplateAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) == 0 {
-		log.Fatalf("usage: launch_template <name>")
+		return fmt.Errorf("%w: launch_template <name>", errs.ErrUsage)
 	}
 
 	name := args[0]
@@ -33,15 +45,15 @@ func launchTemplateAction(cmd *cobra.Command, args []string) {
 
 	f, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
-		log.Fatalf("create file %s err: %s", fname, err)
+		return fmt.Errorf("create file %s err: %s", fname, err)
 	}
 
 	defer f.Close()
 
-	svc := ec2.New(config.Session())
+	svc := client.EC2(config.Session())
 	var securityGroups []string
 	var defaultSG string
-	err = svc.DescribeSecurityGroupsPages(&ec2.DescribeSecurityGroupsInput{}, func(dsgo *ec2.DescribeSecurityGroupsOutput, b bool) bool {
+	err = svc.DescribeSecurityGroupsPagesWithContext(ctx, &ec2.DescribeSecurityGroupsInput{}, func(dsgo *ec2.DescribeSecurityGroupsOutput, b bool) bool {
 		for _, sg := range dsgo.SecurityGroups {
 			var name string
 			if sg.GroupName != nil {
@@ -49,18 +61,18 @@ func launchTemplateAction(cmd *cobra.Command, args []string) {
 			}
 
 			securityGroups = append(securityGroups, fmt.Sprintf("%s (%s)", *sg.GroupId, name))
-			if name == "allow-ssh" {
+			if name == securityGroupName {
 				defaultSG = *sg.GroupId
 			}
 		}
 		return true
 	})
 	if err != nil {
-		log.Fatalf("DescribeSecurityGroups err: %s", err)
+		return fmt.Errorf("DescribeSecurityGroups err: %w", err)
 	}
 
 	var subnets []string
-	err = svc.DescribeSubnetsPages(&ec2.DescribeSubnetsInput{}, func(dso *ec2.DescribeSubnetsOutput, b bool) bool {
+	err = svc.DescribeSubnetsPagesWithContext(ctx, &ec2.DescribeSubnetsInput{}, func(dso *ec2.DescribeSubnetsOutput, b bool) bool {
 		for _, s := range dso.Subnets {
 			var name string
 
@@ -75,13 +87,13 @@ func launchTemplateAction(cmd *cobra.Command, args []string) {
 		return true
 	})
 	if err != nil {
-		log.Fatalf("DescribeSubnets err: %s", err)
+		return fmt.Errorf("DescribeSubnets err: %w", err)
 	}
 
 	var keyPairs []string
-	kps, err := svc.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{})
+	kps, err := svc.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{})
 	if err != nil {
-		log.Fatalf("DescribeKeyPairs err: %s", err)
+		return fmt.Errorf("DescribeKeyPairs err: %w", err)
 	}
 	for _, kp := range kps.KeyPairs {
 		keyPairs = append(keyPairs, *kp.KeyName)
@@ -94,6 +106,8 @@ func launchTemplateAction(cmd *cobra.Command, args []string) {
 		KeyPairs:             keyPairs,
 		DefaultSubnet:        strings.Fields(subnets[rand.Intn(len(subnets))])[0],
 		DefaultSecurityGroup: defaultSG,
+		InstanceType:         instanceType,
+		UbuntuRelease:        ubuntuRelease,
 	}
 
 	if len(keyPairs) > 0 {
@@ -103,10 +117,12 @@ func launchTemplateAction(cmd *cobra.Command, args []string) {
 
 	err = tmpl.Execute(f, cfg)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	fmt.Fprintf(os.Stderr, "wrote %s\n", fname)
+
+	return nil
 }
 
 type tmplConfig struct {
@@ -117,13 +133,15 @@ type tmplConfig struct {
 	DefaultSubnet        string
 	KeyPairs             []string
 	DefaultKeyPair       string
+	InstanceType         string
+	UbuntuRelease        string
 }
 
 var tmpl = template.Must(template.New("tmpl").Parse(tmplText))
 
 var tmplText = `name: {{.Name}}
 
-instance_type: t4g.small
+instance_type: {{.InstanceType}}
 {{range .SecurityGroups}}
 # {{.}}
 {{- end}}
@@ -137,7 +155,7 @@ subnet: {{.DefaultSubnet}}
 {{- end}}
 key_pair: {{.DefaultKeyPair}}
 
-ubuntu_release: 22.04
+ubuntu_release: {{.UbuntuRelease}}
 
 # user_data script content
 # see CloudInit docs for details
@@ -0,0 +1,254 @@
+// Package lifecycle implements the ec2 start, stop, reboot and hibernate
+// commands.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/ec2/filter"
+	"github.com/psanford/aws-buddy/ec2/instance"
+	"github.com/psanford/aws-buddy/ec2/terminate"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitFlag   bool
+	filterFlag []string
+	forceFlag  bool
+)
+
+type operation struct {
+	name  string
+	short string
+	// result is the audit log's new value
+	result string
+}
+
+var operations = []operation{
+	{"start", "Start instances", "running"},
+	{"stop", "Stop instances", "stopped"},
+	{"reboot", "Reboot instances", "rebooted"},
+	{"hibernate", "Stop instances, saving their memory to the root volume", "hibernated"},
+}
+
+// Commands returns the start, stop, reboot and hibernate commands.
+func Commands() []*cobra.Command {
+	cmds := make([]*cobra.Command, 0, len(operations))
+	for _, op := range operations {
+		cmds = append(cmds, command(op))
+	}
+	return cmds
+}
+
+func command(op operation) *cobra.Command {
+	cmd := cobra.Command{
+		Use:               op.name + " <instance> [...<instance>]",
+		Short:             op.short,
+		RunE:              action(op),
+		ValidArgsFunction: completion.Instances(),
+	}
+
+	waitHelp := "Wait for the instances to reach the new state"
+	if op.name == "reboot" {
+		cmd.Long = `Reboot instances. Reboots are asynchronous: RebootInstances returns
+before the instances go down, and their status checks may still report
+ok from before the reboot. So reboot --wait is best effort; it waits
+until the status checks pass, which may be before the reboot happens.`
+		waitHelp = "Wait for the instances to pass their status checks (best effort, see above)"
+	}
+	cmd.Flags().BoolVarP(&waitFlag, "wait", "", false, waitHelp)
+	cmd.Flags().StringArrayVarP(&filterFlag, "filter", "f", nil, "Operate on the instances matching these filter expressions instead (see ec2 list --help)")
+	if op.name == "stop" {
+		cmd.Flags().BoolVarP(&forceFlag, "force", "", false, "Force the instances to stop without flushing file system caches")
+	}
+
+	return &cmd
+}
+
+func action(op operation) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		instances, err := resolve(ctx, op, args)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		ids := make([]*string, 0, len(instances))
+		for i, inst := range instances {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			terminate.PrintSummary(w, inst)
+			ids = append(ids, inst.InstanceId)
+		}
+
+		api, input := op.request(ids)
+		if console.DryRun {
+			console.PrintDryRun(api, input)
+			return nil
+		}
+
+		if err := terminate.Confirm(ctx, op.name, instances); err != nil {
+			return err
+		}
+
+		svc := client.EC2(config.Session())
+		if err := send(ctx, svc, input); err != nil {
+			return fmt.Errorf("%s err: %w", api, err)
+		}
+
+		for _, inst := range instances {
+			audit.Record(audit.Entry{
+				Action:   "ec2 " + op.name,
+				Resource: *inst.InstanceId,
+				OldValue: fmt.Sprintf("%s (%s)", instance.Name(inst), *inst.State.Name),
+				NewValue: op.result,
+			})
+		}
+
+		if !waitFlag {
+			return nil
+		}
+		return wait(ctx, w, svc, op, instances)
+	}
+}
+
+func resolve(ctx context.Context, op operation, args []string) ([]*ec2.Instance, error) {
+	usage := fmt.Errorf("%w: %s <instance> [...<instance>] or %s -f <filter>", errs.ErrUsage, op.name, op.name)
+
+	if len(filterFlag) > 0 {
+		if len(args) > 0 {
+			return nil, usage
+		}
+		return filter.Instances(ctx, filterFlag)
+	}
+
+	if len(args) == 0 && console.Interactive() {
+		inst, err := instance.Pick(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []*ec2.Instance{inst}, nil
+	} else if len(args) == 0 {
+		return nil, usage
+	}
+
+	return instance.ResolveAll(ctx, args)
+}
+
+func (op operation) request(ids []*string) (string, interface{}) {
+	switch op.name {
+	case "start":
+		return "StartInstances", &ec2.StartInstancesInput{InstanceIds: ids}
+	case "reboot":
+		return "RebootInstances", &ec2.RebootInstancesInput{InstanceIds: ids}
+	case "hibernate":
+		return "StopInstances", &ec2.StopInstancesInput{InstanceIds: ids, Hibernate: aws.Bool(true)}
+	}
+	input := &ec2.StopInstancesInput{InstanceIds: ids}
+	if forceFlag {
+		input.Force = aws.Bool(true)
+	}
+	return "StopInstances", input
+}
+
+func send(ctx context.Context, svc ec2iface.EC2API, input interface{}) error {
+	var err error
+	switch input := input.(type) {
+	case *ec2.StartInstancesInput:
+		_, err = svc.StartInstancesWithContext(ctx, input)
+	case *ec2.StopInstancesInput:
+		_, err = svc.StopInstancesWithContext(ctx, input)
+	case *ec2.RebootInstancesInput:
+		_, err = svc.RebootInstancesWithContext(ctx, input)
+	}
+	return err
+}
+
+// wait waits for the instances to reach op's state, redrawing their states
+// from each of the waiter's polls, and then prints the final states.
+func wait(ctx context.Context, w io.Writer, svc ec2iface.EC2API, op operation, instances []*ec2.Instance) error {
+	ids := make([]*string, 0, len(instances))
+	states := make(map[string]string)
+	for _, inst := range instances {
+		ids = append(ids, inst.InstanceId)
+		states[*inst.InstanceId] = aws.StringValue(inst.State.Name)
+	}
+
+	rows := func() [][]string {
+		rows := [][]string{{"id", "name", "state"}}
+		for _, inst := range instances {
+			rows = append(rows, []string{*inst.InstanceId, instance.Name(inst), states[*inst.InstanceId]})
+		}
+		return rows
+	}
+
+	table := console.NewLiveTable()
+	table.Set(rows())
+
+	// the waiters poll synchronously, so the handler runs on this goroutine
+	watch := request.WithWaiterRequestOptions(func(r *request.Request) {
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			if r.Error != nil {
+				return
+			}
+			switch out := r.Data.(type) {
+			case *ec2.DescribeInstancesOutput:
+				for _, inst := range instance.InstancesFromDesc(out) {
+					states[*inst.InstanceId] = aws.StringValue(inst.State.Name)
+				}
+			case *ec2.DescribeInstanceStatusOutput:
+				for _, st := range out.InstanceStatuses {
+					states[*st.InstanceId] = fmt.Sprintf("%s (status %s)", aws.StringValue(st.InstanceState.Name), aws.StringValue(st.InstanceStatus.Status))
+				}
+			}
+			table.Set(rows())
+		})
+	})
+	opts := []request.WaiterOption{
+		watch,
+		request.WithWaiterDelay(request.ConstantWaiterDelay(5 * time.Second)),
+		request.WithWaiterMaxAttempts(120),
+	}
+
+	var err error
+	switch op.name {
+	case "start":
+		err = svc.WaitUntilInstanceRunningWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: ids}, opts...)
+	case "reboot":
+		// best effort: the status may still be ok from before the reboot
+		err = svc.WaitUntilInstanceStatusOkWithContext(ctx, &ec2.DescribeInstanceStatusInput{InstanceIds: ids}, opts...)
+	default:
+		err = svc.WaitUntilInstanceStoppedWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: ids}, opts...)
+	}
+	table.Clear()
+	if err != nil {
+		fmt.Fprint(w, console.FormatTable(rows()))
+		return fmt.Errorf("wait for instances err: %w", err)
+	}
+
+	desc, err := svc.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: ids})
+	if err != nil {
+		return fmt.Errorf("DescribeInstances err: %w", err)
+	}
+	for _, inst := range instance.InstancesFromDesc(desc) {
+		states[*inst.InstanceId] = aws.StringValue(inst.State.Name)
+	}
+	fmt.Fprint(w, console.FormatTable(rows()))
+
+	return nil
+}
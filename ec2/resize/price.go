@@ -0,0 +1,66 @@
+package resize
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/config"
+)
+
+// onDemandPrice returns the hourly on-demand USD price of instanceType in
+// region for shared tenancy instances running system ("Linux" or
+// "Windows").
+func onDemandPrice(ctx context.Context, instanceType, region, system string) (float64, error) {
+	// the pricing API is only served from a few regions
+	sess := config.Session().Copy(&aws.Config{Region: aws.String("us-east-1")})
+	svc := client.Pricing(sess)
+
+	filter := func(field, value string) *pricing.Filter {
+		return &pricing.Filter{
+			Type:  aws.String(pricing.FilterTypeTermMatch),
+			Field: aws.String(field),
+			Value: aws.String(value),
+		}
+	}
+
+	resp, err := svc.GetProductsWithContext(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters: []*pricing.Filter{
+			filter("instanceType", instanceType),
+			filter("regionCode", region),
+			filter("operatingSystem", system),
+			filter("tenancy", "Shared"),
+			filter("preInstalledSw", "NA"),
+			filter("capacitystatus", "Used"),
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("GetProducts err: %w", err)
+	}
+
+	// each product's terms look like
+	// {"OnDemand": {"<sku>.<offer>": {"priceDimensions": {"<id>": {"pricePerUnit": {"USD": "0.0104"}}}}}}
+	for _, product := range resp.PriceList {
+		terms, _ := product["terms"].(map[string]interface{})
+		onDemand, _ := terms["OnDemand"].(map[string]interface{})
+		for _, term := range onDemand {
+			term, _ := term.(map[string]interface{})
+			dims, _ := term["priceDimensions"].(map[string]interface{})
+			for _, dim := range dims {
+				dim, _ := dim.(map[string]interface{})
+				perUnit, _ := dim["pricePerUnit"].(map[string]interface{})
+				usd, _ := perUnit["USD"].(string)
+				price, err := strconv.ParseFloat(usd, 64)
+				if err == nil && price > 0 {
+					return price, nil
+				}
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("no on-demand price for %s in %s", instanceType, region)
+}
@@ -0,0 +1,276 @@
+package resize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/ec2/instance"
+	"github.com/psanford/aws-buddy/ec2/terminate"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "resize <instance> <new-type>",
+		Short: "Change an instance's type (stop, modify, start)",
+		Long: `Change an instance's type. A running instance is stopped, modified and
+started again; a stopped instance is just modified. If stopping,
+modifying or starting fails or is interrupted, the instance is put back
+to its old type (and started again if it was running).`,
+		RunE:              resizeAction,
+		ValidArgsFunction: completion.Instances(0),
+	}
+
+	return &cmd
+}
+
+func resizeAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if len(args) != 2 {
+		return fmt.Errorf("%w: resize <instance> <new-type>", errs.ErrUsage)
+	}
+	newType := args[1]
+
+	inst, err := instance.Resolve(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("fetch instance err: %w", err)
+	}
+	instanceID := *inst.InstanceId
+	oldType := *inst.InstanceType
+	state := aws.StringValue(inst.State.Name)
+
+	if newType == oldType {
+		return fmt.Errorf("%w: %s is already %s", errs.ErrUsage, instanceID, newType)
+	}
+	if state != ec2.InstanceStateNameRunning && state != ec2.InstanceStateNameStopped {
+		return fmt.Errorf("%w: %s is %s; it must be running or stopped", errs.ErrUsage, instanceID, state)
+	}
+
+	svc := client.EC2(config.Session())
+	if err := validate(ctx, svc, inst, newType); err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	terminate.PrintSummary(w, inst)
+	fmt.Fprintf(w, "new type : %s → %s\n", oldType, newType)
+	fmt.Fprintf(w, "price    : %s\n", priceDelta(ctx, inst, newType))
+
+	wasRunning := state == ec2.InstanceStateNameRunning
+	ids := []*string{inst.InstanceId}
+	modifyInput := &ec2.ModifyInstanceAttributeInput{
+		InstanceId:   inst.InstanceId,
+		InstanceType: &ec2.AttributeValue{Value: aws.String(newType)},
+	}
+
+	if console.DryRun {
+		if wasRunning {
+			console.PrintDryRun("StopInstances", &ec2.StopInstancesInput{InstanceIds: ids})
+		}
+		console.PrintDryRun("ModifyInstanceAttribute", modifyInput)
+		if wasRunning {
+			console.PrintDryRun("StartInstances", &ec2.StartInstancesInput{InstanceIds: ids})
+		}
+		return nil
+	}
+
+	if err := terminate.Confirm(ctx, fmt.Sprintf("resize (%s → %s)", oldType, newType), []*ec2.Instance{inst}); err != nil {
+		return err
+	}
+
+	if wasRunning {
+		fmt.Fprintf(w, "stopping %s\n", instanceID)
+		if _, err := svc.StopInstancesWithContext(ctx, &ec2.StopInstancesInput{InstanceIds: ids}); err != nil {
+			return fmt.Errorf("StopInstances err: %w", err)
+		}
+		if err := svc.WaitUntilInstanceStoppedWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: ids}); err != nil {
+			err = fmt.Errorf("wait for stop err: %w (%s still has type %s)", err, instanceID, oldType)
+			// don't leave it stopped, even if interrupted
+			fmt.Fprintf(w, "starting %s again\n", instanceID)
+			if rbErr := restart(context.WithoutCancel(ctx), svc, ids); rbErr != nil {
+				return fmt.Errorf("%w; restarting err: %v", err, rbErr)
+			}
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "changing type to %s\n", newType)
+	if _, err := svc.ModifyInstanceAttributeWithContext(ctx, modifyInput); err != nil {
+		err = fmt.Errorf("ModifyInstanceAttribute err: %w", err)
+		if wasRunning {
+			fmt.Fprintf(w, "starting %s again as %s\n", instanceID, oldType)
+			if rbErr := start(context.WithoutCancel(ctx), svc, ids); rbErr != nil {
+				return fmt.Errorf("%w; restarting err: %v", err, rbErr)
+			}
+		}
+		return err
+	}
+
+	audit.Record(audit.Entry{
+		Action:   "ec2 resize",
+		Resource: instanceID,
+		OldValue: oldType,
+		NewValue: newType,
+	})
+
+	if !wasRunning {
+		return nil
+	}
+
+	fmt.Fprintf(w, "starting %s\n", instanceID)
+	if err := start(ctx, svc, ids); err != nil {
+		// e.g. InsufficientInstanceCapacity for the new type, or
+		// interrupted; either way put it back as it was
+		err = fmt.Errorf("start as %s err: %w", newType, err)
+		if rbErr := rollback(context.WithoutCancel(ctx), w, svc, inst, oldType, newType); rbErr != nil {
+			return fmt.Errorf("%w; rollback to %s err: %v", err, oldType, rbErr)
+		}
+		return fmt.Errorf("%w; rolled back to %s", err, oldType)
+	}
+
+	fmt.Fprintf(w, "%s is running as %s\n", instanceID, newType)
+	return nil
+}
+
+// validate checks that inst can be changed to newType: the type must
+// exist, be offered in the instance's AZ and match its architecture, and
+// the instance must have an EBS root volume to survive being stopped.
+func validate(ctx context.Context, svc ec2iface.EC2API, inst *ec2.Instance, newType string) error {
+	if aws.StringValue(inst.RootDeviceType) != ec2.DeviceTypeEbs {
+		return fmt.Errorf("%w: %s has an instance store root device and can't be stopped", errs.ErrUsage, *inst.InstanceId)
+	}
+
+	types, err := svc.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: aws.StringSlice([]string{newType}),
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == "InvalidInstanceType" {
+		return fmt.Errorf("%w: unknown instance type %s", errs.ErrUsage, newType)
+	} else if err != nil {
+		return fmt.Errorf("DescribeInstanceTypes err: %w", err)
+	}
+	if len(types.InstanceTypes) == 0 {
+		return fmt.Errorf("%w: unknown instance type %s", errs.ErrUsage, newType)
+	}
+
+	// the type name doesn't reliably say the architecture (a1.* is
+	// graviton), so ask
+	arch := aws.StringValue(inst.Architecture)
+	var supported []string
+	if info := types.InstanceTypes[0].ProcessorInfo; info != nil {
+		supported = aws.StringValueSlice(info.SupportedArchitectures)
+	}
+	var ok bool
+	for _, a := range supported {
+		ok = ok || a == arch
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s is %s but %s is %s", errs.ErrUsage, *inst.InstanceId, arch, newType, strings.Join(supported, ","))
+	}
+
+	az := aws.StringValue(inst.Placement.AvailabilityZone)
+	offerings, err := svc.DescribeInstanceTypeOfferingsWithContext(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(ec2.LocationTypeAvailabilityZone),
+		Filters: []*ec2.Filter{
+			{Name: aws.String("location"), Values: aws.StringSlice([]string{az})},
+			{Name: aws.String("instance-type"), Values: aws.StringSlice([]string{newType})},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("DescribeInstanceTypeOfferings err: %w", err)
+	}
+	if len(offerings.InstanceTypeOfferings) == 0 {
+		return fmt.Errorf("%w: %s isn't offered in %s", errs.ErrUsage, newType, az)
+	}
+
+	return nil
+}
+
+func start(ctx context.Context, svc ec2iface.EC2API, ids []*string) error {
+	if _, err := svc.StartInstancesWithContext(ctx, &ec2.StartInstancesInput{InstanceIds: ids}); err != nil {
+		return fmt.Errorf("StartInstances err: %w", err)
+	}
+	if err := svc.WaitUntilInstanceRunningWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: ids}); err != nil {
+		return fmt.Errorf("wait for start err: %w", err)
+	}
+	return nil
+}
+
+// restart starts an instance that is stopped or stopping.
+func restart(ctx context.Context, svc ec2iface.EC2API, ids []*string) error {
+	if err := svc.WaitUntilInstanceStoppedWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: ids}); err != nil {
+		return fmt.Errorf("wait for stop err: %w", err)
+	}
+	return start(ctx, svc, ids)
+}
+
+// rollback puts a running instance that failed to start as newType back
+// to oldType and starts it again.
+func rollback(ctx context.Context, w io.Writer, svc ec2iface.EC2API, inst *ec2.Instance, oldType, newType string) error {
+	ids := []*string{inst.InstanceId}
+	fmt.Fprintf(w, "rolling back %s to %s\n", *inst.InstanceId, oldType)
+
+	// the instance may have got as far as pending or running before
+	// failing, so stop it before waiting
+	if _, err := svc.StopInstancesWithContext(ctx, &ec2.StopInstancesInput{InstanceIds: ids}); err != nil {
+		return fmt.Errorf("StopInstances err: %w", err)
+	}
+	if err := svc.WaitUntilInstanceStoppedWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: ids}); err != nil {
+		return fmt.Errorf("wait for stop err: %w", err)
+	}
+	_, err := svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId:   inst.InstanceId,
+		InstanceType: &ec2.AttributeValue{Value: aws.String(oldType)},
+	})
+	if err != nil {
+		return fmt.Errorf("ModifyInstanceAttribute err: %w", err)
+	}
+
+	audit.Record(audit.Entry{
+		Action:   "ec2 resize",
+		Resource: *inst.InstanceId,
+		OldValue: newType,
+		NewValue: oldType,
+	})
+
+	return start(ctx, svc, ids)
+}
+
+// priceDelta describes the change in on-demand price from inst's type to
+// newType. Pricing is best effort; the caller may not be allowed to call
+// the pricing API.
+func priceDelta(ctx context.Context, inst *ec2.Instance, newType string) string {
+	system := "Linux"
+	if aws.StringValue(inst.Platform) == ec2.PlatformValuesWindows {
+		system = "Windows"
+	}
+	region := config.ResolvedRegion()
+
+	oldPrice, err := onDemandPrice(ctx, *inst.InstanceType, region, system)
+	if err != nil {
+		return fmt.Sprintf("unknown (%s)", err)
+	}
+	newPrice, err := onDemandPrice(ctx, newType, region, system)
+	if err != nil {
+		return fmt.Sprintf("unknown (%s)", err)
+	}
+
+	sign, delta := "+", newPrice-oldPrice
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	return fmt.Sprintf("$%.4f/hr → $%.4f/hr (%s$%.4f/hr, %s$%.2f/month on-demand %s)", oldPrice, newPrice, sign, delta, sign, delta*730, system)
+}
@@ -1,22 +1,24 @@
 package securitygroup
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/psanford/aws-buddy/regions"
 	"github.com/spf13/cobra"
 )
 
-var (
-	jsonOutput bool
-)
-
 func Command() *cobra.Command {
 	cmd := cobra.Command{
 		Use:     "security_group",
@@ -34,66 +36,142 @@ func sgListCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "list security groups",
-		Run:   sgListAction,
+		RunE:  sgListAction,
 	}
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
+
+	regions.AddFlags(&cmd)
 
 	return &cmd
 }
 
 func sgShowCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "show <sg-id>",
-		Short: "Show a security group",
-		Run:   sgShowAction,
+		Use:               "show <sg-id>",
+		Short:             "Show a security group",
+		RunE:              sgShowAction,
+		ValidArgsFunction: completion.SecurityGroups(0),
 	}
 	return &cmd
 }
 
-func sgListAction(cmd *cobra.Command, args []string) {
-	svc := ec2.New(config.Session())
+type sgRow struct {
+	Region      string            `json:"region" output:"hidden"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Tags        map[string]string `json:"tags"`
+}
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+func newSGRow(sg *ec2.SecurityGroup) sgRow {
+	tags := make(map[string]string)
+	for _, t := range sg.Tags {
+		tags[*t.Key] = *t.Value
+	}
+	return sgRow{
+		ID:          str(sg.GroupId),
+		Name:        str(sg.GroupName),
+		Description: str(sg.Description),
+		Tags:        tags,
+	}
+}
 
-	err := svc.DescribeSecurityGroupsPages(nil, func(output *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
-		for _, sg := range output.SecurityGroups {
-			id := str(sg.GroupId)
-			name := str(sg.GroupName)
-			desc := str(sg.Description)
+func sgListAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := output.New(cmd.OutOrStdout())
 
-			if jsonOutput {
-				jsonOut.Encode(sg)
-			} else {
-				tags := make([]string, 0, len(sg.Tags))
-				for _, t := range sg.Tags {
-					tags = append(tags, fmt.Sprintf("%s:%q", *t.Key, *t.Value))
-				}
+	listErr := regions.Each(ctx, out, func(sess *session.Session, region string, out *output.Printer) error {
+		svc := client.EC2(sess)
 
-				fmt.Printf("%20s %-40s %q %s\n", id, name, desc, strings.Join(tags, ","))
+		err := svc.DescribeSecurityGroupsPagesWithContext(ctx, nil, func(resp *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+			for _, sg := range resp.SecurityGroups {
+				row := newSGRow(sg)
+				row.Region = region
+				out.AddDetail(row, regions.Detail(region, sg))
 			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("DescribeSecurityGroups error: %w", err)
 		}
-		return true
+		return nil
 	})
-	if err != nil {
-		log.Fatalf("DescribeSecurityGroups error: %s", err)
+
+	if err := out.Flush(); err != nil {
+		return err
 	}
+	return listErr
 }
 
-func sgShowAction(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
-		log.Fatalf("Missing required sg-id argument")
+type ruleRow struct {
+	Direction string   `json:"direction"`
+	Protocol  string   `json:"protocol"`
+	Ports     string   `json:"ports"`
+	Sources   []string `json:"sources"`
+}
+
+func ruleRows(direction string, perms []*ec2.IpPermission) []ruleRow {
+	rows := make([]ruleRow, 0, len(perms))
+	for _, perm := range perms {
+		row := ruleRow{
+			Direction: direction,
+			Protocol:  str(perm.IpProtocol),
+			Ports:     "all",
+		}
+		if row.Protocol == "-1" {
+			row.Protocol = "all"
+		}
+		if perm.FromPort != nil && *perm.FromPort != -1 {
+			row.Ports = fmt.Sprintf("%d", *perm.FromPort)
+			if perm.ToPort != nil && *perm.ToPort != *perm.FromPort {
+				row.Ports = fmt.Sprintf("%d-%d", *perm.FromPort, *perm.ToPort)
+			}
+		}
+		for _, r := range perm.IpRanges {
+			row.Sources = append(row.Sources, str(r.CidrIp))
+		}
+		for _, r := range perm.Ipv6Ranges {
+			row.Sources = append(row.Sources, str(r.CidrIpv6))
+		}
+		for _, p := range perm.PrefixListIds {
+			row.Sources = append(row.Sources, str(p.PrefixListId))
+		}
+		for _, g := range perm.UserIdGroupPairs {
+			row.Sources = append(row.Sources, str(g.GroupId))
+		}
+		rows = append(rows, row)
 	}
+	return rows
+}
 
-	svc := ec2.New(config.Session())
+func sgShowAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.EC2(config.Session())
 
-	groups := findSGs(svc, "group-id", args[0])
+	if len(args) == 0 && console.Interactive() {
+		id, err := pickSG(ctx, svc)
+		if err != nil {
+			return err
+		}
+		args = []string{id}
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("%w: missing required sg-id argument", errs.ErrUsage)
+	}
+
+	groups, err := findSGs(ctx, svc, "group-id", args[0])
+	if err != nil {
+		return err
+	}
 	if len(groups) == 0 {
-		groups = findSGs(svc, "group-name", args[0])
+		groups, err = findSGs(ctx, svc, "group-name", args[0])
+		if err != nil {
+			return err
+		}
 	}
 
 	if len(groups) == 0 {
-		log.Fatal("No matching security group found")
+		return fmt.Errorf("%w: no matching security group found", errs.ErrNotFound)
 	}
 
 	var matchGroup ec2.SecurityGroup
@@ -113,44 +191,39 @@ func sgShowAction(cmd *cobra.Command, args []string) {
 		}
 
 		if !matchByID {
-			log.Fatalf("Multiple matching groups found: %s\n", strings.Join(names, ","))
+			return fmt.Errorf("Multiple matching groups found: %s", strings.Join(names, ","))
 		}
 	} else {
 		matchGroup = *groups[0]
 	}
 
 	sg := matchGroup
-	id := str(sg.GroupId)
-	name := str(sg.GroupName)
-	desc := str(sg.Description)
 
+	w := cmd.OutOrStdout()
+	out := output.New(w)
+	if !output.IsTable(out.Default) {
+		out.AddDetail(newSGRow(&sg), &sg)
+		return out.Flush()
+	}
+
+	row := newSGRow(&sg)
 	tags := make([]string, 0, len(sg.Tags))
 	for _, t := range sg.Tags {
 		tags = append(tags, fmt.Sprintf("%s:%q", *t.Key, *t.Value))
 	}
 
-	fmt.Printf("%20s %-40s %q %s\n", id, name, desc, strings.Join(tags, ","))
+	fmt.Fprintf(w, "%20s %-40s %q %s\n\n", row.ID, row.Name, row.Description, strings.Join(tags, ","))
 
-	fmt.Printf("ingress rules:\n")
-	for _, perm := range sg.IpPermissions {
-		jsonText, err := json.Marshal(perm)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("%s\n", jsonText)
+	for _, rule := range ruleRows("ingress", sg.IpPermissions) {
+		out.Add(rule)
 	}
-
-	fmt.Printf("\negress rules:\n")
-	for _, perm := range sg.IpPermissionsEgress {
-		jsonText, err := json.Marshal(perm)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("%s\n", jsonText)
+	for _, rule := range ruleRows("egress", sg.IpPermissionsEgress) {
+		out.Add(rule)
 	}
+	return out.Flush()
 }
 
-func findSGs(svc *ec2.EC2, attr, val string) []*ec2.SecurityGroup {
+func findSGs(ctx context.Context, svc ec2iface.EC2API, attr, val string) ([]*ec2.SecurityGroup, error) {
 	input := ec2.DescribeSecurityGroupsInput{
 		Filters: []*ec2.Filter{
 			{
@@ -159,12 +232,12 @@ func findSGs(svc *ec2.EC2, attr, val string) []*ec2.SecurityGroup {
 			},
 		},
 	}
-	output, err := svc.DescribeSecurityGroups(&input)
+	resp, err := svc.DescribeSecurityGroupsWithContext(ctx, &input)
 	if err != nil {
-		log.Fatalf("DescribeSecurityGroups error: %s", err)
+		return nil, fmt.Errorf("DescribeSecurityGroups error: %w", err)
 	}
 
-	return output.SecurityGroups
+	return resp.SecurityGroups, nil
 }
 
 func str(s *string) string {
@@ -173,3 +246,25 @@ func str(s *string) string {
 	}
 	return ""
 }
+
+func pickSG(ctx context.Context, svc ec2iface.EC2API) (string, error) {
+	var groups []*ec2.SecurityGroup
+	err := svc.DescribeSecurityGroupsPagesWithContext(ctx, nil, func(resp *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+		groups = append(groups, resp.SecurityGroups...)
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("DescribeSecurityGroups error: %w", err)
+	}
+
+	rows := make([][]string, 0, len(groups))
+	for _, sg := range groups {
+		rows = append(rows, []string{*sg.GroupId, *sg.GroupName, aws.StringValue(sg.VpcId), aws.StringValue(sg.Description)})
+	}
+
+	idx, err := console.PickRow("security group", rows)
+	if err != nil {
+		return "", err
+	}
+	return *groups[idx].GroupId, nil
+}
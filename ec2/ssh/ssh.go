@@ -0,0 +1,238 @@
+package ssh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/ec2/instance"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ipFlag     string
+	userFlag   string
+	viaSSMFlag bool
+)
+
+func Command() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "ssh <instance> [-- ssh args...]",
+		Short: "SSH to an instance (by id, name, ip or unique name substring)",
+		Long: `SSH to an instance. The login user is inferred from the instance's AMI
+unless --user is given. Arguments after -- are passed to ssh:
+
+  aws-buddy ec2 ssh web-1 -- -L 8080:localhost:80
+  aws-buddy ec2 ssh web-1 -- uptime
+
+With --via-ssm the connection is tunneled through an SSM session, so the
+instance doesn't need to be reachable directly. This needs the Session
+Manager plugin (session-manager-plugin) installed.`,
+		RunE:              sshAction,
+		ValidArgsFunction: completion.Instances(0),
+	}
+
+	cmd.Flags().StringVarP(&ipFlag, "ip", "", "public", "Address to connect to, public or private; falls back to the other if the instance doesn't have one")
+	cmd.Flags().StringVarP(&userFlag, "user", "l", "", "Login user (default inferred from the AMI)")
+	cmd.Flags().BoolVarP(&viaSSMFlag, "via-ssm", "", false, "Connect through an SSM session instead of directly")
+
+	return &cmd
+}
+
+func sshAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	var sshArgs []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		args, sshArgs = args[:dash], args[dash:]
+	}
+	if ipFlag != "public" && ipFlag != "private" {
+		return fmt.Errorf("%w: --ip must be public or private", errs.ErrUsage)
+	}
+
+	var (
+		inst *ec2.Instance
+		err  error
+	)
+	if len(args) == 0 && console.Interactive() {
+		inst, err = instance.Pick(ctx)
+		if err != nil {
+			return err
+		}
+	} else if len(args) != 1 {
+		return fmt.Errorf("%w: ssh <instance> [-- ssh args...]", errs.ErrUsage)
+	} else {
+		inst, err = instance.Resolve(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("fetch instance err: %w", err)
+		}
+	}
+
+	if state := aws.StringValue(inst.State.Name); state != ec2.InstanceStateNameRunning {
+		return fmt.Errorf("%s is %s", *inst.InstanceId, state)
+	}
+
+	user := userFlag
+	if user == "" {
+		user = loginUser(ctx, inst)
+	}
+
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return err
+	}
+
+	argv := []string{"ssh"}
+	env := os.Environ()
+	if viaSSMFlag {
+		self, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		// the proxy is a new aws-buddy process, so hand it the
+		// credentials and region this one resolved
+		creds, err := config.Session().Config.Credentials.GetWithContext(ctx)
+		if err != nil {
+			return fmt.Errorf("get credentials err: %w", err)
+		}
+		env = config.CredentialEnv(env, creds, config.ResolvedRegion())
+
+		argv = append(argv,
+			"-o", fmt.Sprintf("ProxyCommand=%s ec2 ssm-proxy %%h %%p", shellQuote(self)),
+			fmt.Sprintf("%s@%s", user, *inst.InstanceId),
+		)
+	} else {
+		addr := address(inst)
+		if addr == "" {
+			return fmt.Errorf("%s has no IP address; try --via-ssm", *inst.InstanceId)
+		}
+		argv = append(argv, fmt.Sprintf("%s@%s", user, addr))
+	}
+	argv = append(argv, sshArgs...)
+
+	fmt.Fprintf(os.Stderr, "# Running %s\n", strings.Join(argv, " "))
+	return syscall.Exec(sshPath, argv, env)
+}
+
+// address returns the instance's IP address, preferring the kind chosen
+// with --ip.
+func address(inst *ec2.Instance) string {
+	public, private := aws.StringValue(inst.PublicIpAddress), aws.StringValue(inst.PrivateIpAddress)
+	if ipFlag == "private" {
+		public, private = private, public
+	}
+	if public != "" {
+		return public
+	}
+	return private
+}
+
+// loginUser guesses the default login user for the instance's AMI from
+// its name and owner.
+func loginUser(ctx context.Context, inst *ec2.Instance) string {
+	// launch only starts ubuntu instances, so that's the best guess if
+	// the AMI can't be looked up (e.g. it has been deregistered)
+	user := "ubuntu"
+
+	resp, err := client.EC2(config.Session()).DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
+		ImageIds: []*string{inst.ImageId},
+	})
+	if err != nil || len(resp.Images) == 0 {
+		return user
+	}
+	img := resp.Images[0]
+	name := strings.ToLower(aws.StringValue(img.Name))
+
+	switch {
+	case aws.StringValue(img.OwnerId) == canonicalOwnerID, strings.Contains(name, "ubuntu"):
+		return "ubuntu"
+	case strings.Contains(name, "debian"):
+		return "admin"
+	case strings.Contains(name, "centos"):
+		return "centos"
+	case strings.Contains(name, "fedora"):
+		return "fedora"
+	case strings.Contains(name, "bitnami"):
+		return "bitnami"
+	case strings.HasPrefix(name, "amzn"), strings.HasPrefix(name, "al20"), strings.Contains(name, "rhel"), strings.Contains(name, "suse"):
+		return "ec2-user"
+	case aws.StringValue(inst.Platform) == ec2.PlatformValuesWindows:
+		return "Administrator"
+	}
+	return user
+}
+
+const canonicalOwnerID = "099720109477"
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ProxyCommand returns the hidden ssm-proxy command ssh --via-ssm uses as
+// its ProxyCommand. It starts an SSM session forwarding to the instance's
+// ssh port and hands it to the session manager plugin, which relays it
+// over stdin and stdout.
+func ProxyCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:    "ssm-proxy <instance-id> <port>",
+		Short:  "Relay stdin/stdout to an instance port over SSM (ssh ProxyCommand)",
+		Args:   cobra.ExactArgs(2),
+		Hidden: true,
+		RunE:   proxyAction,
+	}
+
+	return &cmd
+}
+
+func proxyAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	instanceID, port := args[0], args[1]
+
+	plugin, err := exec.LookPath("session-manager-plugin")
+	if err != nil {
+		return fmt.Errorf("session-manager-plugin is needed for --via-ssm: %w", err)
+	}
+
+	input := &ssm.StartSessionInput{
+		Target:       aws.String(instanceID),
+		DocumentName: aws.String("AWS-StartSSHSession"),
+		Parameters: map[string][]*string{
+			"portNumber": {aws.String(port)},
+		},
+	}
+
+	sess := config.Session()
+	svc := client.SSM(sess)
+	resp, err := svc.StartSessionWithContext(ctx, input)
+	if err != nil {
+		return fmt.Errorf("StartSession err: %w", err)
+	}
+
+	respJSON, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	region := config.ResolvedRegion()
+	endpoint := sess.ClientConfig(ssm.EndpointsID).Endpoint
+
+	// the same arguments the aws cli passes the plugin
+	argv := []string{"session-manager-plugin", string(respJSON), region, "StartSession", config.Profile, string(inputJSON), endpoint}
+	return syscall.Exec(plugin, argv, os.Environ())
+}
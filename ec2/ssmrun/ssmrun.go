@@ -0,0 +1,418 @@
+// Package ssmrun implements ec2 exec, which runs shell commands on
+// instances with SSM Run Command, and ec2 ssm-status.
+package ssmrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/ec2/filter"
+	"github.com/psanford/aws-buddy/ec2/instance"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	filterFlag  []string
+	timeoutFlag time.Duration
+)
+
+func ExecCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "exec <instance> [...<instance>] -- <shell command>",
+		Short: "Run a shell command on instances with SSM Run Command",
+		Long: `Run a shell command on one or more instances with SSM Run Command
+(AWS-RunShellScript) and print each instance's output, prefixed with its
+Name tag. Output is streamed while the command runs on up to 10
+instances; on more, each instance's output is printed as it finishes:
+
+  aws-buddy ec2 exec web-1 web-2 -- uptime
+  aws-buddy ec2 exec -f 'tag:env=prod state=running' -- 'df -h /'
+
+The instances must be registered with SSM (see ec2 ssm-status). SSM
+returns at most the first 24000 characters of each instance's stdout and
+8000 of its stderr. Asks for confirmation unless --yes is given, and
+--dry-run prints the request instead. Exits non-zero if the command
+fails on any instance.`,
+		RunE:              execAction,
+		ValidArgsFunction: completion.Instances(),
+	}
+
+	cmd.Flags().StringArrayVarP(&filterFlag, "filter", "f", nil, "Run on the instances matching these filter expressions (see ec2 list --help)")
+	cmd.Flags().DurationVarP(&timeoutFlag, "timeout", "", 10*time.Minute, "Stop the command if it hasn't finished after this long")
+
+	return &cmd
+}
+
+// streamMax is the most instances whose running output is fetched while
+// they run; beyond it each instance's output is printed once it finishes,
+// so large fleets cost one status call per command per poll.
+const streamMax = 10
+
+// target is an instance the command was sent to, how much of its output
+// has been printed, and its result once finished.
+type target struct {
+	id     string
+	label  string
+	stdout int
+	stderr int
+	status string
+	code   int64
+}
+
+func execAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 || dash == len(args) {
+		return fmt.Errorf("%w: exec <instance> [...<instance>] -- <shell command>", errs.ErrUsage)
+	}
+	queries, script := args[:dash], strings.Join(args[dash:], " ")
+
+	var (
+		instances []*ec2.Instance
+		err       error
+	)
+	if len(filterFlag) > 0 && len(queries) > 0 {
+		return fmt.Errorf("%w: give instances or --filter, not both", errs.ErrUsage)
+	} else if len(filterFlag) > 0 {
+		instances, err = filter.Instances(ctx, filterFlag)
+	} else if len(queries) > 0 {
+		instances, err = instance.ResolveAll(ctx, queries)
+	} else {
+		return fmt.Errorf("%w: exec <instance> [...<instance>] -- <shell command>", errs.ErrUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	targets := make([]*target, 0, len(instances))
+	ids := make([]string, 0, len(instances))
+	width := 0
+	for _, inst := range instances {
+		label := instance.Name(inst)
+		if label == "" {
+			label = *inst.InstanceId
+		}
+		if len(label) > width {
+			width = len(label)
+		}
+		targets = append(targets, &target{id: *inst.InstanceId, label: label})
+		ids = append(ids, *inst.InstanceId)
+	}
+	for _, t := range targets {
+		t.label = fmt.Sprintf("%-*s | ", width, t.label)
+	}
+
+	timeout := int64(timeoutFlag / time.Second)
+	if timeout < 30 {
+		// the minimum SSM accepts
+		timeout = 30
+	}
+
+	svc := client.SSM(config.Session())
+	var inputs []*ssm.SendCommandInput
+	// SendCommand takes at most 50 instance ids
+	for start := 0; start < len(ids); start += 50 {
+		end := start + 50
+		if end > len(ids) {
+			end = len(ids)
+		}
+		inputs = append(inputs, &ssm.SendCommandInput{
+			DocumentName:   aws.String("AWS-RunShellScript"),
+			Comment:        aws.String("aws-buddy ec2 exec"),
+			InstanceIds:    aws.StringSlice(ids[start:end]),
+			TimeoutSeconds: aws.Int64(timeout),
+			Parameters: map[string][]*string{
+				"commands":         {aws.String(script)},
+				"executionTimeout": {aws.String(fmt.Sprint(timeout))},
+			},
+		})
+	}
+
+	if console.DryRun {
+		for _, input := range inputs {
+			console.PrintDryRun("SendCommand", input)
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(targets))
+	for _, t := range targets {
+		names = append(names, strings.TrimSpace(strings.TrimSuffix(t.label, " | ")))
+	}
+	what := strings.Join(names, ", ")
+	if len(names) > 3 {
+		what = fmt.Sprintf("%d instances", len(names))
+	}
+	ok := console.Confirm(ctx, fmt.Sprintf("Run %q on %s? [yN]?", script, what))
+	if !ok {
+		return errs.ErrAborted
+	}
+
+	var (
+		commands   []string
+		commandIDs = make(map[string]string)
+	)
+	for _, input := range inputs {
+		resp, err := svc.SendCommandWithContext(ctx, input)
+		if err != nil {
+			return fmt.Errorf("SendCommand err: %w", err)
+		}
+		commandID := aws.StringValue(resp.Command.CommandId)
+		commands = append(commands, commandID)
+		for _, id := range aws.StringValueSlice(input.InstanceIds) {
+			commandIDs[id] = commandID
+		}
+		audit.Record(audit.Entry{
+			Action:   "ec2 exec",
+			Resource: strings.Join(aws.StringValueSlice(input.InstanceIds), ","),
+			NewValue: fmt.Sprintf("%s: %s", commandID, script),
+		})
+	}
+
+	w := cmd.OutOrStdout()
+	pending := targets
+	for len(pending) > 0 {
+		// one ListCommandInvocations call per command polls every
+		// instance's status; output is only fetched for instances that
+		// have finished, or are running when there are few enough to
+		// stream
+		statuses := make(map[string]string)
+		for _, commandID := range commands {
+			err := svc.ListCommandInvocationsPagesWithContext(ctx, &ssm.ListCommandInvocationsInput{
+				CommandId: aws.String(commandID),
+			}, func(resp *ssm.ListCommandInvocationsOutput, lastPage bool) bool {
+				for _, inv := range resp.CommandInvocations {
+					statuses[aws.StringValue(inv.InstanceId)] = aws.StringValue(inv.Status)
+				}
+				return true
+			})
+			if errs.IsCanceled(err) {
+				return interrupted(svc, commandIDs, pending)
+			} else if err != nil {
+				return fmt.Errorf("ListCommandInvocations err: %w", err)
+			}
+		}
+
+		var still []*target
+		for _, t := range pending {
+			// invocations aren't listed until shortly after SendCommand
+			status := statuses[t.id]
+			done := finished(status)
+			if !done && !(len(targets) <= streamMax && status == ssm.CommandInvocationStatusInProgress) {
+				still = append(still, t)
+				continue
+			}
+			inv, err := svc.GetCommandInvocationWithContext(ctx, &ssm.GetCommandInvocationInput{
+				CommandId:  aws.String(commandIDs[t.id]),
+				InstanceId: aws.String(t.id),
+			})
+			if errs.IsCanceled(err) {
+				return interrupted(svc, commandIDs, unfinished(pending))
+			} else if err != nil {
+				return fmt.Errorf("GetCommandInvocation err: %w", err)
+			}
+
+			// the invocation may have finished since it was listed
+			done = finished(aws.StringValue(inv.Status))
+			t.stdout = printLines(w, t.label, aws.StringValue(inv.StandardOutputContent), t.stdout, done)
+			t.stderr = printLines(os.Stderr, t.label, aws.StringValue(inv.StandardErrorContent), t.stderr, done)
+			if done {
+				t.status = aws.StringValue(inv.Status)
+				t.code = aws.Int64Value(inv.ResponseCode)
+			} else {
+				still = append(still, t)
+			}
+		}
+		pending = still
+		if len(pending) == 0 {
+			break
+		}
+		if err := aws.SleepWithContext(ctx, 2*time.Second); err != nil {
+			return interrupted(svc, commandIDs, pending)
+		}
+	}
+
+	var errList []error
+	for _, t := range targets {
+		if t.status != ssm.CommandInvocationStatusSuccess {
+			errList = append(errList, fmt.Errorf("%s: %s (exit %d)", t.id, t.status, t.code))
+		}
+	}
+	if len(targets) == 1 && len(errList) == 1 && targets[0].code > 0 {
+		// a single instance exits with the command's own status
+		return &errs.ExitStatusError{Code: int(targets[0].code), Err: errList[0]}
+	}
+	return errs.PartialFailure(errList)
+}
+
+func finished(status string) bool {
+	switch status {
+	case ssm.CommandInvocationStatusSuccess, ssm.CommandInvocationStatusFailed,
+		ssm.CommandInvocationStatusCancelled, ssm.CommandInvocationStatusTimedOut:
+		return true
+	}
+	return false
+}
+
+// printLines prints content's complete lines after the first printed
+// bytes, each prefixed with label, and returns the new count of printed
+// bytes. If done, a trailing partial line is printed too.
+func printLines(w io.Writer, label, content string, printed int, done bool) int {
+	if len(content) <= printed {
+		return printed
+	}
+	rest := content[printed:]
+	end := strings.LastIndex(rest, "\n") + 1
+	if done {
+		end = len(rest)
+	}
+	if end == 0 {
+		return printed
+	}
+	for _, line := range strings.SplitAfter(rest[:end], "\n") {
+		if line == "" {
+			continue
+		}
+		fmt.Fprintf(w, "%s%s", label, line)
+		if !strings.HasSuffix(line, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+	return printed + end
+}
+
+func unfinished(targets []*target) []*target {
+	var out []*target
+	for _, t := range targets {
+		if t.status == "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// interrupted cancels the command on the instances that haven't finished
+// and reports which ones they were.
+func interrupted(svc ssmiface.SSMAPI, commandIDs map[string]string, pending []*target) error {
+	ctx := context.Background()
+	byCommand := make(map[string][]string)
+	for _, t := range pending {
+		byCommand[commandIDs[t.id]] = append(byCommand[commandIDs[t.id]], t.id)
+	}
+	for commandID, ids := range byCommand {
+		_, err := svc.CancelCommandWithContext(ctx, &ssm.CancelCommandInput{
+			CommandId:   aws.String(commandID),
+			InstanceIds: aws.StringSlice(ids),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "# CancelCommand %s err: %s\n", commandID, err)
+		}
+	}
+	for _, t := range pending {
+		fmt.Fprintf(os.Stderr, "# canceled on %s (%s)\n", t.id, strings.TrimSuffix(t.label, " | "))
+	}
+	return context.Canceled
+}
+
+func StatusCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "ssm-status",
+		Short: "Show which instances are registered with SSM and their agent versions",
+		RunE:  statusAction,
+	}
+
+	return &cmd
+}
+
+type statusRow struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	State        string    `json:"state"`
+	PingStatus   string    `json:"ping_status"`
+	AgentVersion string    `json:"agent_version"`
+	Latest       bool      `json:"latest"`
+	Platform     string    `json:"platform"`
+	LastPing     time.Time `json:"last_ping" output:"hidden"`
+}
+
+func statusAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	infos := make(map[string]*ssm.InstanceInformation)
+	var order []string
+	err := client.SSM(config.Session()).DescribeInstanceInformationPagesWithContext(ctx, &ssm.DescribeInstanceInformationInput{}, func(resp *ssm.DescribeInstanceInformationOutput, lastPage bool) bool {
+		for _, info := range resp.InstanceInformationList {
+			id := aws.StringValue(info.InstanceId)
+			infos[id] = info
+			order = append(order, id)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("DescribeInstanceInformation err: %w", err)
+	}
+
+	instances, err := instance.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := output.New(cmd.OutOrStdout())
+	seen := make(map[string]bool)
+	for _, inst := range instances {
+		inst := inst
+		id := *inst.InstanceId
+		seen[id] = true
+		row := statusRow{
+			ID:         id,
+			Name:       instance.Name(&inst),
+			State:      aws.StringValue(inst.State.Name),
+			PingStatus: "not registered",
+		}
+		if info := infos[id]; info != nil {
+			fillStatus(&row, info)
+		}
+		out.Add(row)
+	}
+
+	// managed nodes that aren't instances in this region, e.g. hybrid
+	// activations (mi-...)
+	for _, id := range order {
+		if seen[id] {
+			continue
+		}
+		info := infos[id]
+		row := statusRow{
+			ID:   id,
+			Name: aws.StringValue(info.ComputerName),
+		}
+		fillStatus(&row, info)
+		out.Add(row)
+	}
+
+	return out.Flush()
+}
+
+func fillStatus(row *statusRow, info *ssm.InstanceInformation) {
+	row.PingStatus = aws.StringValue(info.PingStatus)
+	row.AgentVersion = aws.StringValue(info.AgentVersion)
+	row.Latest = aws.BoolValue(info.IsLatestVersion)
+	row.Platform = strings.TrimSpace(aws.StringValue(info.PlatformName) + " " + aws.StringValue(info.PlatformVersion))
+	row.LastPing = aws.TimeValue(info.LastPingDateTime)
+}
@@ -2,13 +2,16 @@ package tag
 
 import (
 	"fmt"
-	"log"
-	"time"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
 	"github.com/psanford/aws-buddy/config"
 	"github.com/psanford/aws-buddy/console"
 	"github.com/psanford/aws-buddy/ec2/instance"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
@@ -27,60 +30,71 @@ func Command() *cobra.Command {
 
 func tagListCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:     "list <instance-id>",
-		Aliases: []string{"ls"},
-		Short:   "list tags on instance",
-		Run:     tagListAction,
+		Use:               "list <instance>",
+		Aliases:           []string{"ls"},
+		Short:             "list tags on instance",
+		RunE:              tagListAction,
+		ValidArgsFunction: completion.Instances(0),
 	}
 
 	return &cmd
 }
 
-func tagListAction(cmd *cobra.Command, args []string) {
+func tagListAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatal("Missing required <instance-id>")
+		return fmt.Errorf("%w: missing required <instance>", errs.ErrUsage)
 	}
 
-	instanceID := args[0]
-
-	inst, err := instance.Get(instanceID)
+	inst, err := instance.Resolve(ctx, args[0])
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	instanceID := *inst.InstanceId
 
 	var name string
-	tbl := make([][]string, 0, len(inst.Tags))
+	w := cmd.OutOrStdout()
+	out := output.New(w)
 	for _, t := range inst.Tags {
-		tbl = append(tbl, []string{*t.Key, *t.Value})
+		out.AddDetail(tagRow{Key: *t.Key, Value: *t.Value}, t)
 		if *t.Key == "Name" {
 			name = *t.Value
 		}
 	}
 
-	fmt.Printf("%s (%s) tags:\n", name, instanceID)
-	fmt.Print(console.FormatTable(tbl))
+	if output.IsTable(out.Default) {
+		fmt.Fprintf(w, "%s (%s) tags:\n", name, instanceID)
+	}
+	return out.Flush()
+}
+
+type tagRow struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 func tagSetCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "set <instance-id> <tag-name> <tag-value>",
-		Short: "set tag on instance",
-		Run:   setTagAction,
+		Use:               "set <instance> <tag-name> <tag-value>",
+		Short:             "set tag on instance",
+		RunE:              setTagAction,
+		ValidArgsFunction: completion.Instances(0),
 	}
 
 	return &cmd
 }
 
-func setTagAction(cmd *cobra.Command, args []string) {
+func setTagAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 3 {
-		log.Fatal("Missing required <instance-id> <tag-name> <tag-value>")
+		return fmt.Errorf("%w: missing required <instance> <tag-name> <tag-value>", errs.ErrUsage)
 	}
 
-	instanceID := args[0]
-	inst, err := instance.Get(instanceID)
+	inst, err := instance.Resolve(ctx, args[0])
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	instanceID := *inst.InstanceId
 
 	var (
 		instName string
@@ -97,19 +111,11 @@ func setTagAction(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	fmt.Printf("%s (%s)\n\n", instanceID, instName)
-	fmt.Printf("tag %s: %s => %s\n\n", tagName, oldVal, newVal)
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "%s (%s)\n\n", instanceID, instName)
+	fmt.Fprintf(w, "tag %s: %s => %s\n\n", tagName, oldVal, newVal)
 
-	ok := console.Confirm("Are you sure you want to make this change [yN]? ")
-	if !ok {
-		log.Fatalln("Aborting")
-	}
-
-	// give you a chance to reconsider and ctrl-c
-	time.Sleep(3 * time.Second)
-
-	svc := ec2.New(config.Session())
-	_, err = svc.CreateTags(&ec2.CreateTagsInput{
+	input := &ec2.CreateTagsInput{
 		Resources: []*string{&instanceID},
 		Tags: []*ec2.Tag{
 			{
@@ -117,32 +123,61 @@ func setTagAction(cmd *cobra.Command, args []string) {
 				Value: &newVal,
 			},
 		},
-	})
+	}
+
+	if console.DryRun {
+		console.PrintDryRun("CreateTags", input)
+		return nil
+	}
+
+	ok := console.Confirm(ctx, "Are you sure you want to make this change [yN]? ")
+	if !ok {
+		return errs.ErrAborted
+	}
+
+	// give you a chance to reconsider and ctrl-c
+	if err := console.GracePeriod(ctx); err != nil {
+		return err
+	}
+
+	svc := client.EC2(config.Session())
+	_, err = svc.CreateTagsWithContext(ctx, input)
 	if err != nil {
-		log.Fatalf("CreateTag err: %s\n", err)
+		return fmt.Errorf("CreateTag err: %w", err)
 	}
+
+	audit.Record(audit.Entry{
+		Action:   "tag set",
+		Resource: fmt.Sprintf("%s tag:%s", instanceID, tagName),
+		OldValue: oldVal,
+		NewValue: newVal,
+	})
+
+	return nil
 }
 
 func tagRemoveCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "rm <instance-id> <tag-name>",
-		Short: "remove tag on instance",
-		Run:   removeTagAction,
+		Use:               "rm <instance> <tag-name>",
+		Short:             "remove tag on instance",
+		RunE:              removeTagAction,
+		ValidArgsFunction: completion.Instances(0),
 	}
 
 	return &cmd
 }
 
-func removeTagAction(cmd *cobra.Command, args []string) {
+func removeTagAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 2 {
-		log.Fatal("Missing required <instance-id> <tag-name>")
+		return fmt.Errorf("%w: missing required <instance> <tag-name>", errs.ErrUsage)
 	}
 
-	instanceID := args[0]
-	inst, err := instance.Get(instanceID)
+	inst, err := instance.Resolve(ctx, args[0])
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	instanceID := *inst.InstanceId
 
 	var (
 		instName string
@@ -159,22 +194,14 @@ func removeTagAction(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	fmt.Printf("%s (%s)\n\n", instanceID, instName)
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "%s (%s)\n\n", instanceID, instName)
 	if oldVal == nil {
-		log.Fatalf("Tag not set on instance")
-	}
-	fmt.Printf("tag %s: %s => (deleted)\n\n", tagName, *oldVal)
-
-	ok := console.Confirm("Are you sure you want to make this change [yN]? ")
-	if !ok {
-		log.Fatalln("Aborting")
+		return fmt.Errorf("Tag not set on instance")
 	}
+	fmt.Fprintf(w, "tag %s: %s => (deleted)\n\n", tagName, *oldVal)
 
-	// give you a chance to reconsider and ctrl-c
-	time.Sleep(3 * time.Second)
-
-	svc := ec2.New(config.Session())
-	svc.DeleteTags(&ec2.DeleteTagsInput{
+	input := &ec2.DeleteTagsInput{
 		Resources: []*string{&instanceID},
 		Tags: []*ec2.Tag{
 			{
@@ -182,5 +209,35 @@ func removeTagAction(cmd *cobra.Command, args []string) {
 				Value: oldVal,
 			},
 		},
+	}
+
+	if console.DryRun {
+		console.PrintDryRun("DeleteTags", input)
+		return nil
+	}
+
+	ok := console.Confirm(ctx, "Are you sure you want to make this change [yN]? ")
+	if !ok {
+		return errs.ErrAborted
+	}
+
+	// give you a chance to reconsider and ctrl-c
+	if err := console.GracePeriod(ctx); err != nil {
+		return err
+	}
+
+	svc := client.EC2(config.Session())
+	_, err = svc.DeleteTagsWithContext(ctx, input)
+	if err != nil {
+		return fmt.Errorf("DeleteTags err: %w", err)
+	}
+
+	audit.Record(audit.Entry{
+		Action:   "tag rm",
+		Resource: fmt.Sprintf("%s tag:%s", instanceID, tagName),
+		OldValue: *oldVal,
+		NewValue: "(deleted)",
 	})
+
+	return nil
 }
@@ -1,66 +1,112 @@
 package terminate
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"time"
+	"io"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/fatih/color"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
 	"github.com/psanford/aws-buddy/config"
 	"github.com/psanford/aws-buddy/console"
 	"github.com/psanford/aws-buddy/ec2/instance"
+	"github.com/psanford/aws-buddy/errs"
 	"github.com/spf13/cobra"
 )
 
 func Command() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "terminate <i-instanceid>",
-		Short: "Terminate instance",
-		Run:   terminateAction,
+		Use:               "terminate <instance>",
+		Short:             "Terminate instance",
+		RunE:              terminateAction,
+		ValidArgsFunction: completion.Instances(0),
 	}
 
 	return &cmd
 }
 
-func terminateAction(cmd *cobra.Command, args []string) {
-	if len(args) == 0 {
-		log.Fatalf("usage: terminate <i-instanceid>")
+func terminateAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	var (
+		inst *ec2.Instance
+		err  error
+	)
+	if len(args) == 0 && console.Interactive() {
+		inst, err = instance.Pick(ctx)
+		if err != nil {
+			return err
+		}
+	} else if len(args) == 0 {
+		return fmt.Errorf("%w: terminate <instance>", errs.ErrUsage)
+	} else {
+		inst, err = instance.Resolve(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("fetch instance err: %w", err)
+		}
 	}
+	instanceID := *inst.InstanceId
+	name := instance.Name(inst)
 
-	instanceID := args[0]
-	inst, err := instance.Get(instanceID)
-	if err != nil {
-		log.Fatalf("fetch instance err: %s", err)
-	}
+	PrintSummary(cmd.OutOrStdout(), inst)
 
-	tags := make(map[string]string)
-	for _, t := range inst.Tags {
-		tags[*t.Key] = *t.Value
+	input := &ec2.TerminateInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
 	}
-	name := tags["Name"]
 
-	fmt.Printf("name     : %s\n", name)
-	fmt.Printf("id       : %s\n", *inst.InstanceId)
-	fmt.Printf("type     : %s\n", *inst.InstanceType)
-	fmt.Printf("az       : %s\n", *inst.Placement.AvailabilityZone)
-	fmt.Printf("state    : %s\n", *inst.State.Name)
+	if console.DryRun {
+		console.PrintDryRun("TerminateInstances", input)
+		return nil
+	}
 
-	ok := console.Confirm(fmt.Sprintf("Are you sure you want to terminate %s %s? [yN]?", color.New(color.FgRed).Sprint(instanceID), name))
-	if !ok {
-		log.Fatalln("Aborting")
+	if err := Confirm(ctx, "terminate", []*ec2.Instance{inst}); err != nil {
+		return err
 	}
 
-	// give a few seconds to change your mind
-	time.Sleep(3 * time.Second)
+	svc := client.EC2(config.Session())
+	_, err = svc.TerminateInstancesWithContext(ctx, input)
 
-	svc := ec2.New(config.Session())
-	_, err = svc.TerminateInstances(&ec2.TerminateInstancesInput{
-		InstanceIds: aws.StringSlice([]string{instanceID}),
+	if err != nil {
+		return fmt.Errorf("Terminate instance err: %w", err)
+	}
+
+	audit.Record(audit.Entry{
+		Action:   "ec2 terminate",
+		Resource: instanceID,
+		OldValue: fmt.Sprintf("%s (%s)", name, *inst.State.Name),
+		NewValue: "terminated",
 	})
 
-	if err != nil {
-		log.Fatalf("Terminate instance err: %s", err)
+	return nil
+}
+
+// PrintSummary prints the details of inst shown before asking to change
+// it.
+func PrintSummary(w io.Writer, inst *ec2.Instance) {
+	fmt.Fprintf(w, "name     : %s\n", instance.Name(inst))
+	fmt.Fprintf(w, "id       : %s\n", *inst.InstanceId)
+	fmt.Fprintf(w, "type     : %s\n", *inst.InstanceType)
+	fmt.Fprintf(w, "az       : %s\n", *inst.Placement.AvailabilityZone)
+	fmt.Fprintf(w, "state    : %s\n", *inst.State.Name)
+}
+
+// Confirm asks whether to verb instances, then gives a few seconds to
+// change your mind. It returns errs.ErrAborted if the answer is no.
+func Confirm(ctx context.Context, verb string, instances []*ec2.Instance) error {
+	names := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		names = append(names, strings.TrimSpace(fmt.Sprintf("%s %s", color.New(color.FgRed).Sprint(*inst.InstanceId), instance.Name(inst))))
 	}
+
+	ok := console.Confirm(ctx, fmt.Sprintf("Are you sure you want to %s %s? [yN]?", verb, strings.Join(names, ", ")))
+	if !ok {
+		return errs.ErrAborted
+	}
+
+	// give a few seconds to change your mind
+	return console.GracePeriod(ctx)
 }
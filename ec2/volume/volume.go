@@ -1,23 +1,17 @@
 package volume
 
 import (
-	"encoding/csv"
-	"encoding/json"
-	"fmt"
-	"log"
-	"os"
-	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/psanford/aws-buddy/regions"
 	"github.com/spf13/cobra"
 )
 
-var (
-	jsonOutput bool
-	csvOutput  bool
-)
-
 func Command() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "volume",
@@ -34,51 +28,60 @@ func volumeListCommand() *cobra.Command {
 		Use:     "list",
 		Aliases: []string{"ls"},
 		Short:   "list volumes",
-		Run:     volumeListAction,
+		RunE:    volumeListAction,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
-	cmd.Flags().BoolVarP(&csvOutput, "csv", "", false, "Output as csv")
+	regions.AddFlags(&cmd)
 
 	return &cmd
 }
 
-func volumeListAction(cmd *cobra.Command, args []string) {
-	ec2Svc := ec2.New(config.Session())
-
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+type volumeRow struct {
+	Region     string    `json:"region" output:"hidden"`
+	ID         string    `json:"id"`
+	Encryption string    `json:"encryption"`
+	Instances  []string  `json:"instances"`
+	SizeGB     int64     `json:"size_gb" output:"hidden"`
+	Type       string    `json:"type" output:"hidden"`
+	State      string    `json:"state" output:"hidden"`
+	AZ         string    `json:"az" output:"hidden"`
+	CreateTime time.Time `json:"create_time" output:"hidden"`
+}
 
-	csvOut := csv.NewWriter(os.Stdout)
+func volumeListAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := output.New(cmd.OutOrStdout())
 
-	err := ec2Svc.DescribeVolumesPages(&ec2.DescribeVolumesInput{}, func(dvo *ec2.DescribeVolumesOutput, b bool) bool {
-		for _, vol := range dvo.Volumes {
-			if jsonOutput {
-				jsonOut.Encode(vol)
-				continue
+	listErr := regions.Each(ctx, out, func(sess *session.Session, region string, out *output.Printer) error {
+		ec2Svc := client.EC2(sess)
+		return ec2Svc.DescribeVolumesPagesWithContext(ctx, &ec2.DescribeVolumesInput{}, func(dvo *ec2.DescribeVolumesOutput, b bool) bool {
+			for _, vol := range dvo.Volumes {
+				var instances []string
+				for _, attach := range vol.Attachments {
+					instances = append(instances, *attach.InstanceId)
+				}
+				enc := "unencrypted"
+				if vol.Encrypted != nil && *vol.Encrypted {
+					enc = "encrypted"
+				}
+				out.AddDetail(volumeRow{
+					Region:     region,
+					ID:         *vol.VolumeId,
+					Encryption: enc,
+					Instances:  instances,
+					SizeGB:     aws.Int64Value(vol.Size),
+					Type:       aws.StringValue(vol.VolumeType),
+					State:      aws.StringValue(vol.State),
+					AZ:         aws.StringValue(vol.AvailabilityZone),
+					CreateTime: aws.TimeValue(vol.CreateTime),
+				}, regions.Detail(region, vol))
 			}
-
-			var instances []string
-			for _, attach := range vol.Attachments {
-				instances = append(instances, *attach.InstanceId)
-			}
-			enc := "unencrypted"
-			if vol.Encrypted != nil && *vol.Encrypted {
-				enc = "encrypted"
-			}
-			if csvOutput {
-				csvOut.Write([]string{*vol.VolumeId, enc, strings.Join(instances, ",")})
-			} else {
-				fmt.Printf("%20.20s %3s %s\n", *vol.VolumeId, enc, strings.Join(instances, ","))
-			}
-		}
-		return true
+			return true
+		})
 	})
-	if err != nil {
-		log.Fatal(err)
-	}
 
-	if csvOutput {
-		csvOut.Flush()
+	if err := out.Flush(); err != nil {
+		return err
 	}
+	return listErr
 }
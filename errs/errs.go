@@ -0,0 +1,171 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Exit codes returned by aws-buddy. Scripts (and org each) can use these to
+// tell the different failure modes apart.
+const (
+	ExitOK             = 0
+	ExitError          = 1
+	ExitUsage          = 2
+	ExitNotFound       = 3
+	ExitAccessDenied   = 4
+	ExitThrottled      = 5
+	ExitAborted        = 6
+	ExitPartialFailure = 7
+)
+
+var (
+	ErrUsage    = errors.New("usage")
+	ErrNotFound = errors.New("not found")
+	ErrAborted  = errors.New("aborted")
+)
+
+// PartialFailureError is returned when some, but not necessarily all,
+// of the items a command operated on failed.
+type PartialFailureError struct {
+	Errors []error
+}
+
+func (e *PartialFailureError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d error(s):\n  %s", len(e.Errors), strings.Join(msgs, "\n  "))
+}
+
+// PartialFailure returns a *PartialFailureError for errors, or nil if
+// there are none.
+func PartialFailure(errors []error) error {
+	if len(errors) == 0 {
+		return nil
+	}
+	return &PartialFailureError{Errors: errors}
+}
+
+// ExitStatusError is an error that carries an explicit exit code,
+// e.g. from a subprocess.
+type ExitStatusError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitStatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitStatusError) Unwrap() error {
+	return e.Err
+}
+
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *ExitStatusError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	var partial *PartialFailureError
+	switch {
+	case IsCanceled(err):
+		return ExitAborted
+	case errors.As(err, &partial):
+		return ExitPartialFailure
+	case errors.Is(err, ErrAborted):
+		return ExitAborted
+	case errors.Is(err, ErrUsage):
+		return ExitUsage
+	case errors.Is(err, ErrNotFound):
+		return ExitNotFound
+	}
+
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return ExitError
+	}
+
+	if request.IsErrorThrottle(awsErr) {
+		return ExitThrottled
+	}
+
+	code := awsErr.Code()
+	switch {
+	case strings.Contains(code, "NotFound"), strings.HasPrefix(code, "NoSuch"), strings.Contains(code, "NonExistent"):
+		return ExitNotFound
+	case strings.Contains(code, "AccessDenied"), strings.Contains(code, "Unauthorized"), code == "AuthorizationError":
+		return ExitAccessDenied
+	}
+
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		switch reqErr.StatusCode() {
+		case http.StatusNotFound:
+			return ExitNotFound
+		case http.StatusForbidden:
+			return ExitAccessDenied
+		case http.StatusTooManyRequests:
+			return ExitThrottled
+		}
+	}
+
+	return ExitError
+}
+
+// IsCanceled reports whether err is the result of the command's context
+// being canceled, e.g. by ctrl-c. A partial failure is canceled if every
+// one of its errors is.
+func IsCanceled(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return true
+	}
+
+	var partial *PartialFailureError
+	if errors.As(err, &partial) {
+		for _, err := range partial.Errors {
+			if !IsCanceled(err) {
+				return false
+			}
+		}
+		return len(partial.Errors) > 0
+	}
+
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == request.CanceledErrorCode
+}
+
+// CodeName returns a short description of an exit code.
+func CodeName(code int) string {
+	switch code {
+	case ExitOK:
+		return "ok"
+	case ExitUsage:
+		return "usage error"
+	case ExitNotFound:
+		return "not found"
+	case ExitAccessDenied:
+		return "access denied"
+	case ExitThrottled:
+		return "throttled"
+	case ExitAborted:
+		return "aborted"
+	case ExitPartialFailure:
+		return "partial failure"
+	}
+	return "error"
+}
@@ -0,0 +1,646 @@
+package fake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// The WithContext variants fail like the SDK does if ctx is already done,
+// and otherwise ignore it; fake calls return immediately.
+
+func canceled(err error) error {
+	return awserr.New(request.CanceledErrorCode, "request context canceled", err)
+}
+
+func (f *EC2) DescribeInstancesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeInstances(input)
+}
+
+func (f *EC2) DescribeInstancesPagesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.DescribeInstancesPages(input, fn)
+}
+
+func (f *EC2) RunInstancesWithContext(ctx aws.Context, input *ec2.RunInstancesInput, opts ...request.Option) (*ec2.Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.RunInstances(input)
+}
+
+func (f *EC2) TerminateInstancesWithContext(ctx aws.Context, input *ec2.TerminateInstancesInput, opts ...request.Option) (*ec2.TerminateInstancesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.TerminateInstances(input)
+}
+
+func (f *EC2) StartInstancesWithContext(ctx aws.Context, input *ec2.StartInstancesInput, opts ...request.Option) (*ec2.StartInstancesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.StartInstances(input)
+}
+
+func (f *EC2) StopInstancesWithContext(ctx aws.Context, input *ec2.StopInstancesInput, opts ...request.Option) (*ec2.StopInstancesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.StopInstances(input)
+}
+
+func (f *EC2) RebootInstancesWithContext(ctx aws.Context, input *ec2.RebootInstancesInput, opts ...request.Option) (*ec2.RebootInstancesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.RebootInstances(input)
+}
+
+func (f *EC2) WaitUntilInstanceRunningWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.WaiterOption) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.WaitUntilInstanceRunning(input)
+}
+
+func (f *EC2) WaitUntilInstanceStoppedWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.WaiterOption) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.WaitUntilInstanceStopped(input)
+}
+
+func (f *EC2) WaitUntilInstanceStatusOkWithContext(ctx aws.Context, input *ec2.DescribeInstanceStatusInput, opts ...request.WaiterOption) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.WaitUntilInstanceStatusOk(input)
+}
+
+func (f *EC2) ModifyInstanceAttributeWithContext(ctx aws.Context, input *ec2.ModifyInstanceAttributeInput, opts ...request.Option) (*ec2.ModifyInstanceAttributeOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ModifyInstanceAttribute(input)
+}
+
+func (f *EC2) DescribeInstanceTypesWithContext(ctx aws.Context, input *ec2.DescribeInstanceTypesInput, opts ...request.Option) (*ec2.DescribeInstanceTypesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeInstanceTypes(input)
+}
+
+func (f *EC2) DescribeInstanceTypeOfferingsWithContext(ctx aws.Context, input *ec2.DescribeInstanceTypeOfferingsInput, opts ...request.Option) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeInstanceTypeOfferings(input)
+}
+
+func (f *EC2) DescribeImagesWithContext(ctx aws.Context, input *ec2.DescribeImagesInput, opts ...request.Option) (*ec2.DescribeImagesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeImages(input)
+}
+
+func (f *EC2) CreateTagsWithContext(ctx aws.Context, input *ec2.CreateTagsInput, opts ...request.Option) (*ec2.CreateTagsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.CreateTags(input)
+}
+
+func (f *EC2) DeleteTagsWithContext(ctx aws.Context, input *ec2.DeleteTagsInput, opts ...request.Option) (*ec2.DeleteTagsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DeleteTags(input)
+}
+
+func (f *EC2) GetConsoleOutputWithContext(ctx aws.Context, input *ec2.GetConsoleOutputInput, opts ...request.Option) (*ec2.GetConsoleOutputOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetConsoleOutput(input)
+}
+
+func (f *EC2) DescribeSecurityGroupsWithContext(ctx aws.Context, input *ec2.DescribeSecurityGroupsInput, opts ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeSecurityGroups(input)
+}
+
+func (f *EC2) DescribeSecurityGroupsPagesWithContext(ctx aws.Context, input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.DescribeSecurityGroupsPages(input, fn)
+}
+
+func (f *EC2) DescribeVolumesPagesWithContext(ctx aws.Context, input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.DescribeVolumesPages(input, fn)
+}
+
+func (f *EC2) DescribeNetworkInterfacesPagesWithContext(ctx aws.Context, input *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.DescribeNetworkInterfacesPages(input, fn)
+}
+
+func (f *EC2) DescribeSubnetsPagesWithContext(ctx aws.Context, input *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.DescribeSubnetsPages(input, fn)
+}
+
+func (f *EC2) DescribeKeyPairsWithContext(ctx aws.Context, input *ec2.DescribeKeyPairsInput, opts ...request.Option) (*ec2.DescribeKeyPairsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeKeyPairs(input)
+}
+
+func (f *EC2) DescribeRegionsWithContext(ctx aws.Context, input *ec2.DescribeRegionsInput, opts ...request.Option) (*ec2.DescribeRegionsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeRegions(input)
+}
+
+func (f *IAM) ListAccountAliasesWithContext(ctx aws.Context, input *iam.ListAccountAliasesInput, opts ...request.Option) (*iam.ListAccountAliasesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListAccountAliases(input)
+}
+
+func (f *IAM) ListUsersWithContext(ctx aws.Context, input *iam.ListUsersInput, opts ...request.Option) (*iam.ListUsersOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListUsers(input)
+}
+
+func (f *IAM) ListUsersPagesWithContext(ctx aws.Context, input *iam.ListUsersInput, fn func(*iam.ListUsersOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListUsersPages(input, fn)
+}
+
+func (f *IAM) GetUserWithContext(ctx aws.Context, input *iam.GetUserInput, opts ...request.Option) (*iam.GetUserOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetUser(input)
+}
+
+func (f *IAM) GetLoginProfileWithContext(ctx aws.Context, input *iam.GetLoginProfileInput, opts ...request.Option) (*iam.GetLoginProfileOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetLoginProfile(input)
+}
+
+func (f *IAM) ListMFADevicesWithContext(ctx aws.Context, input *iam.ListMFADevicesInput, opts ...request.Option) (*iam.ListMFADevicesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListMFADevices(input)
+}
+
+func (f *IAM) ListAccessKeysWithContext(ctx aws.Context, input *iam.ListAccessKeysInput, opts ...request.Option) (*iam.ListAccessKeysOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListAccessKeys(input)
+}
+
+func (f *IAM) ListUserPoliciesPagesWithContext(ctx aws.Context, input *iam.ListUserPoliciesInput, fn func(*iam.ListUserPoliciesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListUserPoliciesPages(input, fn)
+}
+
+func (f *IAM) GetUserPolicyWithContext(ctx aws.Context, input *iam.GetUserPolicyInput, opts ...request.Option) (*iam.GetUserPolicyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetUserPolicy(input)
+}
+
+func (f *IAM) ListAttachedUserPoliciesPagesWithContext(ctx aws.Context, input *iam.ListAttachedUserPoliciesInput, fn func(*iam.ListAttachedUserPoliciesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListAttachedUserPoliciesPages(input, fn)
+}
+
+func (f *IAM) ListGroupsForUserPagesWithContext(ctx aws.Context, input *iam.ListGroupsForUserInput, fn func(*iam.ListGroupsForUserOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListGroupsForUserPages(input, fn)
+}
+
+func (f *IAM) ListGroupPoliciesPagesWithContext(ctx aws.Context, input *iam.ListGroupPoliciesInput, fn func(*iam.ListGroupPoliciesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListGroupPoliciesPages(input, fn)
+}
+
+func (f *IAM) GetGroupPolicyWithContext(ctx aws.Context, input *iam.GetGroupPolicyInput, opts ...request.Option) (*iam.GetGroupPolicyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetGroupPolicy(input)
+}
+
+func (f *IAM) ListAttachedGroupPoliciesPagesWithContext(ctx aws.Context, input *iam.ListAttachedGroupPoliciesInput, fn func(*iam.ListAttachedGroupPoliciesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListAttachedGroupPoliciesPages(input, fn)
+}
+
+func (f *IAM) GetPolicyWithContext(ctx aws.Context, input *iam.GetPolicyInput, opts ...request.Option) (*iam.GetPolicyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetPolicy(input)
+}
+
+func (f *IAM) GetPolicyVersionWithContext(ctx aws.Context, input *iam.GetPolicyVersionInput, opts ...request.Option) (*iam.GetPolicyVersionOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetPolicyVersion(input)
+}
+
+func (f *IAM) GetAccountAuthorizationDetailsPagesWithContext(ctx aws.Context, input *iam.GetAccountAuthorizationDetailsInput, fn func(*iam.GetAccountAuthorizationDetailsOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.GetAccountAuthorizationDetailsPages(input, fn)
+}
+
+func (f *IAM) SimulateCustomPolicyWithContext(ctx aws.Context, input *iam.SimulateCustomPolicyInput, opts ...request.Option) (*iam.SimulatePolicyResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.SimulateCustomPolicy(input)
+}
+
+func (f *IAM) SimulatePrincipalPolicyWithContext(ctx aws.Context, input *iam.SimulatePrincipalPolicyInput, opts ...request.Option) (*iam.SimulatePolicyResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.SimulatePrincipalPolicy(input)
+}
+
+func (f *Organizations) ListAccountsWithContext(ctx aws.Context, input *organizations.ListAccountsInput, opts ...request.Option) (*organizations.ListAccountsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListAccounts(input)
+}
+
+func (f *Organizations) ListAccountsPagesWithContext(ctx aws.Context, input *organizations.ListAccountsInput, fn func(*organizations.ListAccountsOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListAccountsPages(input, fn)
+}
+
+func (f *Organizations) ListRootsWithContext(ctx aws.Context, input *organizations.ListRootsInput, opts ...request.Option) (*organizations.ListRootsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListRoots(input)
+}
+
+func (f *Organizations) ListRootsPagesWithContext(ctx aws.Context, input *organizations.ListRootsInput, fn func(*organizations.ListRootsOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListRootsPages(input, fn)
+}
+
+func (f *Organizations) ListAccountsForParentWithContext(ctx aws.Context, input *organizations.ListAccountsForParentInput, opts ...request.Option) (*organizations.ListAccountsForParentOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListAccountsForParent(input)
+}
+
+func (f *Organizations) ListAccountsForParentPagesWithContext(ctx aws.Context, input *organizations.ListAccountsForParentInput, fn func(*organizations.ListAccountsForParentOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListAccountsForParentPages(input, fn)
+}
+
+func (f *Organizations) ListOrganizationalUnitsForParentWithContext(ctx aws.Context, input *organizations.ListOrganizationalUnitsForParentInput, opts ...request.Option) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListOrganizationalUnitsForParent(input)
+}
+
+func (f *Organizations) ListOrganizationalUnitsForParentPagesWithContext(ctx aws.Context, input *organizations.ListOrganizationalUnitsForParentInput, fn func(*organizations.ListOrganizationalUnitsForParentOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListOrganizationalUnitsForParentPages(input, fn)
+}
+
+func (f *Organizations) ListPoliciesWithContext(ctx aws.Context, input *organizations.ListPoliciesInput, opts ...request.Option) (*organizations.ListPoliciesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListPolicies(input)
+}
+
+func (f *Organizations) ListPoliciesPagesWithContext(ctx aws.Context, input *organizations.ListPoliciesInput, fn func(*organizations.ListPoliciesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListPoliciesPages(input, fn)
+}
+
+func (f *Organizations) DescribePolicyWithContext(ctx aws.Context, input *organizations.DescribePolicyInput, opts ...request.Option) (*organizations.DescribePolicyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribePolicy(input)
+}
+
+func (f *Organizations) DescribeOrganizationWithContext(ctx aws.Context, input *organizations.DescribeOrganizationInput, opts ...request.Option) (*organizations.DescribeOrganizationOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeOrganization(input)
+}
+
+func (f *Organizations) DescribeAccountWithContext(ctx aws.Context, input *organizations.DescribeAccountInput, opts ...request.Option) (*organizations.DescribeAccountOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeAccount(input)
+}
+
+func (f *Organizations) DescribeOrganizationalUnitWithContext(ctx aws.Context, input *organizations.DescribeOrganizationalUnitInput, opts ...request.Option) (*organizations.DescribeOrganizationalUnitOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeOrganizationalUnit(input)
+}
+
+func (f *Organizations) ListParentsWithContext(ctx aws.Context, input *organizations.ListParentsInput, opts ...request.Option) (*organizations.ListParentsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListParents(input)
+}
+
+func (f *Route53) ListHostedZonesWithContext(ctx aws.Context, input *route53.ListHostedZonesInput, opts ...request.Option) (*route53.ListHostedZonesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListHostedZones(input)
+}
+
+func (f *Route53) ListHostedZonesPagesWithContext(ctx aws.Context, input *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListHostedZonesPages(input, fn)
+}
+
+func (f *Route53) ListResourceRecordSetsWithContext(ctx aws.Context, input *route53.ListResourceRecordSetsInput, opts ...request.Option) (*route53.ListResourceRecordSetsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListResourceRecordSets(input)
+}
+
+func (f *Route53) ListResourceRecordSetsPagesWithContext(ctx aws.Context, input *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListResourceRecordSetsPages(input, fn)
+}
+
+func (f *S3) ListBucketsWithContext(ctx aws.Context, input *s3.ListBucketsInput, opts ...request.Option) (*s3.ListBucketsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListBuckets(input)
+}
+
+func (f *S3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetObject(input)
+}
+
+func (f *S3) HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.HeadObject(input)
+}
+
+func (f *S3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.PutObject(input)
+}
+
+func (f *S3) ListObjectsV2WithContext(ctx aws.Context, input *s3.ListObjectsV2Input, opts ...request.Option) (*s3.ListObjectsV2Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListObjectsV2(input)
+}
+
+func (f *S3) ListObjectsV2PagesWithContext(ctx aws.Context, input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListObjectsV2Pages(input, fn)
+}
+
+func (f *SQS) ListQueuesWithContext(ctx aws.Context, input *sqs.ListQueuesInput, opts ...request.Option) (*sqs.ListQueuesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListQueues(input)
+}
+
+func (f *SQS) ListQueuesPagesWithContext(ctx aws.Context, input *sqs.ListQueuesInput, fn func(*sqs.ListQueuesOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListQueuesPages(input, fn)
+}
+
+func (f *SQS) GetQueueAttributesWithContext(ctx aws.Context, input *sqs.GetQueueAttributesInput, opts ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetQueueAttributes(input)
+}
+
+func (f *SQS) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ReceiveMessage(input)
+}
+
+func (f *SQS) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DeleteMessage(input)
+}
+
+func (f *SSM) StartSessionWithContext(ctx aws.Context, input *ssm.StartSessionInput, opts ...request.Option) (*ssm.StartSessionOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.StartSession(input)
+}
+
+func (f *SSM) SendCommandWithContext(ctx aws.Context, input *ssm.SendCommandInput, opts ...request.Option) (*ssm.SendCommandOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.SendCommand(input)
+}
+
+func (f *SSM) GetCommandInvocationWithContext(ctx aws.Context, input *ssm.GetCommandInvocationInput, opts ...request.Option) (*ssm.GetCommandInvocationOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetCommandInvocation(input)
+}
+
+func (f *SSM) ListCommandInvocationsWithContext(ctx aws.Context, input *ssm.ListCommandInvocationsInput, opts ...request.Option) (*ssm.ListCommandInvocationsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.ListCommandInvocations(input)
+}
+
+func (f *SSM) ListCommandInvocationsPagesWithContext(ctx aws.Context, input *ssm.ListCommandInvocationsInput, fn func(*ssm.ListCommandInvocationsOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.ListCommandInvocationsPages(input, fn)
+}
+
+func (f *SSM) CancelCommandWithContext(ctx aws.Context, input *ssm.CancelCommandInput, opts ...request.Option) (*ssm.CancelCommandOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.CancelCommand(input)
+}
+
+func (f *SSM) DescribeInstanceInformationWithContext(ctx aws.Context, input *ssm.DescribeInstanceInformationInput, opts ...request.Option) (*ssm.DescribeInstanceInformationOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeInstanceInformation(input)
+}
+
+func (f *SSM) DescribeInstanceInformationPagesWithContext(ctx aws.Context, input *ssm.DescribeInstanceInformationInput, fn func(*ssm.DescribeInstanceInformationOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.DescribeInstanceInformationPages(input, fn)
+}
+
+func (f *SSM) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetParameter(input)
+}
+
+func (f *SSM) PutParameterWithContext(ctx aws.Context, input *ssm.PutParameterInput, opts ...request.Option) (*ssm.PutParameterOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.PutParameter(input)
+}
+
+func (f *SSM) DeleteParameterWithContext(ctx aws.Context, input *ssm.DeleteParameterInput, opts ...request.Option) (*ssm.DeleteParameterOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DeleteParameter(input)
+}
+
+func (f *SSM) DescribeParametersWithContext(ctx aws.Context, input *ssm.DescribeParametersInput, opts ...request.Option) (*ssm.DescribeParametersOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.DescribeParameters(input)
+}
+
+func (f *SSM) DescribeParametersPagesWithContext(ctx aws.Context, input *ssm.DescribeParametersInput, fn func(*ssm.DescribeParametersOutput, bool) bool, opts ...request.Option) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(err)
+	}
+	return f.DescribeParametersPages(input, fn)
+}
+
+func (f *STS) GetCallerIdentityWithContext(ctx aws.Context, input *sts.GetCallerIdentityInput, opts ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetCallerIdentity(input)
+}
+
+func (f *STS) AssumeRoleWithContext(ctx aws.Context, input *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.AssumeRole(input)
+}
+
+func (f *Pricing) GetProductsWithContext(ctx aws.Context, input *pricing.GetProductsInput, opts ...request.Option) (*pricing.GetProductsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(err)
+	}
+	return f.GetProducts(input)
+}
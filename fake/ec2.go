@@ -0,0 +1,617 @@
+package fake
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// EC2 is an in-memory ec2iface.EC2API.
+type EC2 struct {
+	ec2iface.EC2API
+	store
+
+	Instances         []*ec2.Instance
+	SecurityGroups    []*ec2.SecurityGroup
+	Volumes           []*ec2.Volume
+	NetworkInterfaces []*ec2.NetworkInterface
+	Subnets           []*ec2.Subnet
+	KeyPairs          []*ec2.KeyPairInfo
+	Images            []*ec2.Image
+	Regions           []string
+
+	// ConsoleOutput maps instance id to its (plain text) console output.
+	ConsoleOutput map[string]string
+
+	// InstanceTypeOfferings maps availability zone to the instance types
+	// offered there. Every type offered anywhere is a valid type.
+	InstanceTypeOfferings map[string][]string
+
+	// InstanceTypeArchitectures maps instance type to its supported
+	// architectures. Types not listed are x86_64.
+	InstanceTypeArchitectures map[string][]string
+
+	// InsufficientCapacity lists instance types that fail to start.
+	InsufficientCapacity []string
+
+	nextID int
+}
+
+// Instance returns a running t3.micro instance with the given id and Name
+// tag, suitable for seeding an EC2 fake.
+func Instance(id, name string) *ec2.Instance {
+	inst := &ec2.Instance{
+		InstanceId:     aws.String(id),
+		InstanceType:   aws.String("t3.micro"),
+		ImageId:        aws.String("ami-00000000"),
+		Architecture:   aws.String(ec2.ArchitectureValuesX8664),
+		RootDeviceType: aws.String(ec2.DeviceTypeEbs),
+		LaunchTime:     aws.Time(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Placement:      &ec2.Placement{AvailabilityZone: aws.String("us-east-1a")},
+		State: &ec2.InstanceState{
+			Code: aws.Int64(16),
+			Name: aws.String(ec2.InstanceStateNameRunning),
+		},
+	}
+	if name != "" {
+		inst.Tags = []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(name)}}
+	}
+	return inst
+}
+
+func (f *EC2) AddInstance(inst *ec2.Instance) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Instances = append(f.Instances, inst)
+}
+
+func (f *EC2) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeInstances")
+
+	ids := aws.StringValueSlice(input.InstanceIds)
+	var res ec2.Reservation
+	for _, inst := range f.Instances {
+		if len(ids) > 0 && !contains(ids, *inst.InstanceId) {
+			continue
+		}
+		ok, err := matchFilters(input.Filters, func(name string) []string {
+			return instanceAttr(inst, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res.Instances = append(res.Instances, inst)
+		}
+	}
+
+	out := &ec2.DescribeInstancesOutput{}
+	if len(res.Instances) > 0 {
+		out.Reservations = []*ec2.Reservation{&res}
+	}
+	return out, nil
+}
+
+func (f *EC2) DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	out, err := f.DescribeInstances(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func instanceAttr(inst *ec2.Instance, name string) []string {
+	if strings.HasPrefix(name, "tag:") {
+		return tagValues(inst.Tags, strings.TrimPrefix(name, "tag:"))
+	}
+	switch name {
+	case "instance-id":
+		return []string{aws.StringValue(inst.InstanceId)}
+	case "instance-state-name":
+		return []string{aws.StringValue(inst.State.Name)}
+	case "instance-type":
+		return []string{aws.StringValue(inst.InstanceType)}
+	case "private-ip-address":
+		return []string{aws.StringValue(inst.PrivateIpAddress)}
+	case "ip-address":
+		return []string{aws.StringValue(inst.PublicIpAddress)}
+	case "availability-zone":
+		return []string{aws.StringValue(inst.Placement.AvailabilityZone)}
+	case "vpc-id":
+		return []string{aws.StringValue(inst.VpcId)}
+	case "subnet-id":
+		return []string{aws.StringValue(inst.SubnetId)}
+	case "image-id":
+		return []string{aws.StringValue(inst.ImageId)}
+	case "key-name":
+		return []string{aws.StringValue(inst.KeyName)}
+	case "network-interface.addresses.private-ip-address":
+		ips := []string{}
+		for _, iface := range inst.NetworkInterfaces {
+			for _, addr := range iface.PrivateIpAddresses {
+				ips = append(ips, aws.StringValue(addr.PrivateIpAddress))
+			}
+		}
+		return ips
+	case "network-interface.addresses.association.public-ip":
+		ips := []string{}
+		for _, iface := range inst.NetworkInterfaces {
+			for _, addr := range iface.PrivateIpAddresses {
+				if addr.Association != nil {
+					ips = append(ips, aws.StringValue(addr.Association.PublicIp))
+				}
+			}
+		}
+		return ips
+	}
+	return nil
+}
+
+func (f *EC2) findInstance(id string) *ec2.Instance {
+	for _, inst := range f.Instances {
+		if *inst.InstanceId == id {
+			return inst
+		}
+	}
+	return nil
+}
+
+func (f *EC2) RunInstances(input *ec2.RunInstancesInput) (*ec2.Reservation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("RunInstances")
+
+	f.nextID++
+	inst := Instance(fmt.Sprintf("i-%017x", f.nextID), "")
+	inst.InstanceType = input.InstanceType
+	inst.ImageId = input.ImageId
+	inst.KeyName = input.KeyName
+	inst.SubnetId = input.SubnetId
+	inst.State = &ec2.InstanceState{
+		Code: aws.Int64(0),
+		Name: aws.String(ec2.InstanceStateNamePending),
+	}
+	for _, id := range input.SecurityGroupIds {
+		inst.SecurityGroups = append(inst.SecurityGroups, &ec2.GroupIdentifier{GroupId: id})
+	}
+	for _, spec := range input.TagSpecifications {
+		if aws.StringValue(spec.ResourceType) == ec2.ResourceTypeInstance {
+			inst.Tags = append(inst.Tags, spec.Tags...)
+		}
+	}
+	f.Instances = append(f.Instances, inst)
+
+	return &ec2.Reservation{Instances: []*ec2.Instance{inst}}, nil
+}
+
+func (f *EC2) TerminateInstances(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("TerminateInstances")
+
+	out := &ec2.TerminateInstancesOutput{}
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		inst := f.findInstance(id)
+		if inst == nil {
+			return nil, apiError("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+		}
+		prev := inst.State
+		inst.State = &ec2.InstanceState{
+			Code: aws.Int64(48),
+			Name: aws.String(ec2.InstanceStateNameTerminated),
+		}
+		out.TerminatingInstances = append(out.TerminatingInstances, &ec2.InstanceStateChange{
+			InstanceId:    inst.InstanceId,
+			PreviousState: prev,
+			CurrentState:  inst.State,
+		})
+	}
+	return out, nil
+}
+
+func (f *EC2) StartInstances(input *ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("StartInstances")
+
+	out := &ec2.StartInstancesOutput{}
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		if inst := f.findInstance(id); inst != nil && contains(f.InsufficientCapacity, *inst.InstanceType) {
+			return nil, apiError("InsufficientInstanceCapacity", "We currently do not have sufficient %s capacity", *inst.InstanceType)
+		}
+		change, err := f.setState(id, 16, ec2.InstanceStateNameRunning)
+		if err != nil {
+			return nil, err
+		}
+		out.StartingInstances = append(out.StartingInstances, change)
+	}
+	return out, nil
+}
+
+func (f *EC2) StopInstances(input *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("StopInstances")
+
+	out := &ec2.StopInstancesOutput{}
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		change, err := f.setState(id, 80, ec2.InstanceStateNameStopped)
+		if err != nil {
+			return nil, err
+		}
+		out.StoppingInstances = append(out.StoppingInstances, change)
+	}
+	return out, nil
+}
+
+func (f *EC2) RebootInstances(input *ec2.RebootInstancesInput) (*ec2.RebootInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("RebootInstances")
+
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		if f.findInstance(id) == nil {
+			return nil, apiError("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+		}
+	}
+	return &ec2.RebootInstancesOutput{}, nil
+}
+
+func (f *EC2) ModifyInstanceAttribute(input *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ModifyInstanceAttribute")
+
+	id := aws.StringValue(input.InstanceId)
+	inst := f.findInstance(id)
+	if inst == nil {
+		return nil, apiError("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+	}
+	if input.InstanceType != nil {
+		if aws.StringValue(inst.State.Name) != ec2.InstanceStateNameStopped {
+			return nil, apiError("IncorrectInstanceState", "The instance '%s' is not in the 'stopped' state.", id)
+		}
+		inst.InstanceType = input.InstanceType.Value
+	}
+	return &ec2.ModifyInstanceAttributeOutput{}, nil
+}
+
+// setState moves an instance straight to its new state; the fake has no
+// pending or stopping states.
+func (f *EC2) setState(id string, code int64, name string) (*ec2.InstanceStateChange, error) {
+	inst := f.findInstance(id)
+	if inst == nil {
+		return nil, apiError("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+	}
+	prev := inst.State
+	inst.State = &ec2.InstanceState{
+		Code: aws.Int64(code),
+		Name: aws.String(name),
+	}
+	return &ec2.InstanceStateChange{
+		InstanceId:    inst.InstanceId,
+		PreviousState: prev,
+		CurrentState:  inst.State,
+	}, nil
+}
+
+func (f *EC2) WaitUntilInstanceRunning(input *ec2.DescribeInstancesInput) error {
+	return f.waitForState("InstanceRunning", aws.StringValueSlice(input.InstanceIds), ec2.InstanceStateNameRunning)
+}
+
+func (f *EC2) WaitUntilInstanceStopped(input *ec2.DescribeInstancesInput) error {
+	return f.waitForState("InstanceStopped", aws.StringValueSlice(input.InstanceIds), ec2.InstanceStateNameStopped)
+}
+
+// WaitUntilInstanceStatusOk treats every running instance as passing its
+// status checks.
+func (f *EC2) WaitUntilInstanceStatusOk(input *ec2.DescribeInstanceStatusInput) error {
+	return f.waitForState("InstanceStatusOk", aws.StringValueSlice(input.InstanceIds), ec2.InstanceStateNameRunning)
+}
+
+// waitForState returns immediately, failing like an exhausted waiter if
+// any of the instances isn't in state.
+func (f *EC2) waitForState(waiter string, ids []string, state string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("WaitUntil" + waiter)
+
+	for _, id := range ids {
+		inst := f.findInstance(id)
+		if inst == nil || aws.StringValue(inst.State.Name) != state {
+			return awserr.New(request.WaiterResourceNotReadyErrorCode, "exceeded wait attempts", nil)
+		}
+	}
+	return nil
+}
+
+func (f *EC2) CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("CreateTags")
+
+	for _, id := range aws.StringValueSlice(input.Resources) {
+		inst := f.findInstance(id)
+		if inst == nil {
+			return nil, apiError("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+		}
+		for _, tag := range input.Tags {
+			inst.Tags = setTag(inst.Tags, *tag.Key, aws.StringValue(tag.Value))
+		}
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func (f *EC2) DeleteTags(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DeleteTags")
+
+	for _, id := range aws.StringValueSlice(input.Resources) {
+		inst := f.findInstance(id)
+		if inst == nil {
+			return nil, apiError("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+		}
+		for _, tag := range input.Tags {
+			kept := inst.Tags[:0]
+			for _, t := range inst.Tags {
+				if *t.Key != *tag.Key {
+					kept = append(kept, t)
+				}
+			}
+			inst.Tags = kept
+		}
+	}
+	return &ec2.DeleteTagsOutput{}, nil
+}
+
+func (f *EC2) GetConsoleOutput(input *ec2.GetConsoleOutputInput) (*ec2.GetConsoleOutputOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetConsoleOutput")
+
+	id := aws.StringValue(input.InstanceId)
+	if f.findInstance(id) == nil {
+		return nil, apiError("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+	}
+	out := &ec2.GetConsoleOutputOutput{InstanceId: input.InstanceId}
+	if text, ok := f.ConsoleOutput[id]; ok {
+		out.Output = aws.String(base64Encode(text))
+		out.Timestamp = aws.Time(time.Now())
+	}
+	return out, nil
+}
+
+func (f *EC2) DescribeSecurityGroups(input *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeSecurityGroups")
+
+	if input == nil {
+		input = &ec2.DescribeSecurityGroupsInput{}
+	}
+	ids := aws.StringValueSlice(input.GroupIds)
+	out := &ec2.DescribeSecurityGroupsOutput{}
+	for _, sg := range f.SecurityGroups {
+		if len(ids) > 0 && !contains(ids, *sg.GroupId) {
+			continue
+		}
+		ok, err := matchFilters(input.Filters, func(name string) []string {
+			if strings.HasPrefix(name, "tag:") {
+				return tagValues(sg.Tags, strings.TrimPrefix(name, "tag:"))
+			}
+			switch name {
+			case "group-id":
+				return []string{aws.StringValue(sg.GroupId)}
+			case "group-name":
+				return []string{aws.StringValue(sg.GroupName)}
+			case "vpc-id":
+				return []string{aws.StringValue(sg.VpcId)}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.SecurityGroups = append(out.SecurityGroups, sg)
+		}
+	}
+	return out, nil
+}
+
+func (f *EC2) DescribeSecurityGroupsPages(input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) error {
+	out, err := f.DescribeSecurityGroups(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *EC2) DescribeVolumesPages(input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("DescribeVolumes")
+	out := &ec2.DescribeVolumesOutput{Volumes: f.Volumes}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *EC2) DescribeNetworkInterfacesPages(input *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("DescribeNetworkInterfaces")
+	var ids []string
+	if input != nil {
+		ids = aws.StringValueSlice(input.NetworkInterfaceIds)
+	}
+	out := &ec2.DescribeNetworkInterfacesOutput{}
+	for _, ni := range f.NetworkInterfaces {
+		if len(ids) == 0 || contains(ids, *ni.NetworkInterfaceId) {
+			out.NetworkInterfaces = append(out.NetworkInterfaces, ni)
+		}
+	}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *EC2) DescribeSubnetsPages(input *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("DescribeSubnets")
+	out := &ec2.DescribeSubnetsOutput{Subnets: f.Subnets}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *EC2) DescribeKeyPairs(input *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeKeyPairs")
+	return &ec2.DescribeKeyPairsOutput{KeyPairs: f.KeyPairs}, nil
+}
+
+func (f *EC2) DescribeRegions(input *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeRegions")
+
+	out := &ec2.DescribeRegionsOutput{}
+	for _, r := range f.Regions {
+		out.Regions = append(out.Regions, &ec2.Region{RegionName: aws.String(r)})
+	}
+	return out, nil
+}
+
+func (f *EC2) DescribeImages(input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeImages")
+
+	ids := aws.StringValueSlice(input.ImageIds)
+	out := &ec2.DescribeImagesOutput{}
+	for _, img := range f.Images {
+		if len(ids) == 0 || contains(ids, aws.StringValue(img.ImageId)) {
+			out.Images = append(out.Images, img)
+		}
+	}
+	return out, nil
+}
+
+func (f *EC2) DescribeInstanceTypes(input *ec2.DescribeInstanceTypesInput) (*ec2.DescribeInstanceTypesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeInstanceTypes")
+
+	out := &ec2.DescribeInstanceTypesOutput{}
+	for _, t := range aws.StringValueSlice(input.InstanceTypes) {
+		var known bool
+		for _, types := range f.InstanceTypeOfferings {
+			known = known || contains(types, t)
+		}
+		if !known {
+			return nil, apiError("InvalidInstanceType", "The following supplied instance types do not exist: [%s]", t)
+		}
+		archs, ok := f.InstanceTypeArchitectures[t]
+		if !ok {
+			archs = []string{ec2.ArchitectureTypeX8664}
+		}
+		out.InstanceTypes = append(out.InstanceTypes, &ec2.InstanceTypeInfo{
+			InstanceType:  aws.String(t),
+			ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice(archs)},
+		})
+	}
+	return out, nil
+}
+
+func (f *EC2) DescribeInstanceTypeOfferings(input *ec2.DescribeInstanceTypeOfferingsInput) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeInstanceTypeOfferings")
+
+	out := &ec2.DescribeInstanceTypeOfferingsOutput{}
+	for az, types := range f.InstanceTypeOfferings {
+		for _, t := range types {
+			ok, err := matchFilters(input.Filters, func(name string) []string {
+				switch name {
+				case "location":
+					return []string{az}
+				case "instance-type":
+					return []string{t}
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out.InstanceTypeOfferings = append(out.InstanceTypeOfferings, &ec2.InstanceTypeOffering{
+					InstanceType: aws.String(t),
+					Location:     aws.String(az),
+					LocationType: input.LocationType,
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+// matchFilters reports whether a resource matches every filter, using
+// attr to look up the resource's values for a filter name. Values may use
+// the * and ? wildcards like the real API. Unknown filter names are an
+// error so tests don't silently match everything.
+func matchFilters(filters []*ec2.Filter, attr func(name string) []string) (bool, error) {
+	for _, filter := range filters {
+		name := aws.StringValue(filter.Name)
+		have := attr(name)
+		if have == nil && !strings.HasPrefix(name, "tag:") {
+			return false, fmt.Errorf("fake: unsupported filter %q", name)
+		}
+
+		var ok bool
+		for _, want := range aws.StringValueSlice(filter.Values) {
+			for _, v := range have {
+				if v != "" && wildcardMatch(want, v) {
+					ok = true
+				}
+			}
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func tagValues(tags []*ec2.Tag, key string) []string {
+	var vals []string
+	for _, t := range tags {
+		if aws.StringValue(t.Key) == key {
+			vals = append(vals, aws.StringValue(t.Value))
+		}
+	}
+	return vals
+}
+
+func setTag(tags []*ec2.Tag, key, value string) []*ec2.Tag {
+	for _, t := range tags {
+		if *t.Key == key {
+			t.Value = aws.String(value)
+			return tags
+		}
+	}
+	return append(tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+}
@@ -0,0 +1,152 @@
+// Package fake provides in-memory AWS service clients so command logic
+// (formatting, filtering, confirm flows) can be exercised without AWS.
+//
+// Each fake embeds its service's aws-sdk-go *iface interface and
+// implements the calls aws-buddy makes against a simple in-memory store.
+// Calling anything else panics on the nil embedded interface, which makes
+// a missing fake method obvious.
+//
+// A typical test seeds a Backend, installs it and runs the root command:
+//
+//	b := fake.NewBackend()
+//	b.EC2.AddInstance(fake.Instance("i-0123456789abcdef0", "web-1"))
+//	defer b.Install()()
+//
+//	root := cmd.RootCommand()
+//	root.SetArgs([]string{"ec2", "list"})
+//	root.SetOut(&buf)
+//	err := root.Execute()
+package fake
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/organizations/organizationsiface"
+	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/psanford/aws-buddy/client"
+)
+
+// Backend holds one fake per service.
+type Backend struct {
+	EC2           *EC2
+	IAM           *IAM
+	SSM           *SSM
+	SQS           *SQS
+	S3            *S3
+	Route53       *Route53
+	Organizations *Organizations
+	Pricing       *Pricing
+	STS           *STS
+}
+
+func NewBackend() *Backend {
+	return &Backend{
+		EC2:           &EC2{},
+		IAM:           &IAM{},
+		SSM:           &SSM{},
+		SQS:           &SQS{},
+		S3:            &S3{},
+		Route53:       &Route53{},
+		Organizations: &Organizations{},
+		Pricing:       &Pricing{},
+		STS: &STS{
+			Account: "123456789012",
+			Arn:     "arn:aws:iam::123456789012:user/fake",
+		},
+	}
+}
+
+// Install makes the client package return b's fakes for every session
+// and returns a function that restores the previous constructors.
+func (b *Backend) Install() (restore func()) {
+	var (
+		oldEC2           = client.EC2
+		oldIAM           = client.IAM
+		oldSSM           = client.SSM
+		oldSQS           = client.SQS
+		oldS3            = client.S3
+		oldRoute53       = client.Route53
+		oldOrganizations = client.Organizations
+		oldPricing       = client.Pricing
+		oldSTS           = client.STS
+	)
+
+	client.EC2 = func(*session.Session) ec2iface.EC2API { return b.EC2 }
+	client.IAM = func(*session.Session) iamiface.IAMAPI { return b.IAM }
+	client.SSM = func(*session.Session) ssmiface.SSMAPI { return b.SSM }
+	client.SQS = func(*session.Session) sqsiface.SQSAPI { return b.SQS }
+	client.S3 = func(*session.Session) s3iface.S3API { return b.S3 }
+	client.Route53 = func(*session.Session) route53iface.Route53API { return b.Route53 }
+	client.Organizations = func(*session.Session) organizationsiface.OrganizationsAPI { return b.Organizations }
+	client.Pricing = func(*session.Session) pricingiface.PricingAPI { return b.Pricing }
+	client.STS = func(*session.Session) stsiface.STSAPI { return b.STS }
+
+	return func() {
+		client.EC2 = oldEC2
+		client.IAM = oldIAM
+		client.SSM = oldSSM
+		client.SQS = oldSQS
+		client.S3 = oldS3
+		client.Route53 = oldRoute53
+		client.Organizations = oldOrganizations
+		client.Pricing = oldPricing
+		client.STS = oldSTS
+	}
+}
+
+// store is embedded in each fake to guard its data and record calls.
+type store struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (s *store) call(op string) {
+	s.calls = append(s.calls, op)
+}
+
+// Calls returns the names of the operations called so far, in order.
+func (s *store) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func apiError(code, format string, args ...interface{}) error {
+	return awserr.NewRequestFailure(awserr.New(code, fmt.Sprintf(format, args...), nil), 400, "fake")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch matches s against pattern, where * matches any run of
+// characters and ? any single character.
+func wildcardMatch(pattern, s string) bool {
+	re := regexp.QuoteMeta(pattern)
+	re = strings.ReplaceAll(re, `\*`, ".*")
+	re = strings.ReplaceAll(re, `\?`, ".")
+	ok, _ := regexp.MatchString(`\A`+re+`\z`, s)
+	return ok
+}
+
+func base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
@@ -0,0 +1,303 @@
+package fake
+
+import (
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+)
+
+// IAM is an in-memory iamiface.IAMAPI. Maps are keyed by user, group or
+// policy name except where noted. Policy documents are stored as plain
+// json and url encoded on the way out like the real API.
+type IAM struct {
+	iamiface.IAMAPI
+	store
+
+	AccountAliases []string
+
+	Users         []*iam.User
+	LoginProfiles map[string]*iam.LoginProfile
+	MFADevices    map[string][]*iam.MFADevice
+	AccessKeys    map[string][]*iam.AccessKeyMetadata
+
+	// UserPolicies and GroupPolicies map a user or group to its inline
+	// policy documents by policy name.
+	UserPolicies  map[string]map[string]string
+	GroupPolicies map[string]map[string]string
+
+	AttachedUserPolicies  map[string][]*iam.AttachedPolicy
+	AttachedGroupPolicies map[string][]*iam.AttachedPolicy
+
+	// UserGroups maps a user to the groups it belongs to.
+	UserGroups map[string][]*iam.Group
+
+	// Policies and PolicyDocuments are keyed by policy arn. The document is
+	// returned for the policy's default version.
+	Policies        map[string]*iam.Policy
+	PolicyDocuments map[string]string
+
+	AuthorizationDetails *iam.GetAccountAuthorizationDetailsOutput
+
+	// Decisions maps an action name to the EvalDecision returned by the
+	// Simulate calls. Unlisted actions are implicitDeny.
+	Decisions map[string]string
+}
+
+// AddUser adds a user named name and returns it.
+func (f *IAM) AddUser(name string) *iam.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u := &iam.User{
+		UserName:   aws.String(name),
+		UserId:     aws.String("AIDA" + name),
+		Arn:        aws.String("arn:aws:iam::123456789012:user/" + name),
+		Path:       aws.String("/"),
+		CreateDate: aws.Time(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	f.Users = append(f.Users, u)
+	return u
+}
+
+func noSuchEntity(kind, name string) error {
+	return apiError(iam.ErrCodeNoSuchEntityException, "The %s with name %s cannot be found.", kind, name)
+}
+
+func (f *IAM) user(name *string) (*iam.User, error) {
+	for _, u := range f.Users {
+		if *u.UserName == aws.StringValue(name) {
+			return u, nil
+		}
+	}
+	return nil, noSuchEntity("user", aws.StringValue(name))
+}
+
+func (f *IAM) ListAccountAliases(input *iam.ListAccountAliasesInput) (*iam.ListAccountAliasesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListAccountAliases")
+	return &iam.ListAccountAliasesOutput{AccountAliases: aws.StringSlice(f.AccountAliases)}, nil
+}
+
+func (f *IAM) ListUsers(input *iam.ListUsersInput) (*iam.ListUsersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListUsers")
+	return &iam.ListUsersOutput{Users: f.Users}, nil
+}
+
+func (f *IAM) ListUsersPages(input *iam.ListUsersInput, fn func(*iam.ListUsersOutput, bool) bool) error {
+	out, err := f.ListUsers(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *IAM) GetUser(input *iam.GetUserInput) (*iam.GetUserOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetUser")
+
+	u, err := f.user(input.UserName)
+	if err != nil {
+		return nil, err
+	}
+	return &iam.GetUserOutput{User: u}, nil
+}
+
+func (f *IAM) GetLoginProfile(input *iam.GetLoginProfileInput) (*iam.GetLoginProfileOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetLoginProfile")
+
+	lp, ok := f.LoginProfiles[aws.StringValue(input.UserName)]
+	if !ok {
+		return nil, noSuchEntity("login profile", aws.StringValue(input.UserName))
+	}
+	return &iam.GetLoginProfileOutput{LoginProfile: lp}, nil
+}
+
+func (f *IAM) ListMFADevices(input *iam.ListMFADevicesInput) (*iam.ListMFADevicesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListMFADevices")
+	return &iam.ListMFADevicesOutput{MFADevices: f.MFADevices[aws.StringValue(input.UserName)]}, nil
+}
+
+func (f *IAM) ListAccessKeys(input *iam.ListAccessKeysInput) (*iam.ListAccessKeysOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListAccessKeys")
+	return &iam.ListAccessKeysOutput{AccessKeyMetadata: f.AccessKeys[aws.StringValue(input.UserName)]}, nil
+}
+
+func (f *IAM) ListUserPoliciesPages(input *iam.ListUserPoliciesInput, fn func(*iam.ListUserPoliciesOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("ListUserPolicies")
+	out := &iam.ListUserPoliciesOutput{PolicyNames: aws.StringSlice(sortedKeys(f.UserPolicies[aws.StringValue(input.UserName)]))}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *IAM) GetUserPolicy(input *iam.GetUserPolicyInput) (*iam.GetUserPolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetUserPolicy")
+
+	doc, ok := f.UserPolicies[aws.StringValue(input.UserName)][aws.StringValue(input.PolicyName)]
+	if !ok {
+		return nil, noSuchEntity("policy", aws.StringValue(input.PolicyName))
+	}
+	return &iam.GetUserPolicyOutput{
+		UserName:       input.UserName,
+		PolicyName:     input.PolicyName,
+		PolicyDocument: aws.String(url.QueryEscape(doc)),
+	}, nil
+}
+
+func (f *IAM) ListAttachedUserPoliciesPages(input *iam.ListAttachedUserPoliciesInput, fn func(*iam.ListAttachedUserPoliciesOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("ListAttachedUserPolicies")
+	out := &iam.ListAttachedUserPoliciesOutput{AttachedPolicies: f.AttachedUserPolicies[aws.StringValue(input.UserName)]}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *IAM) ListGroupsForUserPages(input *iam.ListGroupsForUserInput, fn func(*iam.ListGroupsForUserOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("ListGroupsForUser")
+	out := &iam.ListGroupsForUserOutput{Groups: f.UserGroups[aws.StringValue(input.UserName)]}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *IAM) ListGroupPoliciesPages(input *iam.ListGroupPoliciesInput, fn func(*iam.ListGroupPoliciesOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("ListGroupPolicies")
+	out := &iam.ListGroupPoliciesOutput{PolicyNames: aws.StringSlice(sortedKeys(f.GroupPolicies[aws.StringValue(input.GroupName)]))}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *IAM) GetGroupPolicy(input *iam.GetGroupPolicyInput) (*iam.GetGroupPolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetGroupPolicy")
+
+	doc, ok := f.GroupPolicies[aws.StringValue(input.GroupName)][aws.StringValue(input.PolicyName)]
+	if !ok {
+		return nil, noSuchEntity("policy", aws.StringValue(input.PolicyName))
+	}
+	return &iam.GetGroupPolicyOutput{
+		GroupName:      input.GroupName,
+		PolicyName:     input.PolicyName,
+		PolicyDocument: aws.String(url.QueryEscape(doc)),
+	}, nil
+}
+
+func (f *IAM) ListAttachedGroupPoliciesPages(input *iam.ListAttachedGroupPoliciesInput, fn func(*iam.ListAttachedGroupPoliciesOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("ListAttachedGroupPolicies")
+	out := &iam.ListAttachedGroupPoliciesOutput{AttachedPolicies: f.AttachedGroupPolicies[aws.StringValue(input.GroupName)]}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *IAM) GetPolicy(input *iam.GetPolicyInput) (*iam.GetPolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetPolicy")
+
+	p, ok := f.Policies[aws.StringValue(input.PolicyArn)]
+	if !ok {
+		return nil, noSuchEntity("policy", aws.StringValue(input.PolicyArn))
+	}
+	return &iam.GetPolicyOutput{Policy: p}, nil
+}
+
+func (f *IAM) GetPolicyVersion(input *iam.GetPolicyVersionInput) (*iam.GetPolicyVersionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetPolicyVersion")
+
+	doc, ok := f.PolicyDocuments[aws.StringValue(input.PolicyArn)]
+	if !ok {
+		return nil, noSuchEntity("policy", aws.StringValue(input.PolicyArn))
+	}
+	return &iam.GetPolicyVersionOutput{
+		PolicyVersion: &iam.PolicyVersion{
+			VersionId:        input.VersionId,
+			IsDefaultVersion: aws.Bool(true),
+			Document:         aws.String(url.QueryEscape(doc)),
+		},
+	}, nil
+}
+
+func (f *IAM) GetAccountAuthorizationDetailsPages(input *iam.GetAccountAuthorizationDetailsInput, fn func(*iam.GetAccountAuthorizationDetailsOutput, bool) bool) error {
+	f.mu.Lock()
+	f.call("GetAccountAuthorizationDetails")
+	out := f.AuthorizationDetails
+	if out == nil {
+		out = &iam.GetAccountAuthorizationDetailsOutput{}
+	}
+	f.mu.Unlock()
+
+	fn(out, true)
+	return nil
+}
+
+func (f *IAM) SimulateCustomPolicy(input *iam.SimulateCustomPolicyInput) (*iam.SimulatePolicyResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("SimulateCustomPolicy")
+	return f.simulate(input.ActionNames, input.ResourceArns), nil
+}
+
+func (f *IAM) SimulatePrincipalPolicy(input *iam.SimulatePrincipalPolicyInput) (*iam.SimulatePolicyResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("SimulatePrincipalPolicy")
+	return f.simulate(input.ActionNames, input.ResourceArns), nil
+}
+
+func (f *IAM) simulate(actions, resources []*string) *iam.SimulatePolicyResponse {
+	out := &iam.SimulatePolicyResponse{}
+	for _, action := range aws.StringValueSlice(actions) {
+		decision, ok := f.Decisions[action]
+		if !ok {
+			decision = iam.PolicyEvaluationDecisionTypeImplicitDeny
+		}
+		for _, resource := range resources {
+			out.EvaluationResults = append(out.EvaluationResults, &iam.EvaluationResult{
+				EvalActionName:   aws.String(action),
+				EvalResourceName: resource,
+				EvalDecision:     aws.String(decision),
+			})
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
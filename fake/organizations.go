@@ -0,0 +1,260 @@
+package fake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/organizations/organizationsiface"
+)
+
+// RootID is the id of the organization root in the Organizations fake.
+const RootID = "r-0000"
+
+// OrgID is the id of the organization in the Organizations fake.
+const OrgID = "o-fake"
+
+// Organizations is an in-memory organizationsiface.OrganizationsAPI with
+// a single root. Set NotInOrganization to make it behave like an account
+// outside of any organization.
+type Organizations struct {
+	organizationsiface.OrganizationsAPI
+	store
+
+	NotInOrganization bool
+
+	Accounts []*organizations.Account
+	OUs      []*organizations.OrganizationalUnit
+	Policies []*organizations.Policy
+
+	// Parents maps account and OU ids to the id of their parent OU or
+	// RootID.
+	Parents map[string]string
+}
+
+// AddAccount adds an active account under parentID.
+func (f *Organizations) AddAccount(id, name, parentID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Accounts = append(f.Accounts, &organizations.Account{
+		Id:     aws.String(id),
+		Name:   aws.String(name),
+		Arn:    aws.String("arn:aws:organizations::000000000000:account/" + OrgID + "/" + id),
+		Email:  aws.String(name + "@example.com"),
+		Status: aws.String(organizations.AccountStatusActive),
+	})
+	f.setParent(id, parentID)
+}
+
+// AddOU adds an organizational unit under parentID.
+func (f *Organizations) AddOU(id, name, parentID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.OUs = append(f.OUs, &organizations.OrganizationalUnit{
+		Id:   aws.String(id),
+		Name: aws.String(name),
+	})
+	f.setParent(id, parentID)
+}
+
+// AddSCP adds a service control policy with the given content.
+func (f *Organizations) AddSCP(id, name, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Policies = append(f.Policies, &organizations.Policy{
+		Content: aws.String(content),
+		PolicySummary: &organizations.PolicySummary{
+			Id:          aws.String(id),
+			Name:        aws.String(name),
+			Description: aws.String(""),
+			Type:        aws.String(organizations.PolicyTypeServiceControlPolicy),
+			AwsManaged:  aws.Bool(false),
+		},
+	})
+}
+
+func (f *Organizations) setParent(id, parentID string) {
+	if f.Parents == nil {
+		f.Parents = make(map[string]string)
+	}
+	f.Parents[id] = parentID
+}
+
+func (f *Organizations) ListAccounts(input *organizations.ListAccountsInput) (*organizations.ListAccountsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListAccounts")
+	return &organizations.ListAccountsOutput{Accounts: f.Accounts}, nil
+}
+
+func (f *Organizations) ListAccountsPages(input *organizations.ListAccountsInput, fn func(*organizations.ListAccountsOutput, bool) bool) error {
+	out, err := f.ListAccounts(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *Organizations) ListRoots(input *organizations.ListRootsInput) (*organizations.ListRootsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListRoots")
+	return &organizations.ListRootsOutput{
+		Roots: []*organizations.Root{{Id: aws.String(RootID), Name: aws.String("Root")}},
+	}, nil
+}
+
+func (f *Organizations) ListRootsPages(input *organizations.ListRootsInput, fn func(*organizations.ListRootsOutput, bool) bool) error {
+	out, err := f.ListRoots(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *Organizations) ListAccountsForParent(input *organizations.ListAccountsForParentInput) (*organizations.ListAccountsForParentOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListAccountsForParent")
+
+	out := &organizations.ListAccountsForParentOutput{}
+	for _, acct := range f.Accounts {
+		if f.Parents[*acct.Id] == aws.StringValue(input.ParentId) {
+			out.Accounts = append(out.Accounts, acct)
+		}
+	}
+	return out, nil
+}
+
+func (f *Organizations) ListAccountsForParentPages(input *organizations.ListAccountsForParentInput, fn func(*organizations.ListAccountsForParentOutput, bool) bool) error {
+	out, err := f.ListAccountsForParent(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *Organizations) ListOrganizationalUnitsForParent(input *organizations.ListOrganizationalUnitsForParentInput) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListOrganizationalUnitsForParent")
+
+	out := &organizations.ListOrganizationalUnitsForParentOutput{}
+	for _, ou := range f.OUs {
+		if f.Parents[*ou.Id] == aws.StringValue(input.ParentId) {
+			out.OrganizationalUnits = append(out.OrganizationalUnits, ou)
+		}
+	}
+	return out, nil
+}
+
+func (f *Organizations) ListOrganizationalUnitsForParentPages(input *organizations.ListOrganizationalUnitsForParentInput, fn func(*organizations.ListOrganizationalUnitsForParentOutput, bool) bool) error {
+	out, err := f.ListOrganizationalUnitsForParent(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *Organizations) ListPolicies(input *organizations.ListPoliciesInput) (*organizations.ListPoliciesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListPolicies")
+
+	out := &organizations.ListPoliciesOutput{}
+	for _, p := range f.Policies {
+		if aws.StringValue(p.PolicySummary.Type) == aws.StringValue(input.Filter) {
+			out.Policies = append(out.Policies, p.PolicySummary)
+		}
+	}
+	return out, nil
+}
+
+func (f *Organizations) ListPoliciesPages(input *organizations.ListPoliciesInput, fn func(*organizations.ListPoliciesOutput, bool) bool) error {
+	out, err := f.ListPolicies(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *Organizations) DescribePolicy(input *organizations.DescribePolicyInput) (*organizations.DescribePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribePolicy")
+
+	for _, p := range f.Policies {
+		if *p.PolicySummary.Id == aws.StringValue(input.PolicyId) {
+			return &organizations.DescribePolicyOutput{Policy: p}, nil
+		}
+	}
+	return nil, apiError(organizations.ErrCodePolicyNotFoundException, "policy %s not found", aws.StringValue(input.PolicyId))
+}
+
+func (f *Organizations) DescribeOrganization(input *organizations.DescribeOrganizationInput) (*organizations.DescribeOrganizationOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeOrganization")
+
+	if f.NotInOrganization {
+		return nil, apiError(organizations.ErrCodeAWSOrganizationsNotInUseException, "account is not a member of an organization")
+	}
+	return &organizations.DescribeOrganizationOutput{
+		Organization: &organizations.Organization{
+			Id:                 aws.String(OrgID),
+			Arn:                aws.String("arn:aws:organizations::000000000000:organization/" + OrgID),
+			MasterAccountId:    aws.String("000000000000"),
+			MasterAccountEmail: aws.String("root@example.com"),
+			FeatureSet:         aws.String(organizations.OrganizationFeatureSetAll),
+		},
+	}, nil
+}
+
+func (f *Organizations) DescribeAccount(input *organizations.DescribeAccountInput) (*organizations.DescribeAccountOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeAccount")
+
+	for _, acct := range f.Accounts {
+		if *acct.Id == aws.StringValue(input.AccountId) {
+			return &organizations.DescribeAccountOutput{Account: acct}, nil
+		}
+	}
+	return nil, apiError(organizations.ErrCodeAccountNotFoundException, "account %s not found", aws.StringValue(input.AccountId))
+}
+
+func (f *Organizations) DescribeOrganizationalUnit(input *organizations.DescribeOrganizationalUnitInput) (*organizations.DescribeOrganizationalUnitOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeOrganizationalUnit")
+
+	for _, ou := range f.OUs {
+		if *ou.Id == aws.StringValue(input.OrganizationalUnitId) {
+			return &organizations.DescribeOrganizationalUnitOutput{OrganizationalUnit: ou}, nil
+		}
+	}
+	return nil, apiError(organizations.ErrCodeOrganizationalUnitNotFoundException, "ou %s not found", aws.StringValue(input.OrganizationalUnitId))
+}
+
+// ListParents returns the parent recorded in Parents, or nothing for ids
+// the fake doesn't know about.
+func (f *Organizations) ListParents(input *organizations.ListParentsInput) (*organizations.ListParentsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListParents")
+
+	parentID, ok := f.Parents[aws.StringValue(input.ChildId)]
+	if !ok {
+		return &organizations.ListParentsOutput{}, nil
+	}
+	typ := organizations.ParentTypeOrganizationalUnit
+	if parentID == RootID {
+		typ = organizations.ParentTypeRoot
+	}
+	return &organizations.ListParentsOutput{
+		Parents: []*organizations.Parent{{Id: aws.String(parentID), Type: aws.String(typ)}},
+	}, nil
+}
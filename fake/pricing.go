@@ -0,0 +1,57 @@
+package fake
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+)
+
+// Pricing is an in-memory pricingiface.PricingAPI for EC2 on-demand
+// prices.
+type Pricing struct {
+	pricingiface.PricingAPI
+	store
+
+	// OnDemand maps instance type to its hourly USD price. Prices are
+	// the same in every region and for every operating system.
+	OnDemand map[string]float64
+}
+
+func (f *Pricing) GetProducts(input *pricing.GetProductsInput) (*pricing.GetProductsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetProducts")
+
+	var instanceType string
+	for _, filter := range input.Filters {
+		if aws.StringValue(filter.Field) == "instanceType" {
+			instanceType = aws.StringValue(filter.Value)
+		}
+	}
+
+	out := &pricing.GetProductsOutput{}
+	price, ok := f.OnDemand[instanceType]
+	if !ok {
+		return out, nil
+	}
+	out.PriceList = []aws.JSONValue{{
+		"product": map[string]interface{}{
+			"attributes": map[string]interface{}{"instanceType": instanceType},
+		},
+		"terms": map[string]interface{}{
+			"OnDemand": map[string]interface{}{
+				"FAKE.JRTCKXETXF": map[string]interface{}{
+					"priceDimensions": map[string]interface{}{
+						"FAKE.JRTCKXETXF.6YS6EN2CT7": map[string]interface{}{
+							"unit":         "Hrs",
+							"pricePerUnit": map[string]interface{}{"USD": fmt.Sprintf("%.10f", price)},
+						},
+					},
+				},
+			},
+		},
+	}}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package fake
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+// Route53 is an in-memory route53iface.Route53API.
+type Route53 struct {
+	route53iface.Route53API
+	store
+
+	Zones []*route53.HostedZone
+
+	// Records is keyed by hosted zone id.
+	Records map[string][]*route53.ResourceRecordSet
+}
+
+// AddZone creates a hosted zone for name (which should end in a dot) and
+// returns its id.
+func (f *Route53) AddZone(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := fmt.Sprintf("/hostedzone/Z%08d", len(f.Zones)+1)
+	f.Zones = append(f.Zones, &route53.HostedZone{
+		Id:   aws.String(id),
+		Name: aws.String(name),
+	})
+	return id
+}
+
+// AddRecord adds a record set with the given values to zoneID.
+func (f *Route53) AddRecord(zoneID, name, typ string, ttl int64, values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Records == nil {
+		f.Records = make(map[string][]*route53.ResourceRecordSet)
+	}
+	rrs := &route53.ResourceRecordSet{
+		Name: aws.String(name),
+		Type: aws.String(typ),
+		TTL:  aws.Int64(ttl),
+	}
+	for _, v := range values {
+		rrs.ResourceRecords = append(rrs.ResourceRecords, &route53.ResourceRecord{Value: aws.String(v)})
+	}
+	f.Records[zoneID] = append(f.Records[zoneID], rrs)
+}
+
+func (f *Route53) ListHostedZones(input *route53.ListHostedZonesInput) (*route53.ListHostedZonesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListHostedZones")
+
+	out := &route53.ListHostedZonesOutput{}
+	for _, z := range f.Zones {
+		cp := *z
+		cp.ResourceRecordSetCount = aws.Int64(int64(len(f.Records[*z.Id])))
+		out.HostedZones = append(out.HostedZones, &cp)
+	}
+	return out, nil
+}
+
+func (f *Route53) ListHostedZonesPages(input *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool) error {
+	out, err := f.ListHostedZones(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *Route53) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListResourceRecordSets")
+
+	id := aws.StringValue(input.HostedZoneId)
+	for _, z := range f.Zones {
+		if *z.Id == id {
+			return &route53.ListResourceRecordSetsOutput{ResourceRecordSets: f.Records[id]}, nil
+		}
+	}
+	return nil, apiError(route53.ErrCodeNoSuchHostedZone, "no hosted zone %s", id)
+}
+
+func (f *Route53) ListResourceRecordSetsPages(input *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool) error {
+	out, err := f.ListResourceRecordSets(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
@@ -0,0 +1,194 @@
+package fake
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3 is an in-memory s3iface.S3API.
+type S3 struct {
+	s3iface.S3API
+	store
+
+	// Buckets maps bucket name to its objects, keyed by object key.
+	Buckets map[string]map[string]*Object
+}
+
+type Object struct {
+	Body         []byte
+	ContentType  string
+	LastModified time.Time
+}
+
+// PutBody stores body at bucket/key, creating the bucket if needed.
+func (f *S3) PutBody(bucket, key, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.put(bucket, key, &Object{
+		Body:         []byte(body),
+		ContentType:  "text/plain",
+		LastModified: time.Now(),
+	})
+}
+
+func (f *S3) put(bucket, key string, obj *Object) {
+	if f.Buckets == nil {
+		f.Buckets = make(map[string]map[string]*Object)
+	}
+	if f.Buckets[bucket] == nil {
+		f.Buckets[bucket] = make(map[string]*Object)
+	}
+	f.Buckets[bucket][key] = obj
+}
+
+func (f *S3) object(bucket, key *string) (*Object, error) {
+	objects, ok := f.Buckets[aws.StringValue(bucket)]
+	if !ok {
+		return nil, apiError(s3.ErrCodeNoSuchBucket, "bucket %s does not exist", aws.StringValue(bucket))
+	}
+	obj, ok := objects[aws.StringValue(key)]
+	if !ok {
+		return nil, apiError(s3.ErrCodeNoSuchKey, "key %s does not exist", aws.StringValue(key))
+	}
+	return obj, nil
+}
+
+func (f *S3) ListBuckets(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListBuckets")
+
+	names := make([]string, 0, len(f.Buckets))
+	for name := range f.Buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := &s3.ListBucketsOutput{}
+	for _, name := range names {
+		out.Buckets = append(out.Buckets, &s3.Bucket{Name: aws.String(name)})
+	}
+	return out, nil
+}
+
+func (f *S3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetObject")
+
+	obj, err := f.object(input.Bucket, input.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(obj.Body)),
+		ContentLength: aws.Int64(int64(len(obj.Body))),
+		ContentType:   aws.String(obj.ContentType),
+		LastModified:  aws.Time(obj.LastModified),
+	}, nil
+}
+
+func (f *S3) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("HeadObject")
+
+	obj, err := f.object(input.Bucket, input.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(obj.Body))),
+		ContentType:   aws.String(obj.ContentType),
+		LastModified:  aws.Time(obj.LastModified),
+	}, nil
+}
+
+func (f *S3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("PutObject")
+
+	var body []byte
+	if input.Body != nil {
+		var err error
+		body, err = io.ReadAll(input.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.put(aws.StringValue(input.Bucket), aws.StringValue(input.Key), &Object{
+		Body:         body,
+		ContentType:  aws.StringValue(input.ContentType),
+		LastModified: time.Now(),
+	})
+	return &s3.PutObjectOutput{}, nil
+}
+
+// ListObjectsV2 supports Prefix and Delimiter, returning everything in a
+// single page.
+func (f *S3) ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListObjectsV2")
+
+	objects, ok := f.Buckets[aws.StringValue(input.Bucket)]
+	if !ok {
+		return nil, apiError(s3.ErrCodeNoSuchBucket, "bucket %s does not exist", aws.StringValue(input.Bucket))
+	}
+
+	prefix := aws.StringValue(input.Prefix)
+	delim := aws.StringValue(input.Delimiter)
+
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := &s3.ListObjectsV2Output{
+		Name:      input.Bucket,
+		Prefix:    input.Prefix,
+		Delimiter: input.Delimiter,
+	}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if delim != "" {
+			if i := strings.Index(key[len(prefix):], delim); i >= 0 {
+				common := key[:len(prefix)+i+len(delim)]
+				if !seen[common] {
+					seen[common] = true
+					out.CommonPrefixes = append(out.CommonPrefixes, &s3.CommonPrefix{Prefix: aws.String(common)})
+				}
+				continue
+			}
+		}
+		obj := objects[key]
+		out.Contents = append(out.Contents, &s3.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(int64(len(obj.Body))),
+			LastModified: aws.Time(obj.LastModified),
+		})
+	}
+	out.KeyCount = aws.Int64(int64(len(out.Contents) + len(out.CommonPrefixes)))
+	return out, nil
+}
+
+func (f *S3) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	out, err := f.ListObjectsV2(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
@@ -0,0 +1,163 @@
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// SQS is an in-memory sqsiface.SQSAPI.
+type SQS struct {
+	sqsiface.SQSAPI
+	store
+
+	// Queues is keyed by queue url.
+	Queues map[string]*Queue
+
+	nextID int
+}
+
+// Queue is a fake SQS queue. Messages received with a non-zero visibility
+// timeout are hidden until deleted; they never become visible again.
+type Queue struct {
+	Attributes map[string]string
+	Messages   []*sqs.Message
+
+	inFlight map[string]*sqs.Message
+}
+
+// AddQueue creates a queue named name and returns its url.
+func (f *SQS) AddQueue(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Queues == nil {
+		f.Queues = make(map[string]*Queue)
+	}
+	url := "https://sqs.us-east-1.amazonaws.com/123456789012/" + name
+	f.Queues[url] = &Queue{
+		Attributes: map[string]string{
+			"QueueArn": "arn:aws:sqs:us-east-1:123456789012:" + name,
+		},
+	}
+	return url
+}
+
+// SendBody adds a message with body to the queue at url.
+func (f *SQS) SendBody(url, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	q := f.Queues[url]
+	q.Messages = append(q.Messages, &sqs.Message{
+		MessageId:     aws.String(fmt.Sprintf("msg-%d", f.nextID)),
+		ReceiptHandle: aws.String(fmt.Sprintf("rh-%d", f.nextID)),
+		Body:          aws.String(body),
+	})
+}
+
+func (f *SQS) queue(url *string) (*Queue, error) {
+	q, ok := f.Queues[aws.StringValue(url)]
+	if !ok {
+		return nil, apiError(sqs.ErrCodeQueueDoesNotExist, "queue %s does not exist", aws.StringValue(url))
+	}
+	return q, nil
+}
+
+func (f *SQS) ListQueues(input *sqs.ListQueuesInput) (*sqs.ListQueuesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListQueues")
+
+	prefix := aws.StringValue(input.QueueNamePrefix)
+	var urls []string
+	for url := range f.Queues {
+		name := url[strings.LastIndex(url, "/")+1:]
+		if strings.HasPrefix(name, prefix) {
+			urls = append(urls, url)
+		}
+	}
+	sort.Strings(urls)
+	return &sqs.ListQueuesOutput{QueueUrls: aws.StringSlice(urls)}, nil
+}
+
+func (f *SQS) ListQueuesPages(input *sqs.ListQueuesInput, fn func(*sqs.ListQueuesOutput, bool) bool) error {
+	out, err := f.ListQueues(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *SQS) GetQueueAttributes(input *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetQueueAttributes")
+
+	q, err := f.queue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	attrs := map[string]*string{
+		sqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String(strconv.Itoa(len(q.Messages))),
+		sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String(strconv.Itoa(len(q.inFlight))),
+		sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed:    aws.String("0"),
+	}
+	for k, v := range q.Attributes {
+		attrs[k] = aws.String(v)
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: attrs}, nil
+}
+
+func (f *SQS) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ReceiveMessage")
+
+	q, err := f.queue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	max := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if max == 0 {
+		max = 1
+	}
+	if max > len(q.Messages) {
+		max = len(q.Messages)
+	}
+
+	msgs := q.Messages[:max]
+	if input.VisibilityTimeout == nil || *input.VisibilityTimeout > 0 {
+		if q.inFlight == nil {
+			q.inFlight = make(map[string]*sqs.Message)
+		}
+		for _, m := range msgs {
+			q.inFlight[*m.ReceiptHandle] = m
+		}
+		q.Messages = q.Messages[max:]
+	}
+	return &sqs.ReceiveMessageOutput{Messages: append([]*sqs.Message(nil), msgs...)}, nil
+}
+
+func (f *SQS) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DeleteMessage")
+
+	q, err := f.queue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	handle := aws.StringValue(input.ReceiptHandle)
+	if _, ok := q.inFlight[handle]; !ok {
+		return nil, apiError(sqs.ErrCodeReceiptHandleIsInvalid, "receipt handle %s is invalid", handle)
+	}
+	delete(q.inFlight, handle)
+	return &sqs.DeleteMessageOutput{}, nil
+}
@@ -0,0 +1,277 @@
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// SSM is an in-memory ssmiface.SSMAPI backed by a parameter store.
+type SSM struct {
+	ssmiface.SSMAPI
+	store
+
+	// Parameters is keyed by parameter name.
+	Parameters map[string]*ssm.Parameter
+
+	// InstanceInformation lists the managed nodes registered with SSM.
+	InstanceInformation []*ssm.InstanceInformation
+
+	// RunShellScript, if set, runs the commands of an AWS-RunShellScript
+	// SendCommand on an instance. By default they succeed with no output.
+	RunShellScript func(instanceID string, commands []string) (stdout, stderr string, exitCode int64)
+
+	// Invocations is keyed by command id then instance id.
+	Invocations map[string]map[string]*ssm.GetCommandInvocationOutput
+
+	nextCommand int
+}
+
+// PutParam stores a parameter directly, bypassing PutParameter.
+func (f *SSM) PutParam(name, typ, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.put(name, typ, value)
+}
+
+func (f *SSM) put(name, typ, value string) {
+	if f.Parameters == nil {
+		f.Parameters = make(map[string]*ssm.Parameter)
+	}
+	var version int64 = 1
+	if old, ok := f.Parameters[name]; ok {
+		version = aws.Int64Value(old.Version) + 1
+	}
+	f.Parameters[name] = &ssm.Parameter{
+		Name:             aws.String(name),
+		Type:             aws.String(typ),
+		Value:            aws.String(value),
+		Version:          aws.Int64(version),
+		LastModifiedDate: aws.Time(time.Now()),
+	}
+}
+
+func (f *SSM) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetParameter")
+
+	p, ok := f.Parameters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, apiError(ssm.ErrCodeParameterNotFound, "parameter %s not found", aws.StringValue(input.Name))
+	}
+	cp := *p
+	return &ssm.GetParameterOutput{Parameter: &cp}, nil
+}
+
+func (f *SSM) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("PutParameter")
+
+	name := aws.StringValue(input.Name)
+	old, exists := f.Parameters[name]
+	if exists && !aws.BoolValue(input.Overwrite) {
+		return nil, apiError(ssm.ErrCodeParameterAlreadyExists, "parameter %s already exists", name)
+	}
+	typ := aws.StringValue(input.Type)
+	if typ == "" && exists {
+		typ = aws.StringValue(old.Type)
+	}
+	f.put(name, typ, aws.StringValue(input.Value))
+	return &ssm.PutParameterOutput{Version: f.Parameters[name].Version}, nil
+}
+
+func (f *SSM) DeleteParameter(input *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DeleteParameter")
+
+	name := aws.StringValue(input.Name)
+	if _, ok := f.Parameters[name]; !ok {
+		return nil, apiError(ssm.ErrCodeParameterNotFound, "parameter %s not found", name)
+	}
+	delete(f.Parameters, name)
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+func (f *SSM) DescribeParameters(input *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeParameters")
+
+	names := make([]string, 0, len(f.Parameters))
+	for name := range f.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := &ssm.DescribeParametersOutput{}
+	for _, name := range names {
+		p := f.Parameters[name]
+		out.Parameters = append(out.Parameters, &ssm.ParameterMetadata{
+			Name:             p.Name,
+			Type:             p.Type,
+			Version:          p.Version,
+			LastModifiedDate: p.LastModifiedDate,
+		})
+	}
+	return out, nil
+}
+
+func (f *SSM) DescribeParametersPages(input *ssm.DescribeParametersInput, fn func(*ssm.DescribeParametersOutput, bool) bool) error {
+	out, err := f.DescribeParameters(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+// StartSession returns a session for the target. There's nothing to
+// connect to at its StreamUrl.
+func (f *SSM) StartSession(input *ssm.StartSessionInput) (*ssm.StartSessionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("StartSession")
+
+	id := "fake-" + aws.StringValue(input.Target)
+	return &ssm.StartSessionOutput{
+		SessionId:  aws.String(id),
+		StreamUrl:  aws.String("wss://ssmmessages.fake/v1/data-channel/" + id),
+		TokenValue: aws.String("fake-token"),
+	}, nil
+}
+
+// SendCommand runs the command on each instance immediately, so its
+// invocations are already finished when it returns.
+func (f *SSM) SendCommand(input *ssm.SendCommandInput) (*ssm.SendCommandOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("SendCommand")
+
+	f.nextCommand++
+	commandID := fmt.Sprintf("00000000-0000-0000-0000-%012d", f.nextCommand)
+	if f.Invocations == nil {
+		f.Invocations = make(map[string]map[string]*ssm.GetCommandInvocationOutput)
+	}
+	f.Invocations[commandID] = make(map[string]*ssm.GetCommandInvocationOutput)
+
+	commands := aws.StringValueSlice(input.Parameters["commands"])
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		var (
+			stdout, stderr string
+			code           int64
+		)
+		if f.RunShellScript != nil {
+			stdout, stderr, code = f.RunShellScript(id, commands)
+		}
+		status := ssm.CommandInvocationStatusSuccess
+		if code != 0 {
+			status = ssm.CommandInvocationStatusFailed
+		}
+		f.Invocations[commandID][id] = &ssm.GetCommandInvocationOutput{
+			CommandId:             aws.String(commandID),
+			InstanceId:            aws.String(id),
+			DocumentName:          input.DocumentName,
+			Status:                aws.String(status),
+			ResponseCode:          aws.Int64(code),
+			StandardOutputContent: aws.String(stdout),
+			StandardErrorContent:  aws.String(stderr),
+		}
+	}
+
+	return &ssm.SendCommandOutput{
+		Command: &ssm.Command{
+			CommandId:    aws.String(commandID),
+			DocumentName: input.DocumentName,
+			InstanceIds:  input.InstanceIds,
+		},
+	}, nil
+}
+
+func (f *SSM) GetCommandInvocation(input *ssm.GetCommandInvocationInput) (*ssm.GetCommandInvocationOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetCommandInvocation")
+
+	inv, ok := f.Invocations[aws.StringValue(input.CommandId)][aws.StringValue(input.InstanceId)]
+	if !ok {
+		return nil, apiError(ssm.ErrCodeInvocationDoesNotExist, "")
+	}
+	return inv, nil
+}
+
+func (f *SSM) ListCommandInvocations(input *ssm.ListCommandInvocationsInput) (*ssm.ListCommandInvocationsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ListCommandInvocations")
+
+	out := &ssm.ListCommandInvocationsOutput{}
+	for commandID, invs := range f.Invocations {
+		if input.CommandId != nil && commandID != *input.CommandId {
+			continue
+		}
+		for id, inv := range invs {
+			if input.InstanceId != nil && id != *input.InstanceId {
+				continue
+			}
+			out.CommandInvocations = append(out.CommandInvocations, &ssm.CommandInvocation{
+				CommandId:    inv.CommandId,
+				InstanceId:   inv.InstanceId,
+				DocumentName: inv.DocumentName,
+				Status:       inv.Status,
+			})
+		}
+	}
+	return out, nil
+}
+
+func (f *SSM) ListCommandInvocationsPages(input *ssm.ListCommandInvocationsInput, fn func(*ssm.ListCommandInvocationsOutput, bool) bool) error {
+	out, err := f.ListCommandInvocations(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *SSM) CancelCommand(input *ssm.CancelCommandInput) (*ssm.CancelCommandOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("CancelCommand")
+
+	invs, ok := f.Invocations[aws.StringValue(input.CommandId)]
+	if !ok {
+		return nil, apiError(ssm.ErrCodeInvalidCommandId, "")
+	}
+	for id, inv := range invs {
+		if len(input.InstanceIds) > 0 && !contains(aws.StringValueSlice(input.InstanceIds), id) {
+			continue
+		}
+		if aws.StringValue(inv.Status) == ssm.CommandInvocationStatusInProgress {
+			inv.Status = aws.String(ssm.CommandInvocationStatusCancelled)
+		}
+	}
+	return &ssm.CancelCommandOutput{}, nil
+}
+
+func (f *SSM) DescribeInstanceInformation(input *ssm.DescribeInstanceInformationInput) (*ssm.DescribeInstanceInformationOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("DescribeInstanceInformation")
+	return &ssm.DescribeInstanceInformationOutput{InstanceInformationList: f.InstanceInformation}, nil
+}
+
+func (f *SSM) DescribeInstanceInformationPages(input *ssm.DescribeInstanceInformationInput, fn func(*ssm.DescribeInstanceInformationOutput, bool) bool) error {
+	out, err := f.DescribeInstanceInformation(input)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
@@ -0,0 +1,53 @@
+package fake
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// STS is an in-memory stsiface.STSAPI. The audit log and account scoped
+// config look up the caller identity, so most tests need one.
+type STS struct {
+	stsiface.STSAPI
+	store
+
+	Account string
+	Arn     string
+
+	// AssumedRoles lists the role arns passed to AssumeRole.
+	AssumedRoles []string
+}
+
+func (f *STS) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("GetCallerIdentity")
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(f.Account),
+		Arn:     aws.String(f.Arn),
+		UserId:  aws.String("AIDAFAKE"),
+	}, nil
+}
+
+// AssumeRole returns credentials valid for an hour and records the role
+// in AssumedRoles.
+func (f *STS) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("AssumeRole")
+	f.AssumedRoles = append(f.AssumedRoles, aws.StringValue(input.RoleArn))
+	return &sts.AssumeRoleOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("ASIAFAKE"),
+			SecretAccessKey: aws.String("fake-secret"),
+			SessionToken:    aws.String("fake-token"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+		},
+		AssumedRoleUser: &sts.AssumedRoleUser{
+			Arn: aws.String(aws.StringValue(input.RoleArn) + "/" + aws.StringValue(input.RoleSessionName)),
+		},
+	}, nil
+}
@@ -5,9 +5,12 @@ go 1.22.0
 require (
 	github.com/aws/aws-sdk-go v1.42.19
 	github.com/fatih/color v1.13.0
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/mitchellh/mapstructure v1.1.2
 	github.com/psanford/ubuntuami v0.0.0-20230422234159-fc7335170830
 	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/term v0.3.0
 	golang.org/x/tools v0.4.0
 	gopkg.in/yaml.v2 v2.2.8
 )
@@ -15,11 +18,9 @@ require (
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/kr/pretty v0.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.9 // indirect
 	github.com/mattn/go-isatty v0.0.14 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.3.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 )
@@ -2,24 +2,24 @@ package iam
 
 import (
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/url"
-	"os"
 	"regexp"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
 var (
-	jsonOutput     bool
-	csvOutput      bool
 	iamUserFullArn bool
 )
 
@@ -65,37 +65,39 @@ func iamUserListCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "List users",
-		Run:   iamListUsers,
+		RunE:  iamListUsers,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
-	cmd.Flags().BoolVarP(&csvOutput, "csv", "", false, "Show csv ouput")
 	cmd.Flags().BoolVarP(&iamUserFullArn, "full-arn", "", false, "Show full arn for username")
 
 	return &cmd
 }
 
-func iamListUsers(cmd *cobra.Command, args []string) {
-	iamSvc := iam.New(config.Session())
+type userRow struct {
+	User             string `json:"user"`
+	HasPassword      bool   `json:"has_pass"`
+	PasswordCreation string `json:"pw_creation"`
+	PasswordLastUsed string `json:"pw_last_used"`
+	Keys             int    `json:"keys"`
+	ActiveKeys       int    `json:"active_keys"`
+	MFA              int    `json:"mfa"`
+}
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+func iamListUsers(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	iamSvc := client.IAM(config.Session())
 
-	csvOut := csv.NewWriter(os.Stdout)
+	out := output.New(cmd.OutOrStdout())
 
-	if !jsonOutput && !csvOutput {
-		fmt.Printf("%-20.20s %10s %10s %10s %10s %10s %10s\n", "user", "has_pass", "pw_creation", "pw_last_used", "keys", "active_keys", "mfa")
-	} else if csvOutput {
-		csvOut.Write([]string{"user", "has_pass", "pw_creation", "pw_last_used", "keys", "active_keys", "mfa"})
-	}
+	progress := console.NewProgress("users")
+	defer progress.Clear()
 
-	format := "%-20.20s %10t %10s %10s %10d %10d %10d\n"
-	if iamUserFullArn {
-		format = "%-50s %10t %10s %10s %10d %10d %10d\n"
-	}
+	var cbErr error
+	err := iamSvc.ListUsersPagesWithContext(ctx, &iam.ListUsersInput{}, func(resp *iam.ListUsersOutput, b bool) bool {
+		progress.AddTotal(len(resp.Users))
+		for _, user := range resp.Users {
+			progress.Inc(*user.UserName)
 
-	err := iamSvc.ListUsersPages(&iam.ListUsersInput{}, func(out *iam.ListUsersOutput, b bool) bool {
-		for _, user := range out.Users {
 			passwordLastUsed := "never"
 			passwordCreation := "no-pass"
 			hasPassword := false
@@ -104,129 +106,153 @@ func iamListUsers(cmd *cobra.Command, args []string) {
 				passwordLastUsed = user.PasswordLastUsed.Format("2006-01-02")
 			}
 
-			lp, err := iamSvc.GetLoginProfile(&iam.GetLoginProfileInput{
+			var loginProfile *iam.LoginProfile
+			lp, err := iamSvc.GetLoginProfileWithContext(ctx, &iam.GetLoginProfileInput{
 				UserName: user.UserName,
 			})
 
 			if awserr, ok := err.(awserr.Error); ok && awserr.Code() == iam.ErrCodeNoSuchEntityException {
 				// user does not have a password set
 			} else if err != nil {
-				log.Fatalf("GetLoginProfile err for %s: %s", *user.UserName, err)
+				cbErr = fmt.Errorf("GetLoginProfile err for %s: %w", *user.UserName, err)
+				return false
 			} else {
+				loginProfile = lp.LoginProfile
 				if lp.LoginProfile.CreateDate != nil {
 					passwordCreation = lp.LoginProfile.CreateDate.Format("2006-01-02")
 					hasPassword = true
 				}
 			}
 
-			mfaResp, err := iamSvc.ListMFADevices(&iam.ListMFADevicesInput{
+			mfaResp, err := iamSvc.ListMFADevicesWithContext(ctx, &iam.ListMFADevicesInput{
 				UserName: user.UserName,
 			})
 			if err != nil {
+				progress.Clear()
 				log.Printf("ListMFADevices err for %s: %s", *user.UserName, err)
 				continue
 			}
 
-			keysResp, err := iamSvc.ListAccessKeys(&iam.ListAccessKeysInput{
+			keysResp, err := iamSvc.ListAccessKeysWithContext(ctx, &iam.ListAccessKeysInput{
 				UserName: user.UserName,
 			})
 			if err != nil {
+				progress.Clear()
 				log.Printf("ListAccessKeys err for %s: %s", *user.UserName, err)
 				continue
 			}
 
-			if jsonOutput {
-				jsonOut.Encode(struct {
-					User         *iam.User
-					LoginProfile *iam.LoginProfile
-					ApiKeys      []*iam.AccessKeyMetadata
-					MFADevices   []*iam.MFADevice
-				}{
-					User:         user,
-					LoginProfile: lp.LoginProfile,
-					ApiKeys:      keysResp.AccessKeyMetadata,
-					MFADevices:   mfaResp.MFADevices,
-				})
-			} else {
-				liveKeys := 0
-				for _, k := range keysResp.AccessKeyMetadata {
-					if k.Status != nil && *k.Status == "Active" {
-						liveKeys++
-					}
-				}
-
-				username := *user.UserName
-				if iamUserFullArn {
-					username = *user.Arn
-				}
-				if csvOutput {
-					csvOut.Write([]string{username, fmt.Sprintf("%t", hasPassword), passwordCreation, passwordLastUsed, fmt.Sprintf("%d", len(keysResp.AccessKeyMetadata)), fmt.Sprintf("%d", liveKeys), fmt.Sprintf("%d", len(mfaResp.MFADevices))})
-					csvOut.Flush()
-				} else {
-					fmt.Printf(format, username, hasPassword, passwordCreation, passwordLastUsed, len(keysResp.AccessKeyMetadata), liveKeys, len(mfaResp.MFADevices))
+			liveKeys := 0
+			for _, k := range keysResp.AccessKeyMetadata {
+				if k.Status != nil && *k.Status == "Active" {
+					liveKeys++
 				}
 			}
+
+			username := *user.UserName
+			if iamUserFullArn {
+				username = *user.Arn
+			}
+
+			out.AddDetail(userRow{
+				User:             username,
+				HasPassword:      hasPassword,
+				PasswordCreation: passwordCreation,
+				PasswordLastUsed: passwordLastUsed,
+				Keys:             len(keysResp.AccessKeyMetadata),
+				ActiveKeys:       liveKeys,
+				MFA:              len(mfaResp.MFADevices),
+			}, struct {
+				User         *iam.User
+				LoginProfile *iam.LoginProfile
+				ApiKeys      []*iam.AccessKeyMetadata
+				MFADevices   []*iam.MFADevice
+			}{
+				User:         user,
+				LoginProfile: loginProfile,
+				ApiKeys:      keysResp.AccessKeyMetadata,
+				MFADevices:   mfaResp.MFADevices,
+			})
 		}
 
 		return true
 	})
 
 	if err != nil {
-		log.Fatalf("ListUsers err: %s", err)
+		return fmt.Errorf("ListUsers err: %w", err)
 	}
+	if cbErr != nil {
+		return cbErr
+	}
+
+	return out.Flush()
 }
 
 func iamUserShowCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "show <username>",
 		Short: "Show user",
-		Run:   iamShowUser,
+		RunE:  iamShowUser,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
-
 	return &cmd
 }
 
-func iamShowUser(cmd *cobra.Command, args []string) {
+type policyDoc struct {
+	Name     string
+	Arn      string `json:",omitempty"`
+	Document string
+}
+
+type groupDetail struct {
+	Group            *iam.Group
+	InlinePolicies   []policyDoc
+	AttachedPolicies []*iam.AttachedPolicy
+}
+
+type userDetail struct {
+	User             *iam.User
+	InlinePolicies   []policyDoc
+	AttachedPolicies []policyDoc
+	Groups           []groupDetail
+}
+
+type userShowRow struct {
+	Name             string    `json:"name"`
+	Arn              string    `json:"arn"`
+	Creation         time.Time `json:"creation"`
+	PasswordLastUsed string    `json:"pw_last_used"`
+	Groups           []string  `json:"groups"`
+}
+
+func iamShowUser(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) != 1 {
-		log.Fatalf("Missing required <username> argument")
+		return fmt.Errorf("%w: missing required <username> argument", errs.ErrUsage)
 	}
 	username := args[0]
 
-	iamSvc := iam.New(config.Session())
-
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	iamSvc := client.IAM(config.Session())
 
-	userOutput, err := iamSvc.GetUser(&iam.GetUserInput{
+	userOutput, err := iamSvc.GetUserWithContext(ctx, &iam.GetUserInput{
 		UserName: aws.String(username),
 	})
 
 	if err != nil {
-		log.Fatalf("GetUser error: %s", err)
+		return fmt.Errorf("GetUser error: %w", err)
 	}
 
-	u := userOutput.User
-
-	passwordLastUsed := "never"
-	if u.PasswordLastUsed != nil {
-		passwordLastUsed = u.PasswordLastUsed.Format("2006-01-02")
+	detail := userDetail{
+		User: userOutput.User,
 	}
 
-	fmt.Printf("========[ %s ]===================\n", *u.UserId)
-	fmt.Printf("name         : %s\n", *u.UserName)
-	fmt.Printf("arn          : %s\n", *u.Arn)
-	fmt.Printf("creation     : %s\n", u.CreateDate.Format(time.RFC3339))
-	fmt.Printf("pw last      : %s\n", passwordLastUsed)
-
 	listPolicyInput := iam.ListUserPoliciesInput{
 		UserName: aws.String(username),
 	}
-	err = iamSvc.ListUserPoliciesPages(&listPolicyInput, func(out *iam.ListUserPoliciesOutput, more bool) bool {
-		for _, pname := range out.PolicyNames {
+	err = iamSvc.ListUserPoliciesPagesWithContext(ctx, &listPolicyInput, func(resp *iam.ListUserPoliciesOutput, more bool) bool {
+		for _, pname := range resp.PolicyNames {
 
-			gotPolicy, err := iamSvc.GetUserPolicy(&iam.GetUserPolicyInput{
+			gotPolicy, err := iamSvc.GetUserPolicyWithContext(ctx, &iam.GetUserPolicyInput{
 				UserName:   aws.String(username),
 				PolicyName: pname,
 			})
@@ -235,54 +261,61 @@ func iamShowUser(cmd *cobra.Command, args []string) {
 				continue
 			}
 
-			fmt.Printf("========[ policy %s ]===================\n", *pname)
 			doc, err := url.QueryUnescape(*gotPolicy.PolicyDocument)
 			if err != nil {
 				log.Printf("Decode policy doc err: %s", err)
 				continue
 			}
 
-			fmt.Printf("%s\n", doc)
+			detail.InlinePolicies = append(detail.InlinePolicies, policyDoc{
+				Name:     *pname,
+				Document: doc,
+			})
 		}
 
 		return true
 
 	})
 	if err != nil {
-		log.Fatalf("List user policies err: %s", err)
+		return fmt.Errorf("List user policies err: %w", err)
 	}
 
 	listAttachedInput := iam.ListAttachedUserPoliciesInput{
 		UserName: aws.String(username),
 	}
 
-	fmt.Printf("========[ attached policies ]===================\n")
-	err = iamSvc.ListAttachedUserPoliciesPages(&listAttachedInput, func(laupo *iam.ListAttachedUserPoliciesOutput, b bool) bool {
+	err = iamSvc.ListAttachedUserPoliciesPagesWithContext(ctx, &listAttachedInput, func(laupo *iam.ListAttachedUserPoliciesOutput, b bool) bool {
 		for _, p := range laupo.AttachedPolicies {
-			fmt.Printf("%s : %s\n", *p.PolicyArn, *p.PolicyName)
-			policyInfo, err := iamSvc.GetPolicy(&iam.GetPolicyInput{
+			attached := policyDoc{
+				Name: *p.PolicyName,
+				Arn:  *p.PolicyArn,
+			}
+			policyInfo, err := iamSvc.GetPolicyWithContext(ctx, &iam.GetPolicyInput{
 				PolicyArn: p.PolicyArn,
 			})
 			if err != nil {
 				log.Printf("Get policy info err: %s", err)
+				detail.AttachedPolicies = append(detail.AttachedPolicies, attached)
 				continue
 			}
-			policyDoc, err := iamSvc.GetPolicyVersion(&iam.GetPolicyVersionInput{
+			policyDocResp, err := iamSvc.GetPolicyVersionWithContext(ctx, &iam.GetPolicyVersionInput{
 				PolicyArn: p.PolicyArn,
 				VersionId: policyInfo.Policy.DefaultVersionId,
 			})
 			if err != nil {
 				log.Printf("Get policy doc err: %s", err)
+				detail.AttachedPolicies = append(detail.AttachedPolicies, attached)
 				continue
 			}
-			doc, err := url.QueryUnescape(*policyDoc.PolicyVersion.Document)
+			doc, err := url.QueryUnescape(*policyDocResp.PolicyVersion.Document)
 			if err != nil {
 				log.Printf("Decode policy doc err: %s", err)
+				detail.AttachedPolicies = append(detail.AttachedPolicies, attached)
 				continue
 			}
 
-			fmt.Printf("========[ policy %s ]===================\n", *p.PolicyArn)
-			fmt.Printf("%s\n", doc)
+			attached.Document = doc
+			detail.AttachedPolicies = append(detail.AttachedPolicies, attached)
 		}
 		return true
 	})
@@ -293,18 +326,18 @@ func iamShowUser(cmd *cobra.Command, args []string) {
 	listGroupsInput := iam.ListGroupsForUserInput{
 		UserName: aws.String(username),
 	}
-	err = iamSvc.ListGroupsForUserPages(&listGroupsInput, func(groups *iam.ListGroupsForUserOutput, more bool) bool {
+	err = iamSvc.ListGroupsForUserPagesWithContext(ctx, &listGroupsInput, func(groups *iam.ListGroupsForUserOutput, more bool) bool {
 		for _, g := range groups.Groups {
-			fmt.Printf("========[ group %s ]===================\n", *g.GroupId)
-			fmt.Printf("name         : %s\n", *g.GroupName)
-			fmt.Printf("arn          : %s\n", *g.Arn)
+			group := groupDetail{
+				Group: g,
+			}
 
 			listGroupPoliciesInput := iam.ListGroupPoliciesInput{
 				GroupName: g.GroupName,
 			}
-			err = iamSvc.ListGroupPoliciesPages(&listGroupPoliciesInput, func(p *iam.ListGroupPoliciesOutput, more bool) bool {
+			err = iamSvc.ListGroupPoliciesPagesWithContext(ctx, &listGroupPoliciesInput, func(p *iam.ListGroupPoliciesOutput, more bool) bool {
 				for _, pname := range p.PolicyNames {
-					gotPolicy, err := iamSvc.GetGroupPolicy(&iam.GetGroupPolicyInput{
+					gotPolicy, err := iamSvc.GetGroupPolicyWithContext(ctx, &iam.GetGroupPolicyInput{
 						GroupName:  g.GroupName,
 						PolicyName: pname,
 					})
@@ -314,8 +347,10 @@ func iamShowUser(cmd *cobra.Command, args []string) {
 						continue
 					}
 
-					fmt.Printf("========[ group-policy %s ]===================\n", *pname)
-					fmt.Printf("%s\n", *gotPolicy.PolicyDocument)
+					group.InlinePolicies = append(group.InlinePolicies, policyDoc{
+						Name:     *pname,
+						Document: *gotPolicy.PolicyDocument,
+					})
 				}
 
 				return true
@@ -327,61 +362,142 @@ func iamShowUser(cmd *cobra.Command, args []string) {
 			listAttachedGroup := iam.ListAttachedGroupPoliciesInput{
 				GroupName: g.GroupName,
 			}
-			fmt.Printf("========[ attached group policies ]===================\n")
-			iamSvc.ListAttachedGroupPoliciesPages(&listAttachedGroup, func(lagpo *iam.ListAttachedGroupPoliciesOutput, b bool) bool {
-				for _, p := range lagpo.AttachedPolicies {
-					fmt.Printf("%s : %s\n", *p.PolicyArn, *p.PolicyName)
-				}
-
+			err = iamSvc.ListAttachedGroupPoliciesPagesWithContext(ctx, &listAttachedGroup, func(lagpo *iam.ListAttachedGroupPoliciesOutput, b bool) bool {
+				group.AttachedPolicies = append(group.AttachedPolicies, lagpo.AttachedPolicies...)
 				return true
 			})
 			if err != nil {
 				log.Printf("List attched group policies err: %s", err)
 			}
 
+			detail.Groups = append(detail.Groups, group)
 		}
 
 		return true
 	})
 	if err != nil {
-		log.Fatalf("List user groups err: %s", err)
+		return fmt.Errorf("List user groups err: %w", err)
+	}
+
+	u := detail.User
+
+	passwordLastUsed := "never"
+	if u.PasswordLastUsed != nil {
+		passwordLastUsed = u.PasswordLastUsed.Format("2006-01-02")
+	}
+
+	w := cmd.OutOrStdout()
+	out := output.New(w)
+	if !output.IsTable(out.Default) {
+		row := userShowRow{
+			Name:             *u.UserName,
+			Arn:              *u.Arn,
+			Creation:         aws.TimeValue(u.CreateDate),
+			PasswordLastUsed: passwordLastUsed,
+		}
+		for _, g := range detail.Groups {
+			row.Groups = append(row.Groups, *g.Group.GroupName)
+		}
+		out.AddDetail(row, detail)
+		return out.Flush()
+	}
+
+	fmt.Fprintf(w, "========[ %s ]===================\n", *u.UserId)
+	fmt.Fprintf(w, "name         : %s\n", *u.UserName)
+	fmt.Fprintf(w, "arn          : %s\n", *u.Arn)
+	fmt.Fprintf(w, "creation     : %s\n", u.CreateDate.Format(time.RFC3339))
+	fmt.Fprintf(w, "pw last      : %s\n", passwordLastUsed)
+
+	for _, p := range detail.InlinePolicies {
+		fmt.Fprintf(w, "========[ policy %s ]===================\n", p.Name)
+		fmt.Fprintf(w, "%s\n", p.Document)
 	}
+
+	fmt.Fprintf(w, "========[ attached policies ]===================\n")
+	for _, p := range detail.AttachedPolicies {
+		fmt.Fprintf(w, "%s : %s\n", p.Arn, p.Name)
+		if p.Document == "" {
+			continue
+		}
+		fmt.Fprintf(w, "========[ policy %s ]===================\n", p.Arn)
+		fmt.Fprintf(w, "%s\n", p.Document)
+	}
+
+	for _, g := range detail.Groups {
+		fmt.Fprintf(w, "========[ group %s ]===================\n", *g.Group.GroupId)
+		fmt.Fprintf(w, "name         : %s\n", *g.Group.GroupName)
+		fmt.Fprintf(w, "arn          : %s\n", *g.Group.Arn)
+
+		for _, p := range g.InlinePolicies {
+			fmt.Fprintf(w, "========[ group-policy %s ]===================\n", p.Name)
+			fmt.Fprintf(w, "%s\n", p.Document)
+		}
+
+		fmt.Fprintf(w, "========[ attached group policies ]===================\n")
+		for _, p := range g.AttachedPolicies {
+			fmt.Fprintf(w, "%s : %s\n", *p.PolicyArn, *p.PolicyName)
+		}
+	}
+
+	return nil
 }
 
 func listAccessKeysCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list-access-keys",
 		Short: "List all access keys in account",
-		Run:   listAccessKeysAction,
+		RunE:  listAccessKeysAction,
 	}
 
 	return &cmd
 }
 
-func listAccessKeysAction(cmd *cobra.Command, args []string) {
-	iamSvc := iam.New(config.Session())
+func listAccessKeysAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	iamSvc := client.IAM(config.Session())
 
-	fmt.Printf("%20s %8s %30s %s\n", "key_id", "status", "creation", "user_arn")
+	out := output.New(cmd.OutOrStdout())
 
-	err := iamSvc.ListUsersPages(&iam.ListUsersInput{}, func(out *iam.ListUsersOutput, b bool) bool {
-		for _, user := range out.Users {
-			keysResp, err := iamSvc.ListAccessKeys(&iam.ListAccessKeysInput{
+	progress := console.NewProgress("users")
+	defer progress.Clear()
+
+	err := iamSvc.ListUsersPagesWithContext(ctx, &iam.ListUsersInput{}, func(resp *iam.ListUsersOutput, b bool) bool {
+		progress.AddTotal(len(resp.Users))
+		for _, user := range resp.Users {
+			progress.Inc(*user.UserName)
+
+			keysResp, err := iamSvc.ListAccessKeysWithContext(ctx, &iam.ListAccessKeysInput{
 				UserName: user.UserName,
 			})
 			if err != nil {
+				progress.Clear()
 				log.Printf("ListAccessKeys err for %s: %s", *user.UserName, err)
 				continue
 			}
 
 			for _, k := range keysResp.AccessKeyMetadata {
-				fmt.Printf("%20s %8s %30s %s\n", *k.AccessKeyId, *k.Status, k.CreateDate, *user.Arn)
+				out.AddDetail(accessKeyRow{
+					KeyID:    *k.AccessKeyId,
+					Status:   *k.Status,
+					Creation: aws.TimeValue(k.CreateDate),
+					UserArn:  *user.Arn,
+				}, k)
 			}
 		}
 		return true
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+
+	return out.Flush()
+}
+
+type accessKeyRow struct {
+	KeyID    string    `json:"key_id"`
+	Status   string    `json:"status"`
+	Creation time.Time `json:"creation"`
+	UserArn  string    `json:"user_arn"`
 }
 
 var (
@@ -392,7 +508,7 @@ func iamGetAccountAuthorizationDetailsCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "account-authorization-details",
 		Short: "Get snapshot of account permissions",
-		Run:   iamGetAccountAuthorizationDetailsAction,
+		RunE:  iamGetAccountAuthorizationDetailsAction,
 	}
 
 	cmd.Flags().StringVarP(&filterPolicyMatch, "filter-by-policy-match", "", "", "Regex string to match on policy documents")
@@ -400,35 +516,44 @@ func iamGetAccountAuthorizationDetailsCommand() *cobra.Command {
 	return &cmd
 }
 
-func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string) {
-	iamSvc := iam.New(config.Session())
+func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	iamSvc := client.IAM(config.Session())
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	out := output.New(cmd.OutOrStdout())
+	out.Default = output.JSON
 
 	type IamObject struct {
 		Type   string      `json:"type"`
 		Detail interface{} `json:"detail"`
 	}
 
+	type iamObjectRow struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+		Arn  string `json:"arn"`
+	}
+
 	var filterMatch *regexp.Regexp
 	if filterPolicyMatch != "" {
 		re, err := regexp.Compile("(?i)" + filterPolicyMatch)
 		if err != nil {
-			log.Fatalf("-filter-by-policy-match doesn't compile as a Go regex: %s", err)
+			return fmt.Errorf("%w: -filter-by-policy-match doesn't compile as a Go regex: %s", errs.ErrUsage, err)
 		}
 		filterMatch = re
 	}
 
+	var cbErr error
 	input := &iam.GetAccountAuthorizationDetailsInput{}
-	err := iamSvc.GetAccountAuthorizationDetailsPages(input, func(details *iam.GetAccountAuthorizationDetailsOutput, b bool) bool {
+	err := iamSvc.GetAccountAuthorizationDetailsPagesWithContext(ctx, input, func(details *iam.GetAccountAuthorizationDetailsOutput, b bool) bool {
 		for _, g := range details.GroupDetailList {
 			include := filterMatch == nil
 			for _, pol := range g.GroupPolicyList {
 				if pol.PolicyDocument != nil {
 					doc, err := url.QueryUnescape(*pol.PolicyDocument)
 					if err != nil {
-						log.Fatalf("Unescape group policy doc err doc=%q err=%s", *pol.PolicyDocument, err)
+						cbErr = fmt.Errorf("Unescape group policy doc err doc=%q err=%s", *pol.PolicyDocument, err)
+						return false
 					}
 					pol.PolicyDocument = &doc
 					if include == false && filterMatch != nil {
@@ -439,7 +564,11 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 				}
 			}
 			if include {
-				jsonOut.Encode(IamObject{
+				out.AddDetail(iamObjectRow{
+					Type: "group",
+					Name: aws.StringValue(g.GroupName),
+					Arn:  aws.StringValue(g.Arn),
+				}, IamObject{
 					Type:   "group",
 					Detail: g,
 				})
@@ -451,7 +580,8 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 				if pol.Document != nil {
 					doc, err := url.QueryUnescape(*pol.Document)
 					if err != nil {
-						log.Fatalf("Unescape policy doc err doc=%q err=%s", *pol.Document, err)
+						cbErr = fmt.Errorf("Unescape policy doc err doc=%q err=%s", *pol.Document, err)
+						return false
 					}
 					pol.Document = &doc
 
@@ -463,7 +593,11 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 				}
 			}
 			if include {
-				jsonOut.Encode(IamObject{
+				out.AddDetail(iamObjectRow{
+					Type: "policy",
+					Name: aws.StringValue(m.PolicyName),
+					Arn:  aws.StringValue(m.Arn),
+				}, IamObject{
 					Type:   "policy",
 					Detail: m,
 				})
@@ -474,7 +608,8 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 			if r.AssumeRolePolicyDocument != nil {
 				doc, err := url.QueryUnescape(*r.AssumeRolePolicyDocument)
 				if err != nil {
-					log.Fatalf("Unescape assume role policy doc err doc=%q err=%s", *r.AssumeRolePolicyDocument, err)
+					cbErr = fmt.Errorf("Unescape assume role policy doc err doc=%q err=%s", *r.AssumeRolePolicyDocument, err)
+					return false
 				}
 				r.AssumeRolePolicyDocument = &doc
 				if include == false && filterMatch != nil {
@@ -488,7 +623,8 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 					if ipr.AssumeRolePolicyDocument != nil {
 						doc, err := url.QueryUnescape(*ipr.AssumeRolePolicyDocument)
 						if err != nil {
-							log.Fatalf("Unescape assume role policy doc err doc=%q err=%s", *ipr.AssumeRolePolicyDocument, err)
+							cbErr = fmt.Errorf("Unescape assume role policy doc err doc=%q err=%s", *ipr.AssumeRolePolicyDocument, err)
+							return false
 						}
 						ipr.AssumeRolePolicyDocument = &doc
 						if include == false && filterMatch != nil {
@@ -503,7 +639,8 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 				if pol.PolicyDocument != nil {
 					doc, err := url.QueryUnescape(*pol.PolicyDocument)
 					if err != nil {
-						log.Fatalf("Unescape policy doc err doc=%q err=%s", *pol.PolicyDocument, err)
+						cbErr = fmt.Errorf("Unescape policy doc err doc=%q err=%s", *pol.PolicyDocument, err)
+						return false
 					}
 					pol.PolicyDocument = &doc
 					if include == false && filterMatch != nil {
@@ -514,7 +651,11 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 				}
 			}
 			if include {
-				jsonOut.Encode(IamObject{
+				out.AddDetail(iamObjectRow{
+					Type: "role",
+					Name: aws.StringValue(r.RoleName),
+					Arn:  aws.StringValue(r.Arn),
+				}, IamObject{
 					Type:   "role",
 					Detail: r,
 				})
@@ -525,7 +666,8 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 			for _, pol := range u.UserPolicyList {
 				doc, err := url.QueryUnescape(*pol.PolicyDocument)
 				if err != nil {
-					log.Fatalf("Unescape policy doc err doc=%q err=%s", *pol.PolicyDocument, err)
+					cbErr = fmt.Errorf("Unescape policy doc err doc=%q err=%s", *pol.PolicyDocument, err)
+					return false
 				}
 				pol.PolicyDocument = &doc
 				if include == false && filterMatch != nil {
@@ -535,7 +677,11 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 				}
 			}
 			if include {
-				jsonOut.Encode(IamObject{
+				out.AddDetail(iamObjectRow{
+					Type: "user",
+					Name: aws.StringValue(u.UserName),
+					Arn:  aws.StringValue(u.Arn),
+				}, IamObject{
 					Type:   "user",
 					Detail: u,
 				})
@@ -545,8 +691,13 @@ func iamGetAccountAuthorizationDetailsAction(cmd *cobra.Command, args []string)
 	})
 
 	if err != nil {
-		log.Fatalf("GetAccountAuthorizationDetails err: %s", err)
+		return fmt.Errorf("GetAccountAuthorizationDetails err: %w", err)
+	}
+	if cbErr != nil {
+		return cbErr
 	}
+
+	return out.Flush()
 }
 
 var (
@@ -558,7 +709,7 @@ func testAllIamIdentitiesCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "test-all-iam-identites",
 		Short: "Test access permission to a action+resource for all iam identites in account",
-		Run:   testAllIamIdentitiesAction,
+		RunE:  testAllIamIdentitiesAction,
 	}
 
 	cmd.Flags().StringArrayVarP(&principalActions, "actions", "", nil, "List of api operations (e.g kms:Decrypt)")
@@ -567,26 +718,34 @@ func testAllIamIdentitiesCommand() *cobra.Command {
 	return &cmd
 }
 
-func testAllIamIdentitiesAction(cmd *cobra.Command, args []string) {
-	iamSvc := iam.New(config.Session())
+func testAllIamIdentitiesAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	iamSvc := client.IAM(config.Session())
 
 	if len(principalActions) < 1 {
-		log.Fatalf("--actions is required")
+		return fmt.Errorf("%w: --actions is required", errs.ErrUsage)
 	}
 
 	if len(resoucesFlag) < 1 {
-		log.Fatalf("--resources is required")
+		return fmt.Errorf("%w: --resources is required", errs.ErrUsage)
 	}
 
 	actionNames := aws.StringSlice(principalActions)
 	resourceArns := aws.StringSlice(resoucesFlag)
 
-	csvOut := csv.NewWriter(os.Stdout)
+	progress := console.NewProgress("identities and policies")
+	defer progress.Clear()
+
+	csvOut := csv.NewWriter(progress.Writer(cmd.OutOrStdout()))
 	defer csvOut.Flush()
 
+	var cbErr error
 	input := &iam.GetAccountAuthorizationDetailsInput{}
-	err := iamSvc.GetAccountAuthorizationDetailsPages(input, func(details *iam.GetAccountAuthorizationDetailsOutput, b bool) bool {
+	err := iamSvc.GetAccountAuthorizationDetailsPagesWithContext(ctx, input, func(details *iam.GetAccountAuthorizationDetailsOutput, b bool) bool {
+		progress.AddTotal(len(details.Policies) + len(details.GroupDetailList) + len(details.RoleDetailList) + len(details.UserDetailList))
+
 		for _, m := range details.Policies {
+			progress.Inc(*m.Arn)
 
 			scpi := &iam.SimulateCustomPolicyInput{
 				ActionNames:     actionNames,
@@ -598,15 +757,17 @@ func testAllIamIdentitiesAction(cmd *cobra.Command, args []string) {
 				if pol.Document != nil {
 					doc, err := url.QueryUnescape(*pol.Document)
 					if err != nil {
-						log.Fatalf("Unescape policy doc err doc=%q err=%s", *pol.Document, err)
+						cbErr = fmt.Errorf("Unescape policy doc err doc=%q err=%s", *pol.Document, err)
+						return false
 					}
 
 					scpi.PolicyInputList = append(scpi.PolicyInputList, &doc)
 				}
 			}
-			simResult, err := iamSvc.SimulateCustomPolicy(scpi)
+			simResult, err := iamSvc.SimulateCustomPolicyWithContext(ctx, scpi)
 			if err != nil {
-				log.Fatalf("Failed to simulate policy for %s: %s", *m.Arn, err)
+				cbErr = fmt.Errorf("Failed to simulate policy for %s: %w", *m.Arn, err)
+				return false
 			}
 
 			for _, res := range simResult.EvaluationResults {
@@ -615,23 +776,24 @@ func testAllIamIdentitiesAction(cmd *cobra.Command, args []string) {
 			}
 		}
 
-		simulateARN := func(arn string) {
+		simulateARN := func(arn string) error {
 			sppi := &iam.SimulatePrincipalPolicyInput{
 				ActionNames:     actionNames,
 				ResourceArns:    resourceArns,
 				PolicySourceArn: &arn,
 			}
-			simResult, err := iamSvc.SimulatePrincipalPolicy(sppi)
+			simResult, err := iamSvc.SimulatePrincipalPolicyWithContext(ctx, sppi)
 			if err != nil {
-				log.Fatalf("Failed to simulate permission for %s: %s", arn, err)
+				return fmt.Errorf("Failed to simulate permission for %s: %w", arn, err)
 			}
 			for _, res := range simResult.EvaluationResults {
 				csvOut.Write([]string{"simulate-principal", *res.EvalDecision, *res.EvalActionName, *res.EvalResourceName, arn})
 				csvOut.Flush()
 			}
+			return nil
 		}
 
-		simulatePolicies := func(arn string, policyList []*iam.PolicyDetail) {
+		simulatePolicies := func(arn string, policyList []*iam.PolicyDetail) error {
 			if len(policyList) > 0 {
 				scpi := &iam.SimulateCustomPolicyInput{
 					ActionNames:     actionNames,
@@ -643,15 +805,15 @@ func testAllIamIdentitiesAction(cmd *cobra.Command, args []string) {
 					if pol.PolicyDocument != nil {
 						doc, err := url.QueryUnescape(*pol.PolicyDocument)
 						if err != nil {
-							log.Fatalf("Unescape policy doc err doc=%q err=%s", *pol.PolicyDocument, err)
+							return fmt.Errorf("Unescape policy doc err doc=%q err=%s", *pol.PolicyDocument, err)
 						}
 
 						scpi.PolicyInputList = append(scpi.PolicyInputList, &doc)
 					}
 				}
-				simResult, err := iamSvc.SimulateCustomPolicy(scpi)
+				simResult, err := iamSvc.SimulateCustomPolicyWithContext(ctx, scpi)
 				if err != nil {
-					log.Fatalf("Failed to simulate policy for %s: %s", arn, err)
+					return fmt.Errorf("Failed to simulate policy for %s: %w", arn, err)
 				}
 
 				for _, res := range simResult.EvaluationResults {
@@ -659,24 +821,42 @@ func testAllIamIdentitiesAction(cmd *cobra.Command, args []string) {
 					csvOut.Flush()
 				}
 			}
+			return nil
+		}
+
+		simulate := func(arn string, policyList []*iam.PolicyDetail) bool {
+			progress.Inc(arn)
+			if cbErr = simulateARN(arn); cbErr != nil {
+				return false
+			}
+			cbErr = simulatePolicies(arn, policyList)
+			return cbErr == nil
 		}
 
 		for _, g := range details.GroupDetailList {
-			simulateARN(*g.Arn)
-			simulatePolicies(*g.Arn, g.GroupPolicyList)
+			if !simulate(*g.Arn, g.GroupPolicyList) {
+				return false
+			}
 		}
 		for _, r := range details.RoleDetailList {
-			simulateARN(*r.Arn)
-			simulatePolicies(*r.Arn, r.RolePolicyList)
+			if !simulate(*r.Arn, r.RolePolicyList) {
+				return false
+			}
 		}
 		for _, u := range details.UserDetailList {
-			simulateARN(*u.Arn)
-			simulatePolicies(*u.Arn, u.UserPolicyList)
+			if !simulate(*u.Arn, u.UserPolicyList) {
+				return false
+			}
 		}
 		return true
 	})
 
 	if err != nil {
-		log.Fatalf("GetAccountAuthorizationDetails err: %s", err)
+		return fmt.Errorf("GetAccountAuthorizationDetails err: %w", err)
 	}
+	if cbErr != nil {
+		return cbErr
+	}
+
+	return nil
 }
@@ -5,7 +5,9 @@ import (
 	"log"
 
 	"github.com/aws/aws-sdk-go/service/ssoadmin"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
@@ -24,29 +26,32 @@ func identityCenterListPermissionSets() *cobra.Command {
 	return &cobra.Command{
 		Use:   "list-permission-sets",
 		Short: "List Identity Center Permission Sets",
-		Run:   listPermissionSets,
+		RunE:  listPermissionSets,
 	}
 }
 
-func listPermissionSets(cmd *cobra.Command, args []string) {
-	ssoAdminSvc := ssoadmin.New(config.Session())
+func listPermissionSets(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	ssoAdminSvc := client.SSOAdmin(config.Session())
 
-	instances, err := ssoAdminSvc.ListInstances(&ssoadmin.ListInstancesInput{})
+	instances, err := ssoAdminSvc.ListInstancesWithContext(ctx, &ssoadmin.ListInstancesInput{})
 	if err != nil {
-		log.Fatalf("Failed to list SSO instances: %v", err)
+		return fmt.Errorf("Failed to list SSO instances: %w", err)
 	}
 
 	if len(instances.Instances) == 0 {
-		log.Fatalf("No SSO instances found")
+		return fmt.Errorf("No SSO instances found")
 	}
 
 	instanceArn := *instances.Instances[0].InstanceArn
 
-	err = ssoAdminSvc.ListPermissionSetsPages(&ssoadmin.ListPermissionSetsInput{
+	out := output.New(cmd.OutOrStdout())
+
+	err = ssoAdminSvc.ListPermissionSetsPagesWithContext(ctx, &ssoadmin.ListPermissionSetsInput{
 		InstanceArn: &instanceArn,
 	}, func(output *ssoadmin.ListPermissionSetsOutput, lastPage bool) bool {
 		for _, permissionSet := range output.PermissionSets {
-			describeOutput, err := ssoAdminSvc.DescribePermissionSet(&ssoadmin.DescribePermissionSetInput{
+			describeOutput, err := ssoAdminSvc.DescribePermissionSetWithContext(ctx, &ssoadmin.DescribePermissionSetInput{
 				InstanceArn:      &instanceArn,
 				PermissionSetArn: permissionSet,
 			})
@@ -55,12 +60,22 @@ func listPermissionSets(cmd *cobra.Command, args []string) {
 				continue
 			}
 
-			fmt.Printf("%s %s\n", *permissionSet, *describeOutput.PermissionSet.Name)
+			out.AddDetail(permissionSetRow{
+				Arn:  *permissionSet,
+				Name: *describeOutput.PermissionSet.Name,
+			}, describeOutput.PermissionSet)
 		}
 		return true
 	})
 
 	if err != nil {
-		log.Fatalf("Failed to list permission sets: %v", err)
+		return fmt.Errorf("Failed to list permission sets: %w", err)
 	}
+
+	return out.Flush()
+}
+
+type permissionSetRow struct {
+	Arn  string `json:"arn"`
+	Name string `json:"name"`
 }
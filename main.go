@@ -5,12 +5,13 @@ import (
 	"os"
 
 	"github.com/psanford/aws-buddy/cmd"
+	"github.com/psanford/aws-buddy/errs"
 )
 
 func main() {
 	err := cmd.Execute()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+		os.Exit(errs.ExitCode(err))
 	}
 }
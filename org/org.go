@@ -1,7 +1,7 @@
 package org
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,15 +9,15 @@ import (
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/organizations"
-	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
 var (
-	jsonOutput       bool
 	assumeRoleName   string
 	orgListFileName  string
 	externalCommand  string
@@ -43,11 +43,10 @@ func orgListAccountsCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "List accounts",
-		Run:   orgListAccountsAction,
+		RunE:  orgListAccountsAction,
 	}
 
 	cmd.Flags().BoolVarP(&includeSuspended, "include-suspended", "", false, "Include suspended accounts")
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
 
 	return &cmd
 }
@@ -56,7 +55,7 @@ func orgEachAccountCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "each",
 		Short: "Run command against each account",
-		Run:   orgEachAccountAction,
+		RunE:  orgEachAccountAction,
 	}
 
 	cmd.Flags().StringVarP(&assumeRoleName, "role", "", "", "Role name to assume in each account")
@@ -66,67 +65,70 @@ func orgEachAccountCommand() *cobra.Command {
 	return &cmd
 }
 
-func orgListAccountsAction(cmd *cobra.Command, args []string) {
-	svc := organizations.New(config.Session())
+func orgListAccountsAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.Organizations(config.Session())
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	out := output.New(cmd.OutOrStdout())
 
-	err := svc.ListAccountsPages(nil, func(output *organizations.ListAccountsOutput, lastPage bool) bool {
-		for _, account := range output.Accounts {
-			if jsonOutput {
-				jsonOut.Encode(account)
-			} else {
-				if *account.Status == "SUSPENDED" && !includeSuspended {
-					continue
-				}
-
-				status := *account.Status
-				if status == "ACTIVE" {
-					status = ""
-				} else {
-					status = " " + status
-				}
-
-				fmt.Printf("%s %s%s\n", *account.Id, *account.Name, status)
+	err := svc.ListAccountsPagesWithContext(ctx, nil, func(resp *organizations.ListAccountsOutput, lastPage bool) bool {
+		for _, account := range resp.Accounts {
+			if *account.Status == "SUSPENDED" && !includeSuspended {
+				continue
 			}
+
+			out.AddDetail(accountRow{
+				ID:     *account.Id,
+				Name:   *account.Name,
+				Status: *account.Status,
+			}, account)
 		}
 
 		return true
 	})
 
 	if err != nil {
-		log.Fatalf("ListAccounts error: %s", err)
+		return fmt.Errorf("ListAccounts error: %w", err)
 	}
+
+	return out.Flush()
 }
 
-func orgEachAccountAction(cmd *cobra.Command, args []string) {
+type accountRow struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+func orgEachAccountAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	var cmdPath string
 	if externalCommand != "" {
 		p, err := exec.LookPath(externalCommand)
 		if err != nil {
-			log.Fatalf("Failed to find full path for cmd: %s %s", externalCommand, err)
+			return fmt.Errorf("Failed to find full path for cmd: %s %s", externalCommand, err)
 		}
 		cmdPath = p
 	} else {
 		buddyPath, err := os.Executable()
 		if err != nil {
-			log.Fatalf("Failed to find my own executable: %s", err)
+			return fmt.Errorf("Failed to find my own executable: %w", err)
 		}
 		cmdPath = buddyPath
 	}
 
-	svc := organizations.New(config.Session())
+	svc := client.Organizations(config.Session())
 
-	stsClient := sts.New(config.Session())
+	stsClient := client.STS(config.Session())
+	stdout := cmd.OutOrStdout()
 
 	if assumeRoleName == "" {
-		log.Fatal("-role is a required flag")
+		return fmt.Errorf("%w: --role is a required flag", errs.ErrUsage)
 	}
 
-	ident, err := stsClient.GetCallerIdentity(nil)
+	ident, err := stsClient.GetCallerIdentityWithContext(ctx, nil)
 	if err != nil {
-		log.Fatalf("DescribeAccount (root) error: %s", err)
+		return fmt.Errorf("DescribeAccount (root) error: %w", err)
 	}
 	rootAccountID := *ident.Account
 
@@ -135,7 +137,7 @@ func orgEachAccountAction(cmd *cobra.Command, args []string) {
 	if orgListFileName != "" {
 		data, err := os.ReadFile(orgListFileName)
 		if err != nil {
-			log.Fatalf("Read %s err: %s", orgListFileName, err)
+			return fmt.Errorf("Read %s err: %s", orgListFileName, err)
 		}
 
 		lines := strings.Split(string(data), "\n")
@@ -156,7 +158,7 @@ func orgEachAccountAction(cmd *cobra.Command, args []string) {
 			}
 		}
 	} else {
-		err = svc.ListAccountsPages(nil, func(output *organizations.ListAccountsOutput, lastPage bool) bool {
+		err = svc.ListAccountsPagesWithContext(ctx, nil, func(output *organizations.ListAccountsOutput, lastPage bool) bool {
 			for _, account := range output.Accounts {
 				if *account.Id == rootAccountID {
 					continue
@@ -170,67 +172,85 @@ func orgEachAccountAction(cmd *cobra.Command, args []string) {
 			return true
 		})
 		if err != nil {
-			log.Fatalf("ListAccount err: %s", err)
+			return fmt.Errorf("ListAccount err: %w", err)
 		}
 	}
 
-	var errors []error
+	var (
+		errList  []error
+		finished []string
+	)
 
 	for _, orgInfo := range orgIDs {
+		if ctx.Err() != nil {
+			break
+		}
+
 		fmt.Fprintf(os.Stderr, "# Account %s %s\n", orgInfo.arn, orgInfo)
 
 		// get the correct arn prefix (for other aws partitions)
 		arnParts := strings.SplitN(orgInfo.arn, ":", 3)
 
 		roleARN := fmt.Sprintf("%s:%s:iam::%s:role/%s", arnParts[0], arnParts[1], orgInfo.id, assumeRoleName)
-		roleSessionName := fmt.Sprintf("%d", time.Now().UTC().UnixNano())
 
-		assumeRoleInput := &sts.AssumeRoleInput{
-			RoleArn:         aws.String(roleARN),
-			RoleSessionName: aws.String(roleSessionName),
-			DurationSeconds: aws.Int64(900),
-		}
-
-		resp, err := stsClient.AssumeRole(assumeRoleInput)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("Assume role error: %s", err))
+		creds, err := config.AssumeRole(config.Session(), roleARN).GetWithContext(ctx)
+		if ctx.Err() != nil {
+			break
+		} else if err != nil {
+			errList = append(errList, fmt.Errorf("account %s: assume role error: %w", orgInfo, err))
+			finished = append(finished, fmt.Sprintf("%s (assume role error)", orgInfo))
 			log.Printf("Assume role error: %s", err)
 			continue
 		}
 
-		cmd := exec.Command(cmdPath, args...)
-		cmd.Env = append(os.Environ(),
-			fmt.Sprintf("AWS_ACCESS_KEY_ID=%s", *resp.Credentials.AccessKeyId),
-			fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%s", *resp.Credentials.SecretAccessKey),
-			fmt.Sprintf("AWS_SESSION_TOKEN=%s", *resp.Credentials.SessionToken),
-		)
+		cmd := exec.CommandContext(ctx, cmdPath, args...)
+		cmd.Env = config.CredentialEnv(os.Environ(), creds, config.ResolvedRegion())
 
-		cmd.Stdout = os.Stdout
+		// give the command a chance to clean up before it is killed
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(os.Interrupt)
+		}
+		cmd.WaitDelay = 10 * time.Second
+
+		cmd.Stdout = stdout
 		cmd.Stderr = os.Stderr
 
 		fmt.Fprintf(os.Stderr, "# Running %s %s\n", cmdPath, strings.Join(args, " "))
 		err = cmd.Run()
+		if ctx.Err() != nil {
+			break
+		}
+
+		code := errs.ExitOK
 		if err != nil {
-			fullErr := fmt.Errorf("Failed to execute cmd on account %s: %s cmd: %s %s", orgInfo, cmdPath, err, strings.Join(args, " "))
-			errors = append(errors, fullErr)
+			code = errs.ExitError
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			}
+			fullErr := fmt.Errorf("account %s (%s): %s cmd: %s %s", orgInfo, errs.CodeName(code), err, cmdPath, strings.Join(args, " "))
+			errList = append(errList, &errs.ExitStatusError{Code: code, Err: fullErr})
 			log.Print(fullErr)
 		}
+		finished = append(finished, fmt.Sprintf("%s (%s)", orgInfo, errs.CodeName(code)))
 	}
 
-	if len(errors) > 0 {
-		log.Printf("Errors:")
-		for _, err := range errors {
-			log.Print(err)
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "# Interrupted after %d of %d accounts\n", len(finished), len(orgIDs))
+		for _, f := range finished {
+			fmt.Fprintf(os.Stderr, "# finished %s\n", f)
 		}
-		os.Exit(1)
+		return err
 	}
+
+	return errs.PartialFailure(errList)
 }
 
 func orgListOrgUnitsCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list-ou-tree",
 		Short: "List organizational units",
-		Run:   orgListOrgUnitsAction,
+		RunE:  orgListOrgUnitsAction,
 	}
 
 	cmd.Flags().BoolVarP(&includeAccts, "include-accts", "", false, "Include Child accounts")
@@ -238,15 +258,21 @@ func orgListOrgUnitsCommand() *cobra.Command {
 	return &cmd
 }
 
-func orgListOrgUnitsAction(cmd *cobra.Command, args []string) {
-	svc := organizations.New(config.Session())
+func orgListOrgUnitsAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.Organizations(config.Session())
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	w := cmd.OutOrStdout()
+	out := output.New(w)
+	tree := output.IsTable(out.Default)
 
 	lri := organizations.ListRootsInput{}
 	depth := -1
-	err := svc.ListRootsPages(&lri, func(lro *organizations.ListRootsOutput, b bool) bool {
+	var (
+		parents []string
+		cbErr   error
+	)
+	err := svc.ListRootsPagesWithContext(ctx, &lri, func(lro *organizations.ListRootsOutput, b bool) bool {
 		depth += 1
 		defer func() { depth -= 1 }()
 
@@ -256,29 +282,59 @@ func orgListOrgUnitsAction(cmd *cobra.Command, args []string) {
 			defer func() { depth -= 1 }()
 
 			for _, ou := range loufpo.OrganizationalUnits {
-				fmt.Printf("%s%s %s\n", strings.Repeat(" ", depth), *ou.Id, *ou.Name)
+				if tree {
+					fmt.Fprintf(w, "%s%s %s\n", strings.Repeat(" ", depth), *ou.Id, *ou.Name)
+				} else {
+					var parent string
+					if len(parents) > 0 {
+						parent = parents[len(parents)-1]
+					}
+					out.AddDetail(ouRow{
+						ID:     *ou.Id,
+						Name:   *ou.Name,
+						Type:   "ou",
+						Parent: parent,
+						Depth:  depth,
+					}, ou)
+				}
 
 				if includeAccts {
 					lafp := organizations.ListAccountsForParentInput{
 						ParentId: ou.Id,
 					}
-					err := svc.ListAccountsForParentPages(&lafp, func(lafpo *organizations.ListAccountsForParentOutput, b bool) bool {
+					err := svc.ListAccountsForParentPagesWithContext(ctx, &lafp, func(lafpo *organizations.ListAccountsForParentOutput, b bool) bool {
 						for _, acct := range lafpo.Accounts {
-							fmt.Printf("%s%s %s\n", strings.Repeat(" ", depth+1), *acct.Id, *acct.Name)
+							if tree {
+								fmt.Fprintf(w, "%s%s %s\n", strings.Repeat(" ", depth+1), *acct.Id, *acct.Name)
+							} else {
+								out.AddDetail(ouRow{
+									ID:     *acct.Id,
+									Name:   *acct.Name,
+									Type:   "account",
+									Parent: *ou.Id,
+									Depth:  depth + 1,
+								}, acct)
+							}
 						}
 						return true
 					})
 					if err != nil {
-						log.Fatalf("list accounts for parent err: %s", err)
+						cbErr = fmt.Errorf("list accounts for parent err: %w", err)
+						return false
 					}
 				}
 
 				loufpi := organizations.ListOrganizationalUnitsForParentInput{
 					ParentId: ou.Id,
 				}
-				err := svc.ListOrganizationalUnitsForParentPages(&loufpi, handleOU)
-				if err != nil {
-					log.Fatalf("list ou for parent err: %s", err)
+				parents = append(parents, *ou.Id)
+				err := svc.ListOrganizationalUnitsForParentPagesWithContext(ctx, &loufpi, handleOU)
+				parents = parents[:len(parents)-1]
+				if err != nil && cbErr == nil {
+					cbErr = fmt.Errorf("list ou for parent err: %w", err)
+				}
+				if cbErr != nil {
+					return false
 				}
 			}
 
@@ -297,12 +353,24 @@ func orgListOrgUnitsAction(cmd *cobra.Command, args []string) {
 			}
 		}
 
-		handleOU(&loufpo, true)
-		return true
+		return handleOU(&loufpo, true)
 	})
 	if err != nil {
-		log.Fatalf("list roots err: %s", err)
+		return fmt.Errorf("list roots err: %w", err)
+	}
+	if cbErr != nil {
+		return cbErr
 	}
+
+	return out.Flush()
+}
+
+type ouRow struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Parent string `json:"parent"`
+	Depth  int    `json:"depth"`
 }
 
 type orgInfo struct {
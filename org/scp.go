@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
@@ -29,36 +32,46 @@ func scpListCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "List Service Control Policies",
-		Run:   scpListAction,
+		RunE:  scpListAction,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json output")
-
 	return &cmd
 }
 
-func scpListAction(cmd *cobra.Command, args []string) {
-	svc := organizations.New(config.Session())
+func scpListAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.Organizations(config.Session())
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	out := output.New(cmd.OutOrStdout())
 
-	err := svc.ListPoliciesPages(&organizations.ListPoliciesInput{
+	err := svc.ListPoliciesPagesWithContext(ctx, &organizations.ListPoliciesInput{
 		Filter: aws.String("SERVICE_CONTROL_POLICY"),
-	}, func(output *organizations.ListPoliciesOutput, lastPage bool) bool {
-		for _, policy := range output.Policies {
-			if jsonOutput {
-				jsonOut.Encode(policy)
-			} else {
-				fmt.Printf("%20s %s\n", *policy.Id, *policy.Name)
-			}
+	}, func(resp *organizations.ListPoliciesOutput, lastPage bool) bool {
+		for _, policy := range resp.Policies {
+			out.AddDetail(newPolicyRow(policy), policy)
 		}
 
 		return true
 	})
 
 	if err != nil {
-		log.Fatalf("ListPolicies error: %s", err)
+		return fmt.Errorf("ListPolicies error: %w", err)
+	}
+
+	return out.Flush()
+}
+
+type policyRow struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	AwsManaged bool   `json:"aws_managed"`
+}
+
+func newPolicyRow(p *organizations.PolicySummary) policyRow {
+	return policyRow{
+		ID:         aws.StringValue(p.Id),
+		Name:       aws.StringValue(p.Name),
+		AwsManaged: aws.BoolValue(p.AwsManaged),
 	}
 }
 
@@ -66,110 +79,129 @@ func scpShowCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "show <policy-id>",
 		Short: "Show Service Control Policy",
-		Run:   scpShowAction,
+		RunE:  scpShowAction,
 	}
 
 	return &cmd
 }
 
-func scpShowAction(cmd *cobra.Command, args []string) {
+func scpShowAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatalf("Usage: show <policy-id>")
+		return fmt.Errorf("%w: show <policy-id>", errs.ErrUsage)
 	}
 
 	policyID := args[0]
 
-	svc := organizations.New(config.Session())
+	svc := client.Organizations(config.Session())
 
-	output, err := svc.DescribePolicy(&organizations.DescribePolicyInput{
+	resp, err := svc.DescribePolicyWithContext(ctx, &organizations.DescribePolicyInput{
 		PolicyId: aws.String(policyID),
 	})
 
 	if err != nil {
-		log.Fatalf("DescribePolicy error: %s", err)
+		return fmt.Errorf("DescribePolicy error: %w", err)
 	}
 
-	policy := output.Policy
+	policy := resp.Policy
+
+	w := cmd.OutOrStdout()
+	out := output.New(w)
+	if !output.IsTable(out.Default) {
+		out.AddDetail(newPolicyRow(policy.PolicySummary), policy)
+		return out.Flush()
+	}
 
-	fmt.Printf("========[ %s ]===================\n", *policy.PolicySummary.Name)
-	fmt.Printf("id          : %s\n", *policy.PolicySummary.Id)
-	fmt.Printf("name        : %s\n", *policy.PolicySummary.Name)
-	fmt.Printf("description : %s\n", *policy.PolicySummary.Description)
-	fmt.Printf("type        : %s\n", *policy.PolicySummary.Type)
-	fmt.Printf("aws managed : %t\n", *policy.PolicySummary.AwsManaged)
-	fmt.Printf("Content     :\n")
+	fmt.Fprintf(w, "========[ %s ]===================\n", *policy.PolicySummary.Name)
+	fmt.Fprintf(w, "id          : %s\n", *policy.PolicySummary.Id)
+	fmt.Fprintf(w, "name        : %s\n", *policy.PolicySummary.Name)
+	fmt.Fprintf(w, "description : %s\n", *policy.PolicySummary.Description)
+	fmt.Fprintf(w, "type        : %s\n", *policy.PolicySummary.Type)
+	fmt.Fprintf(w, "aws managed : %t\n", *policy.PolicySummary.AwsManaged)
+	fmt.Fprintf(w, "Content     :\n")
 
 	var content any
 	err = json.Unmarshal([]byte(*policy.Content), &content)
 	if err != nil {
-		log.Fatalf("Failed to parse policy content: %s", err)
+		return fmt.Errorf("Failed to parse policy content: %w", err)
 	}
 
 	contentJSON, err := json.MarshalIndent(content, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to format policy content: %s", err)
+		return fmt.Errorf("Failed to format policy content: %w", err)
 	}
-	fmt.Println(string(contentJSON))
+	fmt.Fprintln(w, string(contentJSON))
+
+	return nil
 }
 
 func scpDumpCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "dump",
 		Short: "Dump all Service Control Policies",
-		Run:   scpDumpAction,
+		RunE:  scpDumpAction,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json output")
-
 	return &cmd
 }
 
-func scpDumpAction(cmd *cobra.Command, args []string) {
-	svc := organizations.New(config.Session())
+func scpDumpAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.Organizations(config.Session())
+
+	progress := console.NewProgress("policies")
+	defer progress.Clear()
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	w := progress.Writer(cmd.OutOrStdout())
+	out := output.New(w)
+	text := output.IsTable(out.Default)
 
-	err := svc.ListPoliciesPages(&organizations.ListPoliciesInput{
+	err := svc.ListPoliciesPagesWithContext(ctx, &organizations.ListPoliciesInput{
 		Filter: aws.String("SERVICE_CONTROL_POLICY"),
-	}, func(output *organizations.ListPoliciesOutput, lastPage bool) bool {
-		for _, policy := range output.Policies {
-			policyOutput, err := svc.DescribePolicy(&organizations.DescribePolicyInput{
+	}, func(resp *organizations.ListPoliciesOutput, lastPage bool) bool {
+		progress.AddTotal(len(resp.Policies))
+		for _, policy := range resp.Policies {
+			progress.Inc(*policy.Name)
+
+			policyOutput, err := svc.DescribePolicyWithContext(ctx, &organizations.DescribePolicyInput{
 				PolicyId: policy.Id,
 			})
 
 			if err != nil {
+				progress.Clear()
 				log.Printf("Error describing policy %s: %s", *policy.Id, err)
 				continue
 			}
 
 			fullPolicy := policyOutput.Policy
 
-			if jsonOutput {
-				jsonOut.Encode(fullPolicy)
+			if !text {
+				out.AddDetail(newPolicyRow(fullPolicy.PolicySummary), fullPolicy)
 			} else {
-				fmt.Printf("========[ %s ]===================\n", *fullPolicy.PolicySummary.Name)
-				fmt.Printf("id          : %s\n", *fullPolicy.PolicySummary.Id)
-				fmt.Printf("name        : %s\n", *fullPolicy.PolicySummary.Name)
-				fmt.Printf("description : %s\n", *fullPolicy.PolicySummary.Description)
-				fmt.Printf("type        : %s\n", *fullPolicy.PolicySummary.Type)
-				fmt.Printf("aws managed : %t\n", *fullPolicy.PolicySummary.AwsManaged)
-				fmt.Printf("Content     :\n")
+				fmt.Fprintf(w, "========[ %s ]===================\n", *fullPolicy.PolicySummary.Name)
+				fmt.Fprintf(w, "id          : %s\n", *fullPolicy.PolicySummary.Id)
+				fmt.Fprintf(w, "name        : %s\n", *fullPolicy.PolicySummary.Name)
+				fmt.Fprintf(w, "description : %s\n", *fullPolicy.PolicySummary.Description)
+				fmt.Fprintf(w, "type        : %s\n", *fullPolicy.PolicySummary.Type)
+				fmt.Fprintf(w, "aws managed : %t\n", *fullPolicy.PolicySummary.AwsManaged)
+				fmt.Fprintf(w, "Content     :\n")
 
 				var content any
 				err = json.Unmarshal([]byte(*fullPolicy.Content), &content)
 				if err != nil {
+					progress.Clear()
 					log.Printf("Failed to parse policy content for %s: %s", *fullPolicy.PolicySummary.Id, err)
 					continue
 				}
 
 				contentJSON, err := json.MarshalIndent(content, "", "  ")
 				if err != nil {
+					progress.Clear()
 					log.Printf("Failed to format policy content for %s: %s", *fullPolicy.PolicySummary.Id, err)
 					continue
 				}
-				fmt.Println(string(contentJSON))
-				fmt.Println()
+				fmt.Fprintln(w, string(contentJSON))
+				fmt.Fprintln(w)
 			}
 		}
 
@@ -177,6 +209,8 @@ func scpDumpAction(cmd *cobra.Command, args []string) {
 	})
 
 	if err != nil {
-		log.Fatalf("ListPolicies error: %s", err)
+		return fmt.Errorf("ListPolicies error: %w", err)
 	}
+
+	return out.Flush()
 }
@@ -0,0 +1,577 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	Table = "table"
+	JSON  = "json"
+	JSONL = "jsonl"
+	CSV   = "csv"
+	TSV   = "tsv"
+	YAML  = "yaml"
+)
+
+var Formats = []string{Table, JSON, JSONL, CSV, TSV, YAML}
+
+var (
+	// Format is set by the global --output flag. When empty each command
+	// uses its own default (usually table).
+	Format string
+
+	Columns  []string
+	SortBy   string
+	Desc     bool
+	NoHeader bool
+
+	// Query is a JMESPath expression applied to the json form of the
+	// output before it is printed.
+	Query string
+	query *jmespath.JMESPath
+
+	legacyJSON bool
+	legacyCSV  bool
+)
+
+func AddFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&Format, "output", "o", "", fmt.Sprintf("Output format (%s)", strings.Join(Formats, "|")))
+	flags.StringSliceVarP(&Columns, "columns", "", nil, "Columns to show for table, csv and tsv output (e.g. name,id,state)")
+	flags.StringVarP(&SortBy, "sort-by", "", "", "Sort rows by column")
+	flags.BoolVarP(&Desc, "desc", "", false, "Sort in descending order")
+	flags.BoolVarP(&NoHeader, "no-header", "", false, "Omit the header row for table, csv and tsv output")
+	flags.StringVarP(&Query, "query", "", "", "JMESPath query to apply to the json output (e.g. '[].InstanceId')")
+
+	flags.BoolVarP(&legacyJSON, "json", "", false, "Show raw json output")
+	flags.BoolVarP(&legacyCSV, "csv", "", false, "Show csv output")
+	flags.MarkDeprecated("json", "use --output json")
+	flags.MarkDeprecated("csv", "use --output csv")
+}
+
+// CheckFlags validates the output flags. It must be called after flag
+// parsing and before any Printer is used.
+func CheckFlags() error {
+	if legacyJSON {
+		Format = JSON
+	} else if legacyCSV {
+		Format = CSV
+	}
+
+	query = nil
+	if Query != "" {
+		q, err := jmespath.Compile(Query)
+		if err != nil {
+			return fmt.Errorf("invalid --query: %w", err)
+		}
+		query = q
+	}
+
+	if Format == "" {
+		return nil
+	}
+	for _, f := range Formats {
+		if f == Format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown --output format %q (expected one of %s)", Format, strings.Join(Formats, ", "))
+}
+
+// IsTable reports whether the human readable format is selected, taking
+// defaultFormat as the command's default.
+func IsTable(defaultFormat string) bool {
+	return resolve(defaultFormat) == Table
+}
+
+func resolve(defaultFormat string) string {
+	if Format != "" {
+		return Format
+	}
+	if defaultFormat != "" {
+		return defaultFormat
+	}
+	return Table
+}
+
+// Printer buffers records and writes them in the selected format on Flush.
+//
+// Each record has a row, a flat struct whose json tagged fields become the
+// columns for table, csv and tsv output, and an optional detail value that
+// is emitted for the structured formats (json, jsonl, yaml) in place of
+// the row. Detail is usually the raw AWS SDK struct.
+//
+// Fields tagged `output:"hidden"` are not shown by default but can be
+// selected with --columns and used with --sort-by.
+//
+// Add and AddDetail are safe to call from multiple goroutines.
+type Printer struct {
+	// Default is the format used when --output is not set.
+	Default string
+
+	// Show lists hidden columns to include in the default columns.
+	Show []string
+
+	w       io.Writer
+	mu      sync.Mutex
+	records []record
+}
+
+type record struct {
+	row    interface{}
+	detail interface{}
+}
+
+func New(w io.Writer) *Printer {
+	return &Printer{
+		w: w,
+	}
+}
+
+func (p *Printer) Add(row interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, record{row: row})
+}
+
+func (p *Printer) AddDetail(row, detail interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, record{row: row, detail: detail})
+}
+
+// Writer returns the writer p prints to, for commands that write some of
+// their output directly.
+func (p *Printer) Writer() io.Writer {
+	return p.w
+}
+
+// Fork returns a Printer with p's settings that holds its records, and
+// anything written to its Writer, until it is passed to Join. Concurrent
+// producers each use a fork so their output can be joined in a fixed
+// order.
+func (p *Printer) Fork() *Printer {
+	return &Printer{
+		Default: p.Default,
+		Show:    append([]string(nil), p.Show...),
+		w:       &bytes.Buffer{},
+	}
+}
+
+// Join writes what was written to fork's Writer to p's and appends fork's
+// records to p's.
+func (p *Printer) Join(fork *Printer) error {
+	if buf, ok := fork.w.(*bytes.Buffer); ok && buf.Len() > 0 {
+		if _, err := p.w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	fork.mu.Lock()
+	defer fork.mu.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, fork.records...)
+	return nil
+}
+
+func (p *Printer) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.records)
+}
+
+func (p *Printer) Flush() error {
+	defer func() {
+		p.records = nil
+	}()
+
+	if err := p.sort(); err != nil {
+		return err
+	}
+
+	if query != nil {
+		return p.writeQuery()
+	}
+
+	switch resolve(p.Default) {
+	case JSON:
+		return p.writeJSON()
+	case JSONL:
+		return p.writeJSONL()
+	case YAML:
+		return p.writeYAML()
+	case CSV:
+		return p.writeCSV()
+	case TSV:
+		return p.writeTSV()
+	default:
+		return p.writeTable()
+	}
+}
+
+func (p *Printer) structured() []interface{} {
+	out := make([]interface{}, 0, len(p.records))
+	for _, r := range p.records {
+		if r.detail != nil {
+			out = append(out, r.detail)
+		} else {
+			out = append(out, r.row)
+		}
+	}
+	return out
+}
+
+func (p *Printer) writeJSON() error {
+	out, err := json.MarshalIndent(p.structured(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", out)
+	return err
+}
+
+func (p *Printer) writeJSONL() error {
+	enc := json.NewEncoder(p.w)
+	for _, v := range p.structured() {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Printer) writeYAML() error {
+	if len(p.records) == 0 {
+		return nil
+	}
+	generic, err := toGeneric(p.structured())
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	_, err = p.w.Write(out)
+	return err
+}
+
+func (p *Printer) writeCSV() error {
+	header, rows, err := p.cells()
+	if err != nil || header == nil {
+		return err
+	}
+	w := csv.NewWriter(p.w)
+	if !NoHeader {
+		w.Write(header)
+	}
+	w.WriteAll(rows)
+	return w.Error()
+}
+
+var tsvReplacer = strings.NewReplacer("\t", " ", "\n", " ")
+
+func (p *Printer) writeTSV() error {
+	header, rows, err := p.cells()
+	if err != nil || header == nil {
+		return err
+	}
+	if !NoHeader {
+		rows = append([][]string{header}, rows...)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = tsvReplacer.Replace(cell)
+		}
+		if _, err := fmt.Fprintln(p.w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Printer) writeTable() error {
+	header, rows, err := p.cells()
+	if err != nil || header == nil {
+		return err
+	}
+	if !NoHeader {
+		rows = append([][]string{header}, rows...)
+	}
+	_, err = io.WriteString(p.w, console.FormatTable(rows))
+	return err
+}
+
+func (p *Printer) cells() ([]string, [][]string, error) {
+	var (
+		cols   []column
+		header []string
+		rows   [][]string
+	)
+	for _, r := range p.records {
+		if r.row == nil {
+			continue
+		}
+		v := reflect.Indirect(reflect.ValueOf(r.row))
+		if cols == nil {
+			var err error
+			cols, err = selectColumns(v.Type(), Columns, p.Show)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, c := range cols {
+				header = append(header, c.name)
+			}
+		}
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			row[i] = formatCell(v.Field(c.index))
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+func (p *Printer) sort() error {
+	if SortBy == "" {
+		return nil
+	}
+
+	var (
+		col   column
+		found bool
+	)
+	for _, r := range p.records {
+		if r.row == nil {
+			continue
+		}
+		cols, err := selectColumns(reflect.Indirect(reflect.ValueOf(r.row)).Type(), []string{SortBy}, nil)
+		if err != nil {
+			return fmt.Errorf("--sort-by: %w", err)
+		}
+		col = cols[0]
+		found = true
+		break
+	}
+	if !found {
+		return nil
+	}
+
+	key := func(r record) reflect.Value {
+		if r.row == nil {
+			return reflect.Value{}
+		}
+		return reflect.Indirect(reflect.ValueOf(r.row)).Field(col.index)
+	}
+
+	sort.SliceStable(p.records, func(i, j int) bool {
+		a, b := key(p.records[i]), key(p.records[j])
+		if Desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+	return nil
+}
+
+type column struct {
+	name   string
+	index  int
+	hidden bool
+}
+
+func columns(t reflect.Type) []column {
+	var cols []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		cols = append(cols, column{
+			name:   name,
+			index:  i,
+			hidden: f.Tag.Get("output") == "hidden",
+		})
+	}
+	return cols
+}
+
+// selectColumns returns the named columns of t in the order given, or the
+// default visible columns plus any hidden columns in show if names is empty.
+func selectColumns(t reflect.Type, names, show []string) ([]column, error) {
+	all := columns(t)
+	if len(names) == 0 {
+		visible := make([]column, 0, len(all))
+		for _, c := range all {
+			if !c.hidden || contains(show, c.name) {
+				visible = append(visible, c)
+			}
+		}
+		return visible, nil
+	}
+
+	selected := make([]column, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		var found bool
+		for _, c := range all {
+			if c.name == name {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			available := make([]string, len(all))
+			for i, c := range all {
+				available[i] = c.name
+			}
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(available, ","))
+		}
+	}
+	return selected, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// less orders two cell values, comparing numbers, bools and times by value
+// and everything else by its formatted string. Empty values sort first.
+func less(a, b reflect.Value) bool {
+	a, b = deref(a), deref(b)
+	if !a.IsValid() || !b.IsValid() {
+		return !a.IsValid() && b.IsValid()
+	}
+
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Before(b.Interface().(time.Time))
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return formatCell(a) < formatCell(b)
+}
+
+func deref(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func formatCell(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			parts[i] = formatCell(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	case reflect.Map:
+		parts := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			parts = append(parts, fmt.Sprintf("%s=%s", formatCell(iter.Key()), formatCell(iter.Value())))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// toGeneric round trips v through json so that the AWS SDK field names
+// are preserved in other encodings.
+func toGeneric(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(out), nil
+}
+
+func normalizeNumbers(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return i
+		}
+		f, _ := vv.Float64()
+		return f
+	case []interface{}:
+		for i := range vv {
+			vv[i] = normalizeNumbers(vv[i])
+		}
+	case map[string]interface{}:
+		for k := range vv {
+			vv[k] = normalizeNumbers(vv[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,202 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/psanford/aws-buddy/console"
+	"gopkg.in/yaml.v2"
+)
+
+func (p *Printer) writeQuery() error {
+	b, err := json.Marshal(p.structured())
+	if err != nil {
+		return err
+	}
+	// jmespath expects plain json values (float64 numbers)
+	var data interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	result, err := query.Search(data)
+	if err != nil {
+		return fmt.Errorf("--query: %w", err)
+	}
+
+	switch resolve(p.Default) {
+	case JSON:
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", out)
+		return err
+	case JSONL:
+		enc := json.NewEncoder(p.w)
+		if list, ok := result.([]interface{}); ok {
+			for _, v := range list {
+				if err := enc.Encode(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return enc.Encode(result)
+	case YAML:
+		out, err := yaml.Marshal(integralFloats(result))
+		if err != nil {
+			return err
+		}
+		_, err = p.w.Write(out)
+		return err
+	}
+
+	header, rows := queryCells(result)
+	if len(rows) == 0 {
+		return nil
+	}
+	if header != nil && !NoHeader {
+		rows = append([][]string{header}, rows...)
+	}
+
+	switch resolve(p.Default) {
+	case CSV:
+		w := csv.NewWriter(p.w)
+		w.WriteAll(rows)
+		return w.Error()
+	case TSV:
+		for _, row := range rows {
+			for i, cell := range row {
+				row[i] = tsvReplacer.Replace(cell)
+			}
+			if _, err := fmt.Fprintln(p.w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if header == nil {
+		// single column of scalars; print them bare so they are easy to
+		// pipe into other commands
+		for _, row := range rows {
+			if _, err := fmt.Fprintln(p.w, row[0]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err = io.WriteString(p.w, console.FormatTable(rows))
+	return err
+}
+
+// queryCells flattens an arbitrary query result into table rows. A list of
+// objects becomes one row per object with a column per key, a single object
+// becomes key/value rows and scalars become a single unnamed column.
+func queryCells(result interface{}) ([]string, [][]string) {
+	switch v := result.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		rows := make([][]string, 0, len(keys))
+		for _, k := range keys {
+			rows = append(rows, []string{k, queryCell(v[k])})
+		}
+		return []string{"key", "value"}, rows
+	case []interface{}:
+		var (
+			keys    []string
+			seen    = make(map[string]bool)
+			objects = true
+		)
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				objects = false
+				break
+			}
+			for _, k := range sortedKeys(m) {
+				if !seen[k] {
+					seen[k] = true
+					keys = append(keys, k)
+				}
+			}
+		}
+
+		rows := make([][]string, 0, len(v))
+		if !objects || len(v) == 0 {
+			for _, item := range v {
+				rows = append(rows, []string{queryCell(item)})
+			}
+			return nil, rows
+		}
+
+		for _, item := range v {
+			m := item.(map[string]interface{})
+			row := make([]string, len(keys))
+			for i, k := range keys {
+				row[i] = queryCell(m[k])
+			}
+			rows = append(rows, row)
+		}
+		return keys, rows
+	default:
+		return nil, [][]string{{queryCell(v)}}
+	}
+}
+
+func queryCell(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case float64:
+		if vv == math.Trunc(vv) && math.Abs(vv) < 1e15 {
+			return fmt.Sprintf("%d", int64(vv))
+		}
+		return fmt.Sprint(vv)
+	case bool:
+		return fmt.Sprint(vv)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return strings.TrimSpace(buf.String())
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func integralFloats(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case float64:
+		if vv == math.Trunc(vv) && math.Abs(vv) < 1e15 {
+			return int64(vv)
+		}
+	case []interface{}:
+		for i := range vv {
+			vv[i] = integralFloats(vv[i])
+		}
+	case map[string]interface{}:
+		for k := range vv {
+			vv[k] = integralFloats(vv[k])
+		}
+	}
+	return v
+}
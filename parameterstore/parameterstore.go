@@ -1,21 +1,27 @@
 package parameterstore
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
 	"github.com/psanford/aws-buddy/config"
 	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/psanford/aws-buddy/regions"
 	"github.com/spf13/cobra"
 )
 
 var (
-	jsonOutput bool
 	paramType  string
 	paramDescr string
 )
@@ -39,69 +45,120 @@ func paramListCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "List parameter",
-		Run:   paramList,
+		RunE:  paramList,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
+	regions.AddFlags(&cmd)
 
 	return &cmd
 }
 
-func paramList(cmd *cobra.Command, args []string) {
-	ssmClient := ssm.New(config.Session())
-
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
-
-	err := ssmClient.DescribeParametersPages(&ssm.DescribeParametersInput{}, func(dpo *ssm.DescribeParametersOutput, b bool) bool {
-		for _, pm := range dpo.Parameters {
-			if jsonOutput {
-				jsonOut.Encode(pm)
-			} else {
-				fmt.Printf("%-12.12s %s\n", *pm.Type, *pm.Name)
+func paramList(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := output.New(cmd.OutOrStdout())
+
+	listErr := regions.Each(ctx, out, func(sess *session.Session, region string, out *output.Printer) error {
+		ssmClient := client.SSM(sess)
+
+		err := ssmClient.DescribeParametersPagesWithContext(ctx, &ssm.DescribeParametersInput{}, func(dpo *ssm.DescribeParametersOutput, b bool) bool {
+			for _, pm := range dpo.Parameters {
+				out.AddDetail(paramRow{
+					Region:       region,
+					Type:         *pm.Type,
+					Name:         *pm.Name,
+					LastModified: aws.TimeValue(pm.LastModifiedDate),
+				}, regions.Detail(region, pm))
 			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("DescribeParameters err: %w", err)
 		}
-		return true
+		return nil
 	})
 
-	if err != nil {
-		log.Fatalf("DescribeParameters err: %s", err)
+	if err := out.Flush(); err != nil {
+		return err
 	}
+	return listErr
+}
+
+type paramRow struct {
+	Region       string    `json:"region" output:"hidden"`
+	Type         string    `json:"type"`
+	Name         string    `json:"name"`
+	LastModified time.Time `json:"last_modified"`
 }
 
 func paramGetCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "get",
-		Short: "Get parameter value",
-		Run:   paramGet,
+		Use:               "get",
+		Short:             "Get parameter value",
+		RunE:              paramGet,
+		ValidArgsFunction: completion.Params(0),
 	}
 
 	return &cmd
 }
 
-func paramGet(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
-		log.Fatalf("Usage: get <path/to/parameter>")
+func paramGet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	ssmClient := client.SSM(config.Session())
+
+	if len(args) == 0 && console.Interactive() {
+		name, err := pickParam(ctx, ssmClient)
+		if err != nil {
+			return err
+		}
+		args = []string{name}
 	}
 
-	ssmClient := ssm.New(config.Session())
+	if len(args) != 1 {
+		return fmt.Errorf("%w: get <path/to/parameter>", errs.ErrUsage)
+	}
 
-	resp, err := ssmClient.GetParameter(&ssm.GetParameterInput{
+	resp, err := ssmClient.GetParameterWithContext(ctx, &ssm.GetParameterInput{
 		Name:           &args[0],
 		WithDecryption: aws.Bool(true),
 	})
 	if err != nil {
-		log.Fatalf("GetParameter err: %s", err)
+		return fmt.Errorf("GetParameter err: %w", err)
 	}
 
-	fmt.Printf("%s\n", *resp.Parameter.Value)
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "%s\n", *resp.Parameter.Value)
+
+	return nil
+}
+
+func pickParam(ctx context.Context, ssmClient ssmiface.SSMAPI) (string, error) {
+	var params []*ssm.ParameterMetadata
+	err := ssmClient.DescribeParametersPagesWithContext(ctx, &ssm.DescribeParametersInput{}, func(dpo *ssm.DescribeParametersOutput, b bool) bool {
+		params = append(params, dpo.Parameters...)
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("DescribeParameters err: %w", err)
+	}
+
+	rows := make([][]string, 0, len(params))
+	for _, pm := range params {
+		rows = append(rows, []string{*pm.Name, *pm.Type, aws.TimeValue(pm.LastModifiedDate).Format("2006-01-02")})
+	}
+
+	idx, err := console.PickRow("param", rows)
+	if err != nil {
+		return "", err
+	}
+	return *params[idx].Name, nil
 }
 
 func paramPutCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "put",
-		Short: "Set or create parameter value",
-		Run:   paramPut,
+		Use:               "put",
+		Short:             "Set or create parameter value",
+		RunE:              paramPut,
+		ValidArgsFunction: completion.Params(0),
 	}
 
 	cmd.Flags().StringVarP(&paramType, "type", "", "SecureString", "Param type (String, StringList, SecureString)")
@@ -110,51 +167,50 @@ func paramPutCommand() *cobra.Command {
 	return &cmd
 }
 
-func paramPut(cmd *cobra.Command, args []string) {
+func paramPut(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatalf("Usage: get <path/to/parameter> [<value>]")
+		return fmt.Errorf("%w: get <path/to/parameter> [<value>]", errs.ErrUsage)
 	}
 	name := args[0]
+	w := cmd.OutOrStdout()
 	var value string
 	if len(args) < 2 {
-		fmt.Print("Enter value: ")
+		fmt.Fprint(w, "Enter value: ")
 		var result string
-		fmt.Scanln(&result)
+		fmt.Fscanln(cmd.InOrStdin(), &result)
 
 		result = strings.TrimSpace(result)
 		if result == "" {
-			fmt.Fprintf(os.Stderr, "No value provided, not saving")
-			os.Exit(1)
+			return fmt.Errorf("%w: no value provided, not saving", errs.ErrAborted)
 		}
 		value = result
 	} else {
 		value = args[1]
 	}
 
-	ssmClient := ssm.New(config.Session())
+	ssmClient := client.SSM(config.Session())
 
-	resp, err := ssmClient.GetParameter(&ssm.GetParameterInput{
+	resp, err := ssmClient.GetParameterWithContext(ctx, &ssm.GetParameterInput{
 		Name:           &name,
 		WithDecryption: aws.Bool(true),
 	})
 
 	var (
-		create bool
-		oldVal string
+		create      bool
+		oldVal      string
+		auditOldVal string
 	)
 	if err != nil {
 		create = true
 		oldVal = "(create)"
+		auditOldVal = oldVal
 	} else {
 		oldVal = *resp.Parameter.Value
+		auditOldVal = auditValue(resp.Parameter.Type, oldVal)
 	}
 
-	fmt.Printf("param %s: %s => %s\n\n", name, oldVal, value)
-
-	ok := console.Confirm("Are you sure you want to make this change [yN]? ")
-	if !ok {
-		log.Fatalln("Aborting")
-	}
+	fmt.Fprintf(w, "param %s: %s => %s\n\n", name, oldVal, value)
 
 	overwrite := !create
 	input := ssm.PutParameterInput{
@@ -168,46 +224,63 @@ func paramPut(cmd *cobra.Command, args []string) {
 		input.Description = &paramDescr
 	}
 
-	_, err = ssmClient.PutParameter(&input)
+	if console.DryRun {
+		console.PrintDryRun("PutParameter", dryRunInput(input))
+		return nil
+	}
+
+	ok := console.Confirm(ctx, "Are you sure you want to make this change [yN]? ")
+	if !ok {
+		return errs.ErrAborted
+	}
+
+	_, err = ssmClient.PutParameterWithContext(ctx, &input)
 	if err != nil {
-		log.Fatalf("PutParameter err: %s", err)
+		return fmt.Errorf("PutParameter err: %w", err)
 	}
+
+	audit.Record(audit.Entry{
+		Action:   "param put",
+		Resource: name,
+		OldValue: auditOldVal,
+		NewValue: auditValue(&paramType, value),
+	})
+
+	return nil
 }
 
 func paramCpCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "cp",
-		Short: "Copy param from old to new path",
-		Run:   paramCp,
+		Use:               "cp",
+		Short:             "Copy param from old to new path",
+		RunE:              paramCp,
+		ValidArgsFunction: completion.Params(0, 1),
 	}
 
 	return &cmd
 }
 
-func paramCp(cmd *cobra.Command, args []string) {
+func paramCp(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) != 2 {
-		log.Fatalf("Usage: cp <old/path> <new/path>")
+		return fmt.Errorf("%w: cp <old/path> <new/path>", errs.ErrUsage)
 	}
 
-	ssmClient := ssm.New(config.Session())
+	ssmClient := client.SSM(config.Session())
 
 	oldPath := args[0]
 	newPath := args[1]
 
-	resp, err := ssmClient.GetParameter(&ssm.GetParameterInput{
+	resp, err := ssmClient.GetParameterWithContext(ctx, &ssm.GetParameterInput{
 		Name:           &oldPath,
 		WithDecryption: aws.Bool(true),
 	})
 	if err != nil {
-		log.Fatalf("GetParameter err: %s", err)
+		return fmt.Errorf("GetParameter err: %w", err)
 	}
 
-	fmt.Printf("param %s => %s (%s)\n\n", oldPath, newPath, *resp.Parameter.Value)
-
-	ok := console.Confirm("Are you sure you want to make this change [yN]? ")
-	if !ok {
-		log.Fatalln("Aborting")
-	}
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "param %s => %s (%s)\n\n", oldPath, newPath, *resp.Parameter.Value)
 
 	input := ssm.PutParameterInput{
 		Name:      &newPath,
@@ -216,52 +289,106 @@ func paramCp(cmd *cobra.Command, args []string) {
 		Type:      resp.Parameter.Type,
 	}
 
-	_, err = ssmClient.PutParameter(&input)
+	if console.DryRun {
+		console.PrintDryRun("PutParameter", dryRunInput(input))
+		return nil
+	}
+
+	ok := console.Confirm(ctx, "Are you sure you want to make this change [yN]? ")
+	if !ok {
+		return errs.ErrAborted
+	}
+
+	_, err = ssmClient.PutParameterWithContext(ctx, &input)
 	if err != nil {
-		log.Fatalf("PutParameter err: %s", err)
+		return fmt.Errorf("PutParameter err: %w", err)
 	}
+
+	audit.Record(audit.Entry{
+		Action:   "param cp",
+		Resource: newPath,
+		OldValue: oldPath,
+		NewValue: auditValue(resp.Parameter.Type, *resp.Parameter.Value),
+	})
+
+	return nil
 }
 
 func paramRmCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "rm",
-		Short: "Delete param at path",
-		Run:   paramRm,
+		Use:               "rm",
+		Short:             "Delete param at path",
+		RunE:              paramRm,
+		ValidArgsFunction: completion.Params(0),
 	}
 
 	return &cmd
 }
 
-func paramRm(cmd *cobra.Command, args []string) {
+func paramRm(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) != 1 {
-		log.Fatalf("Usage: rm <some/path/to/delete>")
+		return fmt.Errorf("%w: rm <some/path/to/delete>", errs.ErrUsage)
 	}
 
-	ssmClient := ssm.New(config.Session())
+	ssmClient := client.SSM(config.Session())
 
 	path := args[0]
 
-	resp, err := ssmClient.GetParameter(&ssm.GetParameterInput{
+	resp, err := ssmClient.GetParameterWithContext(ctx, &ssm.GetParameterInput{
 		Name:           &path,
 		WithDecryption: aws.Bool(true),
 	})
 	if err != nil {
-		log.Fatalf("GetParameter err: %s", err)
+		return fmt.Errorf("GetParameter err: %w", err)
 	}
 
-	fmt.Printf("param %s (%s) => *deleted\n\n", path, *resp.Parameter.Value)
-
-	ok := console.Confirm("Are you sure you want to make this change [yN]? ")
-	if !ok {
-		log.Fatalln("Aborting")
-	}
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "param %s (%s) => *deleted\n\n", path, *resp.Parameter.Value)
 
 	input := ssm.DeleteParameterInput{
 		Name: &path,
 	}
 
-	_, err = ssmClient.DeleteParameter(&input)
+	if console.DryRun {
+		console.PrintDryRun("DeleteParameter", &input)
+		return nil
+	}
+
+	ok := console.Confirm(ctx, "Are you sure you want to make this change [yN]? ")
+	if !ok {
+		return errs.ErrAborted
+	}
+
+	_, err = ssmClient.DeleteParameterWithContext(ctx, &input)
 	if err != nil {
-		log.Fatalf("DeleteParameter err: %s", err)
+		return fmt.Errorf("DeleteParameter err: %w", err)
+	}
+
+	audit.Record(audit.Entry{
+		Action:   "param rm",
+		Resource: path,
+		OldValue: auditValue(resp.Parameter.Type, *resp.Parameter.Value),
+		NewValue: "(deleted)",
+	})
+
+	return nil
+}
+
+// dryRunInput masks a SecureString's value in a copy of input for
+// --dry-run.
+func dryRunInput(input ssm.PutParameterInput) *ssm.PutParameterInput {
+	if aws.StringValue(input.Type) == ssm.ParameterTypeSecureString {
+		input.Value = aws.String(audit.Redacted)
+	}
+	return &input
+}
+
+// auditValue returns the value to record in the audit log for a
+// parameter of type typ. SecureString values are not recorded.
+func auditValue(typ *string, value string) string {
+	if aws.StringValue(typ) == ssm.ParameterTypeSecureString {
+		return audit.Redacted
 	}
+	return value
 }
@@ -0,0 +1,159 @@
+// Package regions runs describe calls across several AWS regions for the
+// --all-regions and --regions flags.
+package regions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/spf13/cobra"
+)
+
+// Concurrency is the maximum number of regions queried at once.
+var Concurrency = 8
+
+var (
+	allRegions bool
+	regionList []string
+)
+
+// AddFlags adds --all-regions and --regions to cmd.
+func AddFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&allRegions, "all-regions", "", false, "Query every enabled region")
+	cmd.Flags().StringSliceVarP(&regionList, "regions", "", nil, "Comma separated list of regions to query")
+}
+
+// Enabled reports whether the command is fanning out to multiple regions.
+func Enabled() bool {
+	return allRegions || len(regionList) > 0
+}
+
+// List returns the regions to query: every region enabled for the account
+// with --all-regions, the --regions values, or just the current region.
+func List(ctx context.Context) ([]string, error) {
+	if len(regionList) > 0 {
+		return regionList, nil
+	}
+	if !allRegions {
+		return []string{config.ResolvedRegion()}, nil
+	}
+
+	resp, err := client.EC2(config.Session()).DescribeRegionsWithContext(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("DescribeRegions err: %w", err)
+	}
+	names := make([]string, 0, len(resp.Regions))
+	for _, r := range resp.Regions {
+		names = append(names, aws.StringValue(r.RegionName))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Each calls fn for each region in List with a session for that region,
+// running up to Concurrency calls at once. When fanning out, a failure in
+// one region doesn't stop the others; all the errors are returned together
+// as an *errs.PartialFailureError, unless every region failed.
+//
+// fn is passed a printer to add its records to. When fanning out each
+// region gets a fork of out, and the forks are joined into out in region
+// order so the output doesn't depend on which region answered first. out,
+// if non-nil, is also set to show the region column.
+func Each(ctx context.Context, out *output.Printer, fn func(sess *session.Session, region string, out *output.Printer) error) error {
+	if !Enabled() {
+		sess := config.Session()
+		return fn(sess, aws.StringValue(sess.Config.Region), out)
+	}
+
+	if out != nil {
+		out.Show = append(out.Show, "region")
+	}
+
+	names, err := List(ctx)
+	if err != nil {
+		return err
+	}
+
+	base := config.Session()
+
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, Concurrency)
+		forks   = make([]*output.Printer, len(names))
+		results = make([]error, len(names))
+		started int
+	)
+	for i, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		i, name := i, name
+		if out != nil {
+			forks[i] = out.Fork()
+		}
+		started++
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			sess := base.Copy(&aws.Config{Region: aws.String(name)})
+			if err := fn(sess, name, forks[i]); err != nil {
+				results[i] = fmt.Errorf("%s: %w", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errList []error
+	for i := 0; i < started; i++ {
+		if out != nil {
+			if err := out.Join(forks[i]); err != nil {
+				return err
+			}
+		}
+		if results[i] != nil {
+			errList = append(errList, results[i])
+		}
+	}
+
+	if len(errList) > 0 && len(errList) == started {
+		// nothing succeeded, so this isn't a partial failure
+		return errors.Join(errList...)
+	}
+	return errs.PartialFailure(errList)
+}
+
+// Detail adds a Region field to detail for structured output when fanning
+// out, so records from different regions can be told apart.
+func Detail(region string, detail interface{}) interface{} {
+	if !Enabled() {
+		return detail
+	}
+
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return detail
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return detail
+	}
+	m["Region"] = region
+	return m
+}
@@ -0,0 +1,277 @@
+// Package replay records AWS API traffic to fixture files and serves it
+// back without touching the network. It is used by the global --record and
+// --replay flags.
+//
+// Each request is keyed by its method, host, path, query, X-Amz-Target
+// header and body, less parameters like AssumeRole's RoleSessionName that
+// vary by user. Identical requests made more than once in a run (e.g.
+// when polling) are numbered so each gets its own response; on replay the
+// last recorded response is reused once the numbered ones run out.
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type fixture struct {
+	Request  fixtureRequest  `json:"request"`
+	Response fixtureResponse `json:"response"`
+}
+
+type fixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Target string `json:"target,omitempty"`
+	body
+}
+
+type fixtureResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	body
+}
+
+// body holds text bodies as-is so fixtures are easy to read and edit,
+// and anything else base64 encoded.
+type body struct {
+	Body       string `json:"body,omitempty"`
+	BodyBase64 string `json:"body_base64,omitempty"`
+}
+
+func newBody(b []byte) body {
+	if utf8.Valid(b) {
+		return body{Body: string(b)}
+	}
+	return body{BodyBase64: base64.StdEncoding.EncodeToString(b)}
+}
+
+func (b body) bytes() ([]byte, error) {
+	if b.BodyBase64 != "" {
+		return base64.StdEncoding.DecodeString(b.BodyBase64)
+	}
+	return []byte(b.Body), nil
+}
+
+// counter numbers repeated requests for the same key.
+type counter struct {
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+func (c *counter) next(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen == nil {
+		c.seen = make(map[string]int)
+	}
+	n := c.seen[key]
+	c.seen[key]++
+	return n
+}
+
+// Recorder is an http.RoundTripper that passes requests to Transport and
+// saves each request and response to Dir.
+type Recorder struct {
+	Dir       string
+	Transport http.RoundTripper
+
+	counter counter
+}
+
+func NewRecorder(dir string, transport http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		Dir:       dir,
+		Transport: transport,
+	}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	key := requestKey(req, reqBody)
+	f := fixture{
+		Request: fixtureRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Target: req.Header.Get("X-Amz-Target"),
+			body:   newBody(reqBody),
+		},
+		Response: fixtureResponse{
+			Status: resp.StatusCode,
+			Header: resp.Header,
+			body:   newBody(respBody),
+		},
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Join(r.Dir, fmt.Sprintf("%s-%d.json", key, r.counter.next(key)))
+	if err := os.WriteFile(name, data, 0600); err != nil {
+		return nil, fmt.Errorf("record %s: %w", name, err)
+	}
+
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper that serves responses from fixtures
+// in Dir written by a Recorder. It never uses the network.
+type Replayer struct {
+	Dir string
+
+	counter counter
+}
+
+func NewReplayer(dir string) (*Replayer, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	return &Replayer{Dir: dir}, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	key := requestKey(req, reqBody)
+	n := r.counter.next(key)
+
+	var data []byte
+	for ; n >= 0; n-- {
+		data, err = os.ReadFile(filepath.Join(r.Dir, fmt.Sprintf("%s-%d.json", key, n)))
+		if err == nil || !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replay: no fixture %s for %s %s: %w", key, req.Method, req.URL, err)
+	}
+
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("replay: parse fixture %s: %w", key, err)
+	}
+	respBody, err := f.Response.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("replay: decode fixture %s body: %w", key, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.Status, http.StatusText(f.Response.Status)),
+		StatusCode:    f.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Response.Header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads and replaces *rc so it can be read again.
+func readBody(rc *io.ReadCloser) ([]byte, error) {
+	if *rc == nil || *rc == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(*rc)
+	(*rc).Close()
+	if err != nil {
+		return nil, err
+	}
+	*rc = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.]+`)
+
+// requestKey names the fixture for a request: a readable service and
+// operation prefix followed by a hash of everything that identifies the
+// request.
+func requestKey(req *http.Request, body []byte) string {
+	target := req.Header.Get("X-Amz-Target")
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s\n", req.Method, req.URL.Host, req.URL.Path, sortedQuery(req.URL), target)
+	h.Write(stableBody(body))
+	sum := hex.EncodeToString(h.Sum(nil))[:16]
+
+	service := strings.Split(req.URL.Host, ".")[0]
+	op := target
+	if i := strings.LastIndex(op, "."); i >= 0 {
+		op = op[i+1:]
+	}
+	if op == "" {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			op = form.Get("Action")
+		}
+	}
+	if op == "" {
+		op = req.Method
+	}
+
+	return unsafeChars.ReplaceAllString(service+"_"+op, "_") + "_" + sum
+}
+
+// volatileParams are form parameters that differ between otherwise
+// identical requests, e.g. by user or host, and so aren't part of the key.
+var volatileParams = []string{"RoleSessionName"}
+
+// stableBody returns body without volatileParams if it's a query protocol
+// form, or body unchanged.
+func stableBody(body []byte) []byte {
+	form, err := url.ParseQuery(string(body))
+	if err != nil || form.Get("Action") == "" {
+		return body
+	}
+	var found bool
+	for _, p := range volatileParams {
+		if _, ok := form[p]; ok {
+			form.Del(p)
+			found = true
+		}
+	}
+	if !found {
+		return body
+	}
+	return []byte(form.Encode())
+}
+
+func sortedQuery(u *url.URL) string {
+	// Encode sorts by key
+	return u.Query().Encode()
+}
@@ -1,19 +1,18 @@
 package route53
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
 var (
-	jsonOutput bool
 	filterZone string
 )
 
@@ -33,26 +32,25 @@ func route53ListCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "List Records",
-		Run:   route53ListRecords,
+		RunE:  route53ListRecords,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
 	cmd.Flags().StringVarP(&filterZone, "zone", "", "", "Filter by zone name")
 
 	return &cmd
 }
 
-func route53ListRecords(cmd *cobra.Command, args []string) {
-	svc := route53.New(config.Session())
+func route53ListRecords(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.Route53(config.Session())
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	out := output.New(cmd.OutOrStdout())
 
 	if filterZone != "" && !strings.HasSuffix(filterZone, ".") {
 		filterZone += "."
 	}
 
-	err := svc.ListHostedZonesPages(nil, func(zoneOut *route53.ListHostedZonesOutput, more bool) bool {
+	err := svc.ListHostedZonesPagesWithContext(ctx, nil, func(zoneOut *route53.ListHostedZonesOutput, more bool) bool {
 		for _, zone := range zoneOut.HostedZones {
 
 			if filterZone != "" && *zone.Name != filterZone {
@@ -62,36 +60,24 @@ func route53ListRecords(cmd *cobra.Command, args []string) {
 			listRRS := route53.ListResourceRecordSetsInput{
 				HostedZoneId: zone.Id,
 			}
-			svc.ListResourceRecordSetsPages(&listRRS, func(recOut *route53.ListResourceRecordSetsOutput, more bool) bool {
+			svc.ListResourceRecordSetsPagesWithContext(ctx, &listRRS, func(recOut *route53.ListResourceRecordSetsOutput, more bool) bool {
 				for _, rrs := range recOut.ResourceRecordSets {
-					if jsonOutput {
-						jsonOut.Encode(rrs)
-					} else {
-						var (
-							recordName string
-							recordType string
-							ttl        int64
-						)
-
-						if rrs.Name != nil {
-							recordName = *rrs.Name
-						}
-
-						if rrs.Type != nil {
-							recordType = *rrs.Type
-						}
-
-						if rrs.TTL != nil {
-							ttl = *rrs.TTL
-						}
-
-						for _, val := range rrs.ResourceRecords {
-							if val.Value != nil {
-								fmt.Printf("%-60.60s %5.5s %6d %s\n", recordName, recordType, ttl, *val.Value)
-							}
+					row := recordRow{
+						Name: aws.StringValue(rrs.Name),
+						Type: aws.StringValue(rrs.Type),
+						TTL:  aws.Int64Value(rrs.TTL),
+					}
 
+					for _, val := range rrs.ResourceRecords {
+						if val.Value != nil {
+							row.Values = append(row.Values, *val.Value)
 						}
 					}
+					if rrs.AliasTarget != nil {
+						row.Values = append(row.Values, "ALIAS "+aws.StringValue(rrs.AliasTarget.DNSName))
+					}
+
+					out.AddDetail(row, rrs)
 				}
 				return true
 			})
@@ -101,42 +87,56 @@ func route53ListRecords(cmd *cobra.Command, args []string) {
 	})
 
 	if err != nil {
-		log.Fatalf("ListHostedZones error: %s", err)
+		return fmt.Errorf("ListHostedZones error: %w", err)
 	}
+
+	return out.Flush()
+}
+
+type recordRow struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	TTL    int64    `json:"ttl"`
+	Values []string `json:"values"`
 }
 
 func route53ListZonesCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "zones",
 		Short: "List Zones",
-		Run:   route53ListZones,
+		RunE:  route53ListZones,
 	}
 
-	cmd.Flags().BoolVarP(&jsonOutput, "json", "", false, "Show raw json ouput")
-
 	return &cmd
 }
 
-func route53ListZones(cmd *cobra.Command, args []string) {
-	svc := route53.New(config.Session())
+func route53ListZones(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.Route53(config.Session())
 
-	jsonOut := json.NewEncoder(os.Stdout)
-	jsonOut.SetIndent("", "  ")
+	out := output.New(cmd.OutOrStdout())
 
-	err := svc.ListHostedZonesPages(nil, func(zoneOut *route53.ListHostedZonesOutput, more bool) bool {
+	err := svc.ListHostedZonesPagesWithContext(ctx, nil, func(zoneOut *route53.ListHostedZonesOutput, more bool) bool {
 		for _, zone := range zoneOut.HostedZones {
-
-			if jsonOutput {
-				jsonOut.Encode(zone)
-			} else {
-				fmt.Printf("%-40.40s %6.6d %s\n", *zone.Id, *zone.ResourceRecordSetCount, *zone.Name)
-			}
+			out.AddDetail(zoneRow{
+				ID:      *zone.Id,
+				Records: *zone.ResourceRecordSetCount,
+				Name:    *zone.Name,
+			}, zone)
 		}
 
 		return true
 	})
 
 	if err != nil {
-		log.Fatalf("ListHostedZones error: %s", err)
+		return fmt.Errorf("ListHostedZones error: %w", err)
 	}
+
+	return out.Flush()
+}
+
+type zoneRow struct {
+	ID      string `json:"id"`
+	Records int64  `json:"records"`
+	Name    string `json:"name"`
 }
@@ -1,18 +1,23 @@
 package s3
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
-	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/mitchellh/mapstructure"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
 	"github.com/spf13/cobra"
 )
 
@@ -31,9 +36,10 @@ func Command() *cobra.Command {
 
 func catCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "cat <[s3://]bucket/path/to/object>",
-		Short: "Cat object",
-		Run:   catAction,
+		Use:               "cat <[s3://]bucket/path/to/object>",
+		Short:             "Cat object",
+		RunE:              catAction,
+		ValidArgsFunction: completion.S3Paths(0),
 	}
 	return &cmd
 }
@@ -48,59 +54,64 @@ func bucketPath(raw string) (string, string) {
 	return bucket, path
 }
 
-func catAction(cmd *cobra.Command, args []string) {
+func catAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatalf("Usage: cat <[s3://]bucket/path/to/obj")
+		return fmt.Errorf("%w: cat <[s3://]bucket/path/to/obj", errs.ErrUsage)
 	}
 
 	bucket, path := bucketPath(args[0])
 
-	svc := s3.New(config.Session())
+	svc := client.S3(config.Session())
 
-	obj, err := svc.GetObject(&s3.GetObjectInput{
+	obj, err := svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: &bucket,
 		Key:    &path,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	_, err = io.Copy(os.Stdout, obj.Body)
+	_, err = io.Copy(cmd.OutOrStdout(), obj.Body)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+
+	return nil
 }
 
 func headCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "head <[s3://]bucket/path/to/object>",
-		Short: "Head object",
-		Run:   headAction,
+		Use:               "head <[s3://]bucket/path/to/object>",
+		Short:             "Head object",
+		RunE:              headAction,
+		ValidArgsFunction: completion.S3Paths(0),
 	}
 	return &cmd
 }
 
-func headAction(cmd *cobra.Command, args []string) {
+func headAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatalf("Usage: head <[s3://]bucket/path/to/obj")
+		return fmt.Errorf("%w: head <[s3://]bucket/path/to/obj", errs.ErrUsage)
 	}
 
 	bucket, path := bucketPath(args[0])
 
-	svc := s3.New(config.Session())
+	svc := client.S3(config.Session())
 
-	obj, err := svc.HeadObject(&s3.HeadObjectInput{
+	obj, err := svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
 		Bucket: &bucket,
 		Key:    &path,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	m := make(map[string]interface{})
 	err = mapstructure.Decode(obj, &m)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	for k, v := range m {
 		if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
@@ -110,10 +121,12 @@ func headAction(cmd *cobra.Command, args []string) {
 
 	out, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	fmt.Printf("%s\n", out)
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", out)
+
+	return nil
 }
 
 var (
@@ -123,9 +136,10 @@ var (
 
 func lsCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "ls <[s3://]bucket/path/prefix>",
-		Short: "List objects",
-		Run:   lsAction,
+		Use:               "ls <[s3://]bucket/path/prefix>",
+		Short:             "List objects",
+		RunE:              lsAction,
+		ValidArgsFunction: completion.S3Paths(0),
 	}
 
 	cmd.Flags().BoolVarP(&recurseFlag, "recurse", "r", false, "Recurse into directories")
@@ -134,14 +148,15 @@ func lsCommand() *cobra.Command {
 	return &cmd
 }
 
-func lsAction(cmd *cobra.Command, args []string) {
+func lsAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatalf("Usage: ls <[s3://]bucket/path/prefix>")
+		return fmt.Errorf("%w: ls <[s3://]bucket/path/prefix>", errs.ErrUsage)
 	}
 
 	bucket, prefix := bucketPath(args[0])
 
-	svc := s3.New(config.Session())
+	svc := client.S3(config.Session())
 
 	input := &s3.ListObjectsV2Input{
 		Bucket:    &bucket,
@@ -153,16 +168,29 @@ func lsAction(cmd *cobra.Command, args []string) {
 		maxDepth = 1
 	}
 
-	listObjectsWithDepth(svc, input, prefix, 1, maxDepth)
+	out := output.New(cmd.OutOrStdout())
+	if err := listObjectsWithDepth(ctx, svc, out, input, prefix, 1, maxDepth); err != nil {
+		return err
+	}
+	return out.Flush()
 }
 
-func listObjectsWithDepth(svc *s3.S3, input *s3.ListObjectsV2Input, basePrefix string, currentDepth, maxDepth int) {
-	if maxDepth == 0 || currentDepth <= maxDepth {
+type objectRow struct {
+	LastModified time.Time `json:"last_modified"`
+	Size         *int64    `json:"size"`
+	Name         string    `json:"name"`
+}
 
-		err := svc.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+func listObjectsWithDepth(ctx context.Context, svc s3iface.S3API, out *output.Printer, input *s3.ListObjectsV2Input, basePrefix string, currentDepth, maxDepth int) error {
+	if maxDepth == 0 || currentDepth <= maxDepth {
+		var listErr error
+		err := svc.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
 			for _, obj := range page.Contents {
-				objName := strings.TrimPrefix(*obj.Key, basePrefix)
-				fmt.Printf("%s %15d %s\n", obj.LastModified.Format("2006/01/02 15:04"), *obj.Size, objName)
+				out.AddDetail(objectRow{
+					LastModified: aws.TimeValue(obj.LastModified),
+					Size:         obj.Size,
+					Name:         strings.TrimPrefix(*obj.Key, basePrefix),
+				}, obj)
 			}
 
 			var directories []*s3.CommonPrefix
@@ -172,22 +200,30 @@ func listObjectsWithDepth(svc *s3.S3, input *s3.ListObjectsV2Input, basePrefix s
 			}
 
 			for _, dir := range directories {
-				dirName := strings.TrimPrefix(*dir.Prefix, basePrefix)
-				fmt.Printf("  DIR %s\n", dirName)
+				out.AddDetail(objectRow{
+					Name: strings.TrimPrefix(*dir.Prefix, basePrefix),
+				}, dir)
 				newInput := &s3.ListObjectsV2Input{
 					Bucket:    input.Bucket,
 					Prefix:    dir.Prefix,
 					Delimiter: aws.String("/"),
 				}
 
-				listObjectsWithDepth(svc, newInput, basePrefix, currentDepth+1, maxDepth)
+				listErr = listObjectsWithDepth(ctx, svc, out, newInput, basePrefix, currentDepth+1, maxDepth)
+				if listErr != nil {
+					return false
+				}
 			}
 
 			return true
 		})
 
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		if listErr != nil {
+			return listErr
 		}
 	}
+	return nil
 }
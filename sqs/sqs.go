@@ -1,14 +1,22 @@
 package sqs
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/psanford/aws-buddy/audit"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/completion"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/errs"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/psanford/aws-buddy/regions"
 	"github.com/spf13/cobra"
 )
 
@@ -29,83 +37,147 @@ func listCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "list",
 		Short: "List SQS queues",
-		Run:   listAction,
+		RunE:  listAction,
 	}
+
+	regions.AddFlags(&cmd)
+
 	return &cmd
 }
 
-func listAction(cmd *cobra.Command, args []string) {
-	svc := sqs.New(config.Session())
+func listAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := output.New(cmd.OutOrStdout())
 
-	result, err := svc.ListQueues(&sqs.ListQueuesInput{})
-	if err != nil {
-		log.Fatal(err)
-	}
+	listErr := regions.Each(ctx, out, func(sess *session.Session, region string, out *output.Printer) error {
+		svc := client.SQS(sess)
 
-	for _, url := range result.QueueUrls {
-		attrs, err := svc.GetQueueAttributes(&sqs.GetQueueAttributesInput{
-			AttributeNames: aws.StringSlice([]string{"All"}),
-			QueueUrl:       url,
+		// SQS only pages when MaxResults is set
+		var urls []*string
+		err := svc.ListQueuesPagesWithContext(ctx, &sqs.ListQueuesInput{
+			MaxResults: aws.Int64(1000),
+		}, func(resp *sqs.ListQueuesOutput, lastPage bool) bool {
+			urls = append(urls, resp.QueueUrls...)
+			return true
 		})
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		arn := attrs.Attributes["QueueArn"]
-		arnParts := strings.Split(*arn, ":")
-		name := arnParts[len(arnParts)-1]
-		messages := attrs.Attributes["ApproximateNumberOfMessages"]
-		messagesPending := attrs.Attributes["ApproximateNumberOfMessagesNotVisible"]
-		messagesDelayed := attrs.Attributes["ApproximateNumberOfMessagesDelayed"]
-
-		fmt.Printf("========[ %s ]===================\n", name)
-		fmt.Printf("arn              : %s\n", *arn)
-		fmt.Printf("url              : %s\n", *url)
-		fmt.Printf("messages         : %s\n", *messages)
-		fmt.Printf("pending messages : %s\n", *messagesPending)
-		fmt.Printf("delayed messages : %s\n", *messagesDelayed)
+
+		for _, url := range urls {
+			attrs, err := svc.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+				AttributeNames: aws.StringSlice([]string{"All"}),
+				QueueUrl:       url,
+			})
+			if err != nil {
+				return err
+			}
+			arn := attrs.Attributes["QueueArn"]
+			arnParts := strings.Split(*arn, ":")
+			name := arnParts[len(arnParts)-1]
+
+			out.AddDetail(queueRow{
+				Region:   region,
+				Name:     name,
+				Messages: aws.StringValue(attrs.Attributes["ApproximateNumberOfMessages"]),
+				Pending:  aws.StringValue(attrs.Attributes["ApproximateNumberOfMessagesNotVisible"]),
+				Delayed:  aws.StringValue(attrs.Attributes["ApproximateNumberOfMessagesDelayed"]),
+				URL:      *url,
+			}, regions.Detail(region, queueDetail{
+				QueueUrl:   url,
+				Attributes: attrs.Attributes,
+			}))
+		}
+		return nil
+	})
+
+	if err := out.Flush(); err != nil {
+		return err
 	}
+	return listErr
+}
+
+type queueRow struct {
+	Region   string `json:"region" output:"hidden"`
+	Name     string `json:"name"`
+	Messages string `json:"messages"`
+	Pending  string `json:"pending"`
+	Delayed  string `json:"delayed"`
+	URL      string `json:"url"`
+}
+
+type queueDetail struct {
+	QueueUrl   *string
+	Attributes map[string]*string
 }
 
 func peekCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "peek <queue-url>",
-		Short: "Peek at messages in an SQS queue",
-		Run:   peekAction,
+		Use:               "peek <queue-url>",
+		Short:             "Peek at messages in an SQS queue",
+		RunE:              peekAction,
+		ValidArgsFunction: completion.Queues(0),
 	}
 	return &cmd
 }
 
-func peekAction(cmd *cobra.Command, args []string) {
+func peekAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	svc := client.SQS(config.Session())
+
+	if len(args) == 0 && console.Interactive() {
+		var urls []string
+		err := svc.ListQueuesPagesWithContext(ctx, &sqs.ListQueuesInput{
+			MaxResults: aws.Int64(1000),
+		}, func(resp *sqs.ListQueuesOutput, lastPage bool) bool {
+			urls = append(urls, aws.StringValueSlice(resp.QueueUrls)...)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		idx, err := console.Pick("queue", urls)
+		if err != nil {
+			return err
+		}
+		args = []string{urls[idx]}
+	}
+
 	if len(args) < 1 {
-		log.Fatalf("Usage: peek <queue-url>")
+		return fmt.Errorf("%w: peek <queue-url>", errs.ErrUsage)
 	}
 
 	queueURL := args[0]
 
-	svc := sqs.New(config.Session())
-
-	result, err := svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+	result, err := svc.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
 		QueueUrl:            aws.String(queueURL),
 		MaxNumberOfMessages: aws.Int64(1),
 		VisibilityTimeout:   aws.Int64(0),
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if len(result.Messages) == 0 {
 		log.Println("No messages in the queue")
-		return
+		return nil
 	}
 
+	out := output.New(cmd.OutOrStdout())
+	out.Default = output.JSON
 	for _, message := range result.Messages {
-		out, err := json.MarshalIndent(message, "", "  ")
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		fmt.Printf("%s\n", out)
+		out.AddDetail(messageRow{
+			MessageID: aws.StringValue(message.MessageId),
+			Body:      aws.StringValue(message.Body),
+		}, message)
 	}
+
+	return out.Flush()
+}
+
+type messageRow struct {
+	MessageID string `json:"message_id"`
+	Body      string `json:"body"`
 }
 
 var (
@@ -114,44 +186,58 @@ var (
 
 func consumeCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "consume <queue-url>",
-		Short: "Consume (receive and delete) messages from an SQS queue",
-		Run:   consumeAction,
+		Use:               "consume <queue-url>",
+		Short:             "Consume (receive and delete) messages from an SQS queue",
+		RunE:              consumeAction,
+		ValidArgsFunction: completion.Queues(0),
 	}
 	cmd.Flags().IntVarP(&countFlag, "count", "n", 1, "Number of messages to consume")
 	return &cmd
 }
 
-func consumeAction(cmd *cobra.Command, args []string) {
+func consumeAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatalf("Usage: consume <queue-url>")
+		return fmt.Errorf("%w: consume <queue-url>", errs.ErrUsage)
 	}
 
 	queueURL := args[0]
 
-	svc := sqs.New(config.Session())
+	svc := client.SQS(config.Session())
+	w := cmd.OutOrStdout()
+
+	// messages that have been printed are deleted even if interrupted
+	deleteCtx := context.WithoutCancel(ctx)
 
 	consumed := 0
-	for consumed < countFlag {
+	for consumed < countFlag && ctx.Err() == nil {
 		remaining := countFlag - consumed
 		maxMessages := int64(10)
 		if remaining < 10 {
 			maxMessages = int64(remaining)
 		}
 
-		result, err := svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+		input := &sqs.ReceiveMessageInput{
 			QueueUrl:              aws.String(queueURL),
 			MaxNumberOfMessages:   aws.Int64(maxMessages),
 			WaitTimeSeconds:       aws.Int64(20),
 			MessageAttributeNames: aws.StringSlice([]string{"All"}),
 			AttributeNames:        aws.StringSlice([]string{"All"}),
-		})
-		if err != nil {
-			log.Fatalf("Error receiving messages: %v", err)
+		}
+		if console.DryRun {
+			// leave the messages visible to other consumers
+			input.VisibilityTimeout = aws.Int64(0)
+		}
+
+		result, err := svc.ReceiveMessageWithContext(ctx, input)
+		if err != nil && ctx.Err() != nil {
+			break
+		} else if err != nil {
+			return fmt.Errorf("Error receiving messages: %w", err)
 		}
 
 		if len(result.Messages) == 0 {
-			fmt.Printf("No more messages available. Consumed %d message(s).\n", consumed)
+			fmt.Fprintf(w, "No more messages available. Consumed %d message(s).\n", consumed)
 			break
 		}
 
@@ -160,12 +246,19 @@ func consumeAction(cmd *cobra.Command, args []string) {
 				break
 			}
 
-			fmt.Printf("%s\n", *message.Body)
+			fmt.Fprintf(w, "%s\n", *message.Body)
 
-			_, err := svc.DeleteMessage(&sqs.DeleteMessageInput{
+			deleteInput := &sqs.DeleteMessageInput{
 				QueueUrl:      aws.String(queueURL),
 				ReceiptHandle: message.ReceiptHandle,
-			})
+			}
+			if console.DryRun {
+				console.PrintDryRun("DeleteMessage", deleteInput)
+				consumed++
+				continue
+			}
+
+			_, err := svc.DeleteMessageWithContext(deleteCtx, deleteInput)
 			if err != nil {
 				log.Printf("Error deleting message %s: %v", *message.MessageId, err)
 			} else {
@@ -174,7 +267,22 @@ func consumeAction(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if consumed > 0 && !console.DryRun {
+		audit.Record(audit.Entry{
+			Action:   "sqs consume",
+			Resource: queueURL,
+			NewValue: fmt.Sprintf("%d message(s) deleted", consumed),
+		})
+	}
+
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintf(w, "Interrupted. Consumed %d message(s).\n", consumed)
+		return err
+	}
+
 	if consumed == countFlag {
-		fmt.Printf("Successfully consumed %d message(s).\n", consumed)
+		fmt.Fprintf(w, "Successfully consumed %d message(s).\n", consumed)
 	}
+
+	return nil
 }
@@ -14,7 +14,10 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/textract"
+	"github.com/psanford/aws-buddy/client"
 	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/console"
+	"github.com/psanford/aws-buddy/errs"
 	"github.com/spf13/cobra"
 	"golang.org/x/tools/txtar"
 )
@@ -36,7 +39,7 @@ func analyzeDocCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "analyze <file>",
 		Short: "Analyze document",
-		Run:   analyzeDocAction,
+		RunE:  analyzeDocAction,
 	}
 
 	cmd.Flags().StringVarP(&bucket, "bucket", "b", "", "S3 bucket used for storage")
@@ -44,37 +47,32 @@ func analyzeDocCommand() *cobra.Command {
 	return &cmd
 }
 
-func analyzeDocAction(cmd *cobra.Command, args []string) {
+func analyzeDocAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if len(args) < 1 {
-		log.Fatalf("Usage: analyze <file>")
+		return fmt.Errorf("%w: analyze <file>", errs.ErrUsage)
 	}
 
 	if bucket == "" {
-		log.Fatalf("--bucket is required")
+		return fmt.Errorf("%w: --bucket is required", errs.ErrUsage)
 	}
 
 	var aggregatedResult textract.AnalyzeDocumentOutput
 
 	content, err := ioutil.ReadFile(args[0])
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	s3svc := s3.New(config.Session())
+	s3svc := client.S3(config.Session())
 	srcPath := fmt.Sprintf("input/%s", filepath.Base(args[0]))
 	resultPath := fmt.Sprintf("output/%s", filepath.Base(args[0]))
-	_, err = s3svc.PutObject(&s3.PutObjectInput{
+	putInput := &s3.PutObjectInput{
 		Bucket: &bucket,
 		Key:    &srcPath,
 		Body:   bytes.NewReader(content),
-	})
-	if err != nil {
-		log.Fatalf("put object err: %s", err)
 	}
-
-	svc := textract.New(config.Session())
-
-	startResult, err := svc.StartDocumentAnalysis(&textract.StartDocumentAnalysisInput{
+	startInput := &textract.StartDocumentAnalysisInput{
 		DocumentLocation: &textract.DocumentLocation{
 			S3Object: &textract.S3Object{
 				Bucket: &bucket,
@@ -86,23 +84,41 @@ func analyzeDocAction(cmd *cobra.Command, args []string) {
 			S3Prefix: &resultPath,
 		},
 		FeatureTypes: aws.StringSlice([]string{"TABLES"}),
-	})
+	}
+
+	if console.DryRun {
+		console.PrintDryRun("PutObject", putInput)
+		console.PrintDryRun("StartDocumentAnalysis", startInput)
+		return nil
+	}
+
+	_, err = s3svc.PutObjectWithContext(ctx, putInput)
+	if err != nil {
+		return fmt.Errorf("put object err: %w", err)
+	}
+
+	svc := client.Textract(config.Session())
+
+	startResult, err := svc.StartDocumentAnalysisWithContext(ctx, startInput)
 
 	if err != nil {
-		log.Fatalf("start analysis err: %s", err)
+		return fmt.Errorf("start analysis err: %w", err)
 	}
 
 	log.Printf("job: %s started", *startResult.JobId)
 	var lastStatus string
 	for i := 0; i < 60; i++ {
-		time.Sleep(5 * time.Second)
+		if err := aws.SleepWithContext(ctx, 5*time.Second); err != nil {
+			log.Printf("job: %s still running, stopped waiting for it", *startResult.JobId)
+			return err
+		}
 
-		result, err := svc.GetDocumentAnalysis(&textract.GetDocumentAnalysisInput{
+		result, err := svc.GetDocumentAnalysisWithContext(ctx, &textract.GetDocumentAnalysisInput{
 			JobId:      startResult.JobId,
 			MaxResults: aws.Int64(5),
 		})
 		if err != nil {
-			log.Fatalf("GetDocumentAnalysis err: %s", err)
+			return fmt.Errorf("GetDocumentAnalysis err: %w", err)
 		}
 		lastStatus = *result.JobStatus
 		if lastStatus == textract.JobStatusSucceeded || lastStatus == textract.JobStatusFailed {
@@ -111,17 +127,17 @@ func analyzeDocAction(cmd *cobra.Command, args []string) {
 	}
 
 	if lastStatus != textract.JobStatusSucceeded {
-		log.Fatalf("Waiting for job timed out with status: %s", lastStatus)
+		return fmt.Errorf("Waiting for job timed out with status: %s", lastStatus)
 	}
 
 	var nextToken *string
 	for {
-		result, err := svc.GetDocumentAnalysis(&textract.GetDocumentAnalysisInput{
+		result, err := svc.GetDocumentAnalysisWithContext(ctx, &textract.GetDocumentAnalysisInput{
 			JobId:     startResult.JobId,
 			NextToken: nextToken,
 		})
 		if err != nil {
-			log.Fatalf("GetDocumentAnalysis err: %s", err)
+			return fmt.Errorf("GetDocumentAnalysis err: %w", err)
 		}
 		aggregatedResult.DocumentMetadata = result.DocumentMetadata
 		for _, b := range result.Blocks {
@@ -137,7 +153,7 @@ func analyzeDocAction(cmd *cobra.Command, args []string) {
 
 	out, err := json.Marshal(aggregatedResult)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	f, err := ioutil.TempFile("", "textract")
@@ -146,10 +162,9 @@ func analyzeDocAction(cmd *cobra.Command, args []string) {
 	} else {
 		f.Write(out)
 		f.Close()
+		fmt.Fprintf(cmd.OutOrStdout(), "wrote: %s\n", f.Name())
 	}
 
-	fmt.Printf("wrote: %s\n", f.Name())
-
 	tableBlocks := make([]textract.Block, 0, 32)
 	blocksByID := make(map[string]textract.Block)
 	for _, block := range aggregatedResult.Blocks {
@@ -182,7 +197,9 @@ func analyzeDocAction(cmd *cobra.Command, args []string) {
 		f2.Close()
 	}
 
-	fmt.Printf("wrote: %s\n", f2.Name())
+	fmt.Fprintf(cmd.OutOrStdout(), "wrote: %s\n", f2.Name())
+
+	return nil
 }
 
 type worker struct {
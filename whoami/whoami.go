@@ -0,0 +1,204 @@
+package whoami
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/organizations/organizationsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/fatih/color"
+	"github.com/psanford/aws-buddy/client"
+	"github.com/psanford/aws-buddy/config"
+	"github.com/psanford/aws-buddy/output"
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "whoami",
+		Short: "Show the identity, account and region commands run against",
+		RunE:  whoamiAction,
+	}
+
+	return &cmd
+}
+
+// Identity describes the credentials and account commands run against.
+// The alias and organization fields are empty when the caller isn't
+// allowed to look them up.
+type Identity struct {
+	Account     string `json:"account"`
+	Alias       string `json:"alias"`
+	Arn         string `json:"arn"`
+	UserID      string `json:"user_id"`
+	Profile     string `json:"profile"`
+	Region      string `json:"region"`
+	AccountName string `json:"account_name"`
+	OUPath      string `json:"ou_path"`
+	OrgID       string `json:"org_id"`
+}
+
+func whoamiAction(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	ident, err := Lookup(ctx, config.Session())
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	out := output.New(w)
+	if !output.IsTable(out.Default) {
+		out.Add(ident)
+		return out.Flush()
+	}
+
+	fmt.Fprintf(w, "account      : %s\n", ident.Account)
+	fmt.Fprintf(w, "alias        : %s\n", orNone(ident.Alias))
+	fmt.Fprintf(w, "arn          : %s\n", ident.Arn)
+	fmt.Fprintf(w, "user id      : %s\n", ident.UserID)
+	fmt.Fprintf(w, "profile      : %s\n", ident.Profile)
+	fmt.Fprintf(w, "region       : %s\n", ident.Region)
+	if ident.OrgID != "" {
+		fmt.Fprintf(w, "account name : %s\n", orNone(ident.AccountName))
+		fmt.Fprintf(w, "ou path      : %s\n", orNone(ident.OUPath))
+		fmt.Fprintf(w, "org id       : %s\n", ident.OrgID)
+	}
+
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// Lookup returns the identity for sess. Only the STS call is required to
+// succeed; the account alias and organization details are best effort.
+func Lookup(ctx context.Context, sess *awssession.Session) (*Identity, error) {
+	callerIdent, err := client.STS(sess).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("GetCallerIdentity err: %w", err)
+	}
+
+	ident := Identity{
+		Account: aws.StringValue(callerIdent.Account),
+		Arn:     aws.StringValue(callerIdent.Arn),
+		UserID:  aws.StringValue(callerIdent.UserId),
+		Profile: profileName(),
+		Region:  aws.StringValue(sess.Config.Region),
+	}
+
+	aliases, err := client.IAM(sess).ListAccountAliasesWithContext(ctx, &iam.ListAccountAliasesInput{})
+	if err == nil && len(aliases.AccountAliases) > 0 {
+		ident.Alias = aws.StringValue(aliases.AccountAliases[0])
+	}
+
+	orgSvc := client.Organizations(sess)
+	org, err := orgSvc.DescribeOrganizationWithContext(ctx, &organizations.DescribeOrganizationInput{})
+	if err != nil {
+		// not in an organization, or not allowed to describe it
+		return &ident, nil
+	}
+	ident.OrgID = aws.StringValue(org.Organization.Id)
+
+	// DescribeAccount and ListParents only work from the management
+	// account or a delegated administrator
+	acct, err := orgSvc.DescribeAccountWithContext(ctx, &organizations.DescribeAccountInput{
+		AccountId: aws.String(ident.Account),
+	})
+	if err == nil {
+		ident.AccountName = aws.StringValue(acct.Account.Name)
+	}
+
+	path, err := ouPath(ctx, orgSvc, ident.Account)
+	if err == nil {
+		ident.OUPath = path
+	}
+
+	return &ident, nil
+}
+
+// ouPath returns the names of the OUs containing the account, from the
+// root down, e.g. "/Workloads/Prod".
+func ouPath(ctx context.Context, svc organizationsiface.OrganizationsAPI, childID string) (string, error) {
+	var names []string
+	for {
+		parents, err := svc.ListParentsWithContext(ctx, &organizations.ListParentsInput{
+			ChildId: aws.String(childID),
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(parents.Parents) == 0 {
+			break
+		}
+		parent := parents.Parents[0]
+		if aws.StringValue(parent.Type) != organizations.ParentTypeOrganizationalUnit {
+			break
+		}
+
+		ou, err := svc.DescribeOrganizationalUnitWithContext(ctx, &organizations.DescribeOrganizationalUnitInput{
+			OrganizationalUnitId: parent.Id,
+		})
+		if err != nil {
+			return "", err
+		}
+		names = append([]string{aws.StringValue(ou.OrganizationalUnit.Name)}, names...)
+		childID = aws.StringValue(parent.Id)
+	}
+
+	return "/" + strings.Join(names, "/"), nil
+}
+
+func profileName() string {
+	if config.Profile != "" {
+		return config.Profile
+	}
+	if p := os.Getenv("AWS_PROFILE"); p != "" {
+		return p
+	}
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return "(environment)"
+	}
+	return "default"
+}
+
+var (
+	bannerOnce sync.Once
+	banner     string
+)
+
+// Banner returns a one line colored summary of the account and region,
+// shown above confirmation prompts with --banner. The lookup is done once
+// per process; if it fails the banner is empty.
+func Banner() string {
+	bannerOnce.Do(func() {
+		sess, err := config.NewSession()
+		if err != nil {
+			return
+		}
+		// shared by every prompt; the lookup is quick and only done once
+		ident, err := Lookup(context.Background(), sess)
+		if err != nil {
+			return
+		}
+
+		account := ident.Account
+		if name := ident.Alias; name != "" {
+			account = fmt.Sprintf("%s (%s)", account, name)
+		} else if name := ident.AccountName; name != "" {
+			account = fmt.Sprintf("%s (%s)", account, name)
+		}
+		banner = color.New(color.FgBlack, color.BgYellow).Sprintf(" account %s | region %s | profile %s ", account, ident.Region, ident.Profile)
+	})
+	return banner
+}